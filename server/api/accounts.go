@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+// AccountHandler serves player accounts and the session tokens they sign
+// in with. Tokens are sent back as bearer tokens to the HTTP API and in the
+// socket handshake. Players without one play as guests.
+type AccountHandler struct {
+	Accounts *auth.Accounts
+	Sessions *auth.Sessions
+}
+
+// CreateAccountRequest registers Username with Password, or without a
+// password to get a magic token to sign in with instead.
+type CreateAccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CreateSessionRequest signs in with either Password or MagicToken.
+type CreateSessionRequest struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	MagicToken string `json:"magicToken"`
+}
+
+type AccountResponse struct {
+	Id        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SessionResponse carries a session token. MagicToken is only set when a
+// passwordless account is created and is not shown again.
+type SessionResponse struct {
+	Account    AccountResponse `json:"account"`
+	Token      string          `json:"token"`
+	ExpiresAt  time.Time       `json:"expiresAt"`
+	MagicToken string          `json:"magicToken"`
+}
+
+func accountResponse(account *auth.Account) AccountResponse {
+	return AccountResponse{Id: account.Id, Username: account.Username, CreatedAt: account.CreatedAt}
+}
+
+// CreateAccount registers an account and signs it in.
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var request CreateAccountRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	account, magicToken, err := h.Accounts.Register(request.Username, request.Password)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "account created", logging.KeyAccountId, account.Id)
+	h.writeSession(w, account, magicToken)
+}
+
+// CreateSession signs in to an account.
+func (h *AccountHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var request CreateSessionRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	account, err := h.Accounts.Login(request.Username, request.Password, request.MagicToken)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	h.writeSession(w, account, "")
+}
+
+// Me returns the account the request's session token was issued to.
+func (h *AccountHandler) Me(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.Sessions.FromRequest(r)
+	if err == nil && claims == nil {
+		err = errors.New("a session token is required")
+	}
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, err)
+		return
+	}
+	account, err := h.Accounts.Store.Account(claims.AccountId)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, accountResponse(account))
+}
+
+func (h *AccountHandler) writeSession(w http.ResponseWriter, account *auth.Account, magicToken string) {
+	token, expiresAt, err := h.Sessions.Issue(account)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorCodeInternal, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, &SessionResponse{
+		Account:    accountResponse(account),
+		Token:      token,
+		ExpiresAt:  expiresAt,
+		MagicToken: magicToken,
+	})
+}
+
+// requestAccountId returns the account of a request's session token, or an
+// empty id for guests. It writes an error and returns false when the token
+// is invalid or expired.
+func requestAccountId(w http.ResponseWriter, r *http.Request, sessions *auth.Sessions) (string, bool) {
+	if sessions == nil {
+		return "", true
+	}
+	claims, err := sessions.FromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, err)
+		return "", false
+	}
+	if claims == nil {
+		return "", true
+	}
+	return claims.AccountId, true
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidAccount):
+		writeError(w, http.StatusUnprocessableEntity, ErrorCodeValidation, err)
+	case errors.Is(err, auth.ErrAccountExists):
+		writeError(w, http.StatusConflict, ErrorCodeConflict, err)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, err)
+	case errors.Is(err, auth.ErrAccountNotFound):
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, err)
+	default:
+		writeError(w, http.StatusInternalServerError, ErrorCodeInternal, err)
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/tracing"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+// MaxSystemMessageLength caps the length of an operator's broadcast.
+const MaxSystemMessageLength = 280
+
+// AdminNotifier carries out operator actions and tells the affected clients
+// about them.
+type AdminNotifier interface {
+	EndRoom(roomId, reason string) error
+	KickPlayer(roomId, playerId, reason string) error
+	BroadcastSystemMessage(roomId, message string) []string
+}
+
+// AdminHandler serves the operator API. Every request must carry Token as a
+// bearer token, and the API is disabled while Token is empty.
+type AdminHandler struct {
+	Token    string
+	Notifier AdminNotifier
+	Cluster  RoomForwarder
+}
+
+type AdminRoom struct {
+	Id          string                   `json:"id"`
+	Phase       string                   `json:"phase"`
+	Players     map[string]*types.Player `json:"players"`
+	PlayerCount int                      `json:"playerCount"`
+	Timer       room.TimerState          `json:"timer"`
+}
+
+type AdminRoomsResponse struct {
+	Rooms []AdminRoom `json:"rooms"`
+}
+
+type EndRoomResponse struct {
+	RoomId string `json:"roomId"`
+	Reason string `json:"reason"`
+}
+
+type KickPlayerResponse struct {
+	RoomId   string `json:"roomId"`
+	PlayerId string `json:"playerId"`
+	Reason   string `json:"reason"`
+}
+
+// BroadcastRequest sends Message to the room RoomId, or to every room when
+// RoomId is empty.
+type BroadcastRequest struct {
+	RoomId  string `json:"roomId"`
+	Message string `json:"message"`
+}
+
+type BroadcastResponse struct {
+	RoomIds []string `json:"roomIds"`
+	Message string   `json:"message"`
+}
+
+func (req *BroadcastRequest) validate() error {
+	if strings.TrimSpace(req.Message) == "" {
+		return errors.New("message is required")
+	}
+	if len(req.Message) > MaxSystemMessageLength {
+		return errors.New("message is too long")
+	}
+	return nil
+}
+
+// ListRooms lists the rooms held by this server with their phase, players
+// and timer.
+func (h *AdminHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	rooms := []AdminRoom{}
+	for _, rm := range room.List() {
+		rooms = append(rooms, AdminRoom{
+			Id:          rm.Id,
+			Phase:       rm.Phase(),
+			Players:     rm.Players,
+			PlayerCount: rm.GetPlayerCount(),
+			Timer:       rm.TimerState(),
+		})
+	}
+	writeJSON(w, http.StatusOK, &AdminRoomsResponse{Rooms: rooms})
+}
+
+// EndRoom force-ends a room. An optional reason query parameter is passed
+// on to its clients.
+func (h *AdminHandler) EndRoom(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	roomId := r.PathValue("roomId")
+	tracing.SetRoomId(r.Context(), roomId)
+	if h.forward(w, r, roomId) {
+		return
+	}
+	reason := r.URL.Query().Get("reason")
+	if err := h.Notifier.EndRoom(roomId, reason); err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	slog.WarnContext(r.Context(), "operator ended room", logging.KeyRoomId, roomId, "reason", reason)
+	writeJSON(w, http.StatusOK, &EndRoomResponse{RoomId: roomId, Reason: reason})
+}
+
+// KickPlayer removes a player from a room. An optional reason query
+// parameter is passed on to the room's clients.
+func (h *AdminHandler) KickPlayer(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	roomId := r.PathValue("roomId")
+	tracing.SetRoomId(r.Context(), roomId)
+	if h.forward(w, r, roomId) {
+		return
+	}
+	playerId := r.PathValue("playerId")
+	reason := r.URL.Query().Get("reason")
+	if err := h.Notifier.KickPlayer(roomId, playerId, reason); err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	slog.WarnContext(r.Context(), "operator kicked player",
+		logging.KeyRoomId, roomId,
+		logging.KeyPlayerId, playerId,
+		"reason", reason,
+	)
+	writeJSON(w, http.StatusOK, &KickPlayerResponse{RoomId: roomId, PlayerId: playerId, Reason: reason})
+}
+
+// Broadcast sends a system message to one room or to all of them.
+func (h *AdminHandler) Broadcast(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	var request BroadcastRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if err := request.validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrorCodeValidation, err)
+		return
+	}
+	roomIds := h.Notifier.BroadcastSystemMessage(request.RoomId, request.Message)
+	slog.InfoContext(r.Context(), "operator broadcast system message", "rooms", len(roomIds))
+	writeJSON(w, http.StatusOK, &BroadcastResponse{RoomIds: roomIds, Message: request.Message})
+}
+
+// authorize checks the request's bearer token, writing an error response
+// and returning false when it does not match.
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if h.Token == "" {
+		writeError(w, http.StatusForbidden, ErrorCodeForbidden, errors.New("the admin API is disabled, set an admin token to enable it"))
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+		slog.WarnContext(r.Context(), "rejected admin request", "path", r.URL.Path, "remote_address", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, errors.New("a valid admin bearer token is required"))
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) forward(w http.ResponseWriter, r *http.Request, roomId string) bool {
+	if h.Cluster == nil || room.HasRoom(roomId) {
+		return false
+	}
+	return h.Cluster.ForwardRoom(w, r, roomId)
+}
@@ -1,90 +1,280 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/bot"
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/matchmaking"
+	"github.com/campbell-rehu/quik-be/names"
+	"github.com/campbell-rehu/quik-be/ratelimit"
 	"github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/tracing"
+	"github.com/campbell-rehu/quik-be/types"
 )
 
-type RoomHandler struct{}
+const MaxPlayerNameLength = names.MaxLength
 
-func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
-	room := room.AddRoom()
+// commandAddPlayer is recorded in a room's log when a player is seated
+// through the API.
+const commandAddPlayer = "add-player"
+
+// RoomForwarder proxies requests for rooms owned by another server.
+type RoomForwarder interface {
+	ForwardRoom(w http.ResponseWriter, r *http.Request, roomId string) bool
+}
+
+// BotSeater seats bot players in rooms.
+type BotSeater interface {
+	AddBot(roomId, difficulty string) (*types.Player, error)
+}
 
-	fmt.Printf("room created with id=%s\n", room.Id)
+// RoomHandler serves rooms. Players seated with a session token from
+// Sessions are kept against its account. RoomQuota, if set, caps the open
+// rooms created from each client address. Bots, if set, seats bots for
+// solo practice.
+type RoomHandler struct {
+	Cluster    RoomForwarder
+	Sessions   *auth.Sessions
+	RoomQuota  *ratelimit.RoomQuota
+	Bots       BotSeater
+	TrustProxy bool
+}
+
+type AddPlayerRequest struct {
+	PlayerId   string `json:"playerId"`
+	PlayerName string `json:"playerName"`
+}
 
-	res, err := json.Marshal(room)
+func (req *AddPlayerRequest) validate() error {
+	if strings.TrimSpace(req.PlayerId) == "" {
+		return errors.New("playerId is required")
+	}
+	name, err := names.Clean(req.PlayerName)
 	if err != nil {
-		log.Printf("Something went wrong: %e", err)
+		return fmt.Errorf("playerName: %w", err)
+	}
+	req.PlayerName = name
+	return nil
+}
+
+func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
+	addr := ratelimit.ClientAddr(r, h.TrustProxy)
+	if h.RoomQuota != nil {
+		if err := h.RoomQuota.Reserve(addr); err != nil {
+			writeError(w, http.StatusTooManyRequests, ErrorCodeTooManyRooms, err)
+			return
+		}
+	}
+	room, err := room.AddRoom()
+	if err != nil {
+		if h.RoomQuota != nil {
+			h.RoomQuota.Cancel(addr)
+		}
+		writeRoomError(w, err)
 		return
 	}
+	if h.RoomQuota != nil {
+		h.RoomQuota.Bind(addr, room.Id)
+	}
+
+	tracing.SetRoomId(r.Context(), room.Id)
+	slog.InfoContext(r.Context(), "room created", logging.KeyRoomId, room.Id)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(string(res))
+	writeJSON(w, http.StatusCreated, room)
 }
 
 func (h *RoomHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 	roomId := r.PathValue("roomId")
+	tracing.SetRoomId(r.Context(), roomId)
+	if h.forward(w, r, roomId) {
+		return
+	}
 	room, err := room.GetRoom(roomId)
 	if err != nil {
-		w.Write([]byte(err.Error()))
-		w.WriteHeader(http.StatusNotFound)
+		writeRoomError(w, err)
 		return
 	}
 
-	fmt.Printf("room found with id=%s\n", room.Id)
+	slog.InfoContext(r.Context(), "room found", logging.KeyRoomId, room.Id)
 
-	res, err := json.Marshal(room)
+	writeJSON(w, http.StatusOK, room)
+}
+
+func (h *RoomHandler) AddPlayerToRoom(w http.ResponseWriter, r *http.Request) {
+	roomId := r.PathValue("roomId")
+	tracing.SetRoomId(r.Context(), roomId)
+	if h.forward(w, r, roomId) {
+		return
+	}
+	var request AddPlayerRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	accountId, ok := requestAccountId(w, r, h.Sessions)
+	if !ok {
+		return
+	}
+
+	room, err := room.GetRoom(roomId)
 	if err != nil {
-		log.Printf("Something went wrong: %e", err)
+		writeRoomError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(string(res))
+	room.RecordCommand(commandAddPlayer, request.PlayerId, &request)
+	err = room.AddAccountPlayerToRoom(request.PlayerId, request.PlayerName, accountId)
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	// The name is suffixed when another player in the room has it.
+	request.PlayerName = room.Players[request.PlayerId].Name
+
+	writeJSON(w, http.StatusCreated, &request)
 }
 
-func (h *RoomHandler) AddPlayerToRoom(w http.ResponseWriter, r *http.Request) {
+// AddBotRequest picks how well the bot plays. The server's default
+// difficulty is used when Difficulty is empty.
+type AddBotRequest struct {
+	Difficulty string `json:"difficulty"`
+}
+
+// AddBot seats a bot player in a room.
+func (h *RoomHandler) AddBot(w http.ResponseWriter, r *http.Request) {
 	roomId := r.PathValue("roomId")
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Fatal(fmt.Printf("unable to read request body, %s", err.Error()))
+	tracing.SetRoomId(r.Context(), roomId)
+	if h.forward(w, r, roomId) {
+		return
+	}
+	var request AddBotRequest
+	// The body may be left out to use the default difficulty.
+	if r.ContentLength != 0 && !decodeJSON(w, r, &request) {
+		return
+	}
+	if h.Bots == nil {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, bot.ErrDisabled)
+		return
 	}
-	var request struct {
-		PlayerId   string `json:"playerId"`
-		PlayerName string `json:"playerName"`
+	player, err := h.Bots.AddBot(roomId, request.Difficulty)
+	switch {
+	case errors.Is(err, bot.ErrDisabled):
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, err)
+		return
+	case errors.Is(err, bot.ErrUnknownDifficulty):
+		writeValidationError(w, fmt.Errorf("difficulty: %w", err))
+		return
+	case err != nil:
+		writeRoomError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "bot added to room", logging.KeyRoomId, roomId, logging.KeyPlayerId, player.Id)
+
+	writeJSON(w, http.StatusCreated, player)
+}
+
+// ReplayResponse is a room's full timeline and the state it rebuilds.
+type ReplayResponse struct {
+	RoomId  string          `json:"roomId"`
+	Entries []room.LogEntry `json:"entries"`
+	Room    *room.Room      `json:"room"`
+}
+
+// Replay returns a room's event log for post-game review, along with the
+// room as rebuilt by replaying it.
+func (h *RoomHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	roomId := r.PathValue("roomId")
+	tracing.SetRoomId(r.Context(), roomId)
+	entries, err := room.EventLog(roomId)
+	if errors.Is(err, room.ErrNoEventLog) && h.forward(w, r, roomId) {
+		return
 	}
-	err = json.Unmarshal(body, &request)
 	if err != nil {
-		log.Fatal(fmt.Printf("unable to unmarshal request %s", err.Error()))
+		writeRoomError(w, err)
+		return
 	}
-	room, err := room.GetRoom(roomId)
+	rebuilt, err := room.Replay(roomId, entries)
 	if err != nil {
-		w.Write([]byte(err.Error()))
-		w.WriteHeader(http.StatusNotFound)
+		writeRoomError(w, err)
 		return
 	}
+	writeJSON(w, http.StatusOK, &ReplayResponse{RoomId: roomId, Entries: entries, Room: rebuilt})
+}
 
-	err = room.AddPlayerToRoom(request.PlayerId, request.PlayerName)
-	if err != nil {
-		w.Write([]byte(err.Error()))
-		w.WriteHeader(http.StatusLocked)
+// forward hands the request to the server owning the room when it is not
+// this one.
+func (h *RoomHandler) forward(w http.ResponseWriter, r *http.Request, roomId string) bool {
+	if h.Cluster == nil || room.HasRoom(roomId) {
+		return false
+	}
+	return h.Cluster.ForwardRoom(w, r, roomId)
+}
+
+func writeRoomError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, room.ErrRoomNotFound), errors.Is(err, room.ErrPlayerNotFound), errors.Is(err, room.ErrNoEventLog):
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, err)
+	case errors.Is(err, room.ErrRoomLocked):
+		writeError(w, http.StatusLocked, ErrorCodeRoomLocked, err)
+	case errors.Is(err, room.ErrRoomFull):
+		writeError(w, http.StatusConflict, ErrorCodeRoomFull, err)
+	case errors.Is(err, room.ErrTooManyRooms):
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeTooManyRooms, err)
+	case errors.Is(err, names.ErrInvalidName), errors.Is(err, names.ErrNameNotAllowed):
+		writeValidationError(w, err)
+	default:
+		writeError(w, http.StatusInternalServerError, ErrorCodeInternal, err)
+	}
+}
+
+type MatchmakingHandler struct {
+	Queue    *matchmaking.Queue
+	Sessions *auth.Sessions
+}
+
+type JoinQueueRequest struct {
+	PlayerId    string            `json:"playerId"`
+	PlayerName  string            `json:"playerName"`
+	Preferences types.Preferences `json:"preferences"`
+}
+
+type JoinQueueResponse struct {
+	PlayerId string `json:"playerId"`
+	Position int    `json:"position"`
+}
+
+func (h *MatchmakingHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
+	var request JoinQueueRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	accountId, ok := requestAccountId(w, r, h.Sessions)
+	if !ok {
 		return
 	}
 
-	res, err := json.Marshal(request)
+	position, err := h.Queue.Join(request.PlayerId, request.PlayerName, accountId, request.Preferences)
 	if err != nil {
-		log.Printf("Something went wrong: %e", err)
+		switch {
+		case errors.Is(err, matchmaking.ErrInvalidTicket):
+			writeValidationError(w, err)
+		case errors.Is(err, matchmaking.ErrAlreadyQueued):
+			writeError(w, http.StatusConflict, ErrorCodeConflict, err)
+		default:
+			writeError(w, http.StatusInternalServerError, ErrorCodeInternal, err)
+		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(string(res))
+	writeJSON(w, http.StatusAccepted, &JoinQueueResponse{PlayerId: request.PlayerId, Position: position})
 }
@@ -0,0 +1,699 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/bot"
+	"github.com/campbell-rehu/quik-be/history"
+	"github.com/campbell-rehu/quik-be/leaderboard"
+	"github.com/campbell-rehu/quik-be/matchmaking"
+	"github.com/campbell-rehu/quik-be/names"
+	"github.com/campbell-rehu/quik-be/ratelimit"
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/campbell-rehu/quik-be/words"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyQueuePosition(string, int, int)   {}
+func (nopNotifier) NotifyMatchFound(string, *roomPkg.Room) {}
+func (nopNotifier) NotifyQueueTimeout(string)              {}
+
+const adminToken = "secret"
+
+var (
+	sessions = auth.NewSessions("secret", time.Hour)
+	accounts = &auth.Accounts{Store: auth.NewMemoryStore()}
+)
+
+// games holds the completed games served by the history routes, and board
+// ranks them.
+var (
+	games = history.NewMemoryStore()
+	board = leaderboard.New()
+)
+
+// roomNotifier carries out admin actions on the rooms without any clients
+// to tell.
+type roomNotifier struct{}
+
+func (roomNotifier) EndRoom(roomId, reason string) error {
+	return roomPkg.EndRoom(roomId)
+}
+
+func (roomNotifier) KickPlayer(roomId, playerId, reason string) error {
+	room, err := roomPkg.GetRoom(roomId)
+	if err != nil {
+		return err
+	}
+	return room.KickPlayer(playerId)
+}
+
+func (roomNotifier) BroadcastSystemMessage(roomId, message string) []string {
+	if roomId == "" {
+		return roomPkg.RoomIds()
+	}
+	return []string{roomId}
+}
+
+// roomBots seats bots in the rooms without playing their turns.
+type roomBots struct{}
+
+func (roomBots) AddBot(roomId, difficulty string) (*types.Player, error) {
+	room, err := roomPkg.GetRoom(roomId)
+	if err != nil {
+		return nil, err
+	}
+	if difficulty == "" {
+		difficulty = bot.DifficultyMedium
+	}
+	b, err := bot.New(difficulty, bot.DefaultLevels(), words.Default())
+	if err != nil {
+		return nil, err
+	}
+	if err := room.AddBotToRoom(b.Id, b.Name); err != nil {
+		return nil, err
+	}
+	return room.Players[b.Id], nil
+}
+
+func newRouter() *http.ServeMux {
+	router := http.NewServeMux()
+	roomHandler := &RoomHandler{Sessions: sessions, Bots: roomBots{}}
+	matchmakingHandler := &MatchmakingHandler{Queue: matchmaking.NewQueue(nopNotifier{}), Sessions: sessions}
+	router.HandleFunc("POST /room", roomHandler.CreateRoom)
+	router.HandleFunc("GET /room/{roomId}", roomHandler.JoinRoom)
+	router.HandleFunc("POST /room/{roomId}/addPlayer", roomHandler.AddPlayerToRoom)
+	router.HandleFunc("POST /room/{roomId}/bots", roomHandler.AddBot)
+	router.HandleFunc("GET /room/{roomId}/replay", roomHandler.Replay)
+	router.HandleFunc("POST /matchmaking", matchmakingHandler.JoinQueue)
+	adminHandler := &AdminHandler{Token: adminToken, Notifier: roomNotifier{}}
+	router.HandleFunc("GET /admin/rooms", adminHandler.ListRooms)
+	router.HandleFunc("DELETE /admin/rooms/{roomId}", adminHandler.EndRoom)
+	router.HandleFunc("DELETE /admin/rooms/{roomId}/players/{playerId}", adminHandler.KickPlayer)
+	router.HandleFunc("POST /admin/broadcast", adminHandler.Broadcast)
+	accountHandler := &AccountHandler{Accounts: accounts, Sessions: sessions}
+	router.HandleFunc("POST /accounts", accountHandler.CreateAccount)
+	router.HandleFunc("GET /accounts/me", accountHandler.Me)
+	router.HandleFunc("POST /sessions", accountHandler.CreateSession)
+	leaderboardHandler := &LeaderboardHandler{Board: board}
+	router.HandleFunc("GET /leaderboard", leaderboardHandler.Leaderboard)
+	historyHandler := &HistoryHandler{Games: games}
+	router.HandleFunc("GET /players/{playerId}/stats", historyHandler.PlayerStats)
+	router.HandleFunc("GET /players/{playerId}/games", historyHandler.PlayerGames)
+	router.HandleFunc("GET /games/{gameId}", historyHandler.Game)
+	return router
+}
+
+func do(t *testing.T, method, target, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	return doAuthorized(t, method, target, body, "")
+}
+
+func doAuthorized(t *testing.T, method, target, body, token string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", got)
+	}
+	var envelope map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("response is not a JSON object: %v, body=%s", err, rec.Body.String())
+	}
+	return rec, envelope
+}
+
+func errorCode(envelope map[string]any) string {
+	e, ok := envelope["error"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	return e["code"].(string)
+}
+
+func addRoom(t *testing.T) *roomPkg.Room {
+	t.Helper()
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return room
+}
+
+func TestCreateRoom(t *testing.T) {
+	rec, envelope := do(t, http.MethodPost, "/room", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	data := envelope["data"].(map[string]any)
+	if data["id"] == "" {
+		t.Fatal("expected a room id")
+	}
+	roomPkg.RemoveRoom(data["id"].(string))
+}
+
+func TestJoinRoom(t *testing.T) {
+	room := addRoom(t)
+	defer roomPkg.RemoveRoom(room.Id)
+
+	rec, envelope := do(t, http.MethodGet, "/room/"+room.Id, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := envelope["data"].(map[string]any)["id"]; got != room.Id {
+		t.Fatalf("room id = %v, want %s", got, room.Id)
+	}
+
+	rec, envelope = do(t, http.MethodGet, "/room/does-not-exist", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if code := errorCode(envelope); code != ErrorCodeNotFound {
+		t.Fatalf("error code = %q, want %q", code, ErrorCodeNotFound)
+	}
+}
+
+func TestAddPlayerToRoom(t *testing.T) {
+	room := addRoom(t)
+	defer roomPkg.RemoveRoom(room.Id)
+	locked := addRoom(t)
+	locked.LockRoom()
+	defer roomPkg.RemoveRoom(locked.Id)
+
+	tests := []struct {
+		name   string
+		roomId string
+		body   string
+		status int
+		code   string
+	}{
+		{"adds player", room.Id, `{"playerId":"p1","playerName":"Kiri"}`, http.StatusCreated, ""},
+		{"empty body", room.Id, ``, http.StatusBadRequest, ErrorCodeBadRequest},
+		{"malformed body", room.Id, `{"playerId":`, http.StatusBadRequest, ErrorCodeBadRequest},
+		{"unknown field", room.Id, `{"playerId":"p2","playerName":"A","admin":true}`, http.StatusBadRequest, ErrorCodeBadRequest},
+		{"missing name", room.Id, `{"playerId":"p2"}`, http.StatusUnprocessableEntity, ErrorCodeValidation},
+		{"name too long", room.Id, `{"playerId":"p2","playerName":"` + strings.Repeat("a", MaxPlayerNameLength+1) + `"}`, http.StatusUnprocessableEntity, ErrorCodeValidation},
+		{"body too large", room.Id, `{"playerId":"` + strings.Repeat("a", MaxRequestBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, ErrorCodeBodyTooLarge},
+		{"room not found", "does-not-exist", `{"playerId":"p2","playerName":"A"}`, http.StatusNotFound, ErrorCodeNotFound},
+		{"room locked", locked.Id, `{"playerId":"p3","playerName":"A"}`, http.StatusLocked, ErrorCodeRoomLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := do(t, http.MethodPost, "/room/"+tt.roomId+"/addPlayer", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body=%s", rec.Code, tt.status, rec.Body.String())
+			}
+			if code := errorCode(envelope); code != tt.code {
+				t.Fatalf("error code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+
+	if _, ok := room.Players["p1"]; !ok {
+		t.Fatal("expected player p1 to be seated")
+	}
+}
+
+func TestPlayerNames(t *testing.T) {
+	names.SetFilter(names.DefaultBlocklist())
+	defer names.SetFilter(nil)
+	room := addRoom(t)
+	defer roomPkg.RemoveRoom(room.Id)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		seated string
+	}{
+		{"trimmed", `{"playerId":"n1","playerName":"  Kiri  "}`, http.StatusCreated, "", "Kiri"},
+		{"suffixed when taken", `{"playerId":"n2","playerName":"kiri"}`, http.StatusCreated, "", "kiri 2"},
+		{"kept when rejoining", `{"playerId":"n1","playerName":"Kiri"}`, http.StatusCreated, "", "Kiri"},
+		{"huge", `{"playerId":"n3","playerName":"` + strings.Repeat("a", 10_000) + `"}`, http.StatusUnprocessableEntity, ErrorCodeValidation, ""},
+		{"profane", `{"playerId":"n3","playerName":"sh1t"}`, http.StatusUnprocessableEntity, ErrorCodeNameNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, envelope := do(t, http.MethodPost, "/room/"+room.Id+"/addPlayer", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body=%s", rec.Code, tt.status, rec.Body.String())
+			}
+			if code := errorCode(envelope); code != tt.code {
+				t.Fatalf("error code = %q, want %q", code, tt.code)
+			}
+			if tt.seated == "" {
+				return
+			}
+			if got := envelope["data"].(map[string]any)["playerName"]; got != tt.seated {
+				t.Fatalf("seated as %v, want %q", got, tt.seated)
+			}
+		})
+	}
+
+	rec, envelope := do(t, http.MethodPost, "/matchmaking", `{"playerId":"n4","playerName":"wanker"}`)
+	if rec.Code != http.StatusUnprocessableEntity || errorCode(envelope) != ErrorCodeNameNotAllowed {
+		t.Fatalf("status = %d, code = %q, want the name refused", rec.Code, errorCode(envelope))
+	}
+}
+
+func TestRoomLimits(t *testing.T) {
+	room := addRoom(t)
+	defer roomPkg.RemoveRoom(room.Id)
+	if err := room.AddPlayerToRoom("p1", "Kiri"); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.AddPlayerToRoom("p2", "Mere"); err != nil {
+		t.Fatal(err)
+	}
+
+	roomPkg.Configure(roomPkg.Settings{
+		TimerDuration: roomPkg.DefaultTimerDuration,
+		WinCount:      roomPkg.DefaultWinCount,
+		MaxRooms:      1,
+		MaxPlayers:    2,
+	})
+	defer roomPkg.Configure(roomPkg.Settings{
+		TimerDuration: roomPkg.DefaultTimerDuration,
+		WinCount:      roomPkg.DefaultWinCount,
+	})
+
+	rec, envelope := do(t, http.MethodPost, "/room/"+room.Id+"/addPlayer", `{"playerId":"p3","playerName":"Tama"}`)
+	if rec.Code != http.StatusConflict || errorCode(envelope) != ErrorCodeRoomFull {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusConflict, ErrorCodeRoomFull)
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/room", "")
+	if rec.Code != http.StatusServiceUnavailable || errorCode(envelope) != ErrorCodeTooManyRooms {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusServiceUnavailable, ErrorCodeTooManyRooms)
+	}
+}
+
+func TestRoomQuota(t *testing.T) {
+	handler := &RoomHandler{Sessions: sessions, RoomQuota: ratelimit.NewRoomQuota(1)}
+	create := func() (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		handler.CreateRoom(rec, httptest.NewRequest(http.MethodPost, "/room", nil))
+		var envelope map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("response is not a JSON object: %v, body=%s", err, rec.Body.String())
+		}
+		return rec, envelope
+	}
+
+	rec, envelope := create()
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	roomId := envelope["data"].(map[string]any)["id"].(string)
+
+	rec, envelope = create()
+	if rec.Code != http.StatusTooManyRequests || errorCode(envelope) != ErrorCodeTooManyRooms {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusTooManyRequests, ErrorCodeTooManyRooms)
+	}
+
+	roomPkg.RemoveRoom(roomId)
+	handler.RoomQuota.Release(roomId)
+	if rec, _ := create(); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want the address's room freed once it was removed", rec.Code)
+	}
+}
+
+func TestAddBot(t *testing.T) {
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer roomPkg.RemoveRoom(room.Id)
+
+	rec, envelope := do(t, http.MethodPost, "/room/"+room.Id+"/bots", `{"difficulty":"hard"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	data := envelope["data"].(map[string]any)
+	if data["bot"] != true || !bot.IsBot(data["id"].(string)) {
+		t.Fatalf("player = %v, want a bot", data)
+	}
+	if room.GetPlayerCount() != 1 {
+		t.Fatalf("player count = %d, want the bot seated", room.GetPlayerCount())
+	}
+
+	rec, _ = do(t, http.MethodPost, "/room/"+room.Id+"/bots", "")
+	if rec.Code != http.StatusCreated || room.GetPlayerCount() != 2 {
+		t.Fatalf("status = %d, want %d for a bot of the default difficulty, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/room/"+room.Id+"/bots", `{"difficulty":"impossible"}`)
+	if rec.Code != http.StatusUnprocessableEntity || errorCode(envelope) != ErrorCodeValidation {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusUnprocessableEntity, ErrorCodeValidation)
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/room/missing/bots", `{}`)
+	if rec.Code != http.StatusNotFound || errorCode(envelope) != ErrorCodeNotFound {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusNotFound, ErrorCodeNotFound)
+	}
+}
+
+func TestJoinQueue(t *testing.T) {
+	rec, envelope := do(t, http.MethodPost, "/matchmaking", `{"playerId":"q1","playerName":"Aroha","preferences":{"difficulty":"Easy"}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	if got := envelope["data"].(map[string]any)["position"]; got != float64(1) {
+		t.Fatalf("position = %v, want 1", got)
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/matchmaking", `{"playerId":"q2","preferences":{"difficulty":"Impossible"}}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if code := errorCode(envelope); code != ErrorCodeValidation {
+		t.Fatalf("error code = %q, want %q", code, ErrorCodeValidation)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	var storeErr error
+	handler := &HealthHandler{Checks: map[string]func() error{
+		"store": func() error { return storeErr },
+	}}
+
+	rec := httptest.NewRecorder()
+	handler.Healthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("healthz status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("readyz status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	storeErr = errors.New("connection refused")
+	rec = httptest.NewRecorder()
+	handler.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), ErrorCodeNotReady) {
+		t.Fatalf("readyz status = %d, body = %s, want %d %s", rec.Code, rec.Body.String(), http.StatusServiceUnavailable, ErrorCodeNotReady)
+	}
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	for _, token := range []string{"", "wrong"} {
+		rec, envelope := doAuthorized(t, http.MethodGet, "/admin/rooms", "", token)
+		if rec.Code != http.StatusUnauthorized || errorCode(envelope) != ErrorCodeUnauthorized {
+			t.Fatalf("token %q: status = %d, code = %q, want %d %q", token, rec.Code, errorCode(envelope), http.StatusUnauthorized, ErrorCodeUnauthorized)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	disabled := &AdminHandler{Notifier: roomNotifier{}}
+	disabled.ListRooms(rec, httptest.NewRequest(http.MethodGet, "/admin/rooms", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d when no token is configured", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestAdminRooms(t *testing.T) {
+	room := addRoom(t)
+	defer roomPkg.RemoveRoom(room.Id)
+	if err := room.AddPlayerToRoom("a1", "Kiri"); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.AddPlayerToRoom("a2", "Mere"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, envelope := doAuthorized(t, http.MethodGet, "/admin/rooms", "", adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var listed map[string]any
+	for _, r := range envelope["data"].(map[string]any)["rooms"].([]any) {
+		if r.(map[string]any)["id"] == room.Id {
+			listed = r.(map[string]any)
+		}
+	}
+	if listed == nil || listed["phase"] != roomPkg.PhaseWaiting || listed["playerCount"] != float64(2) {
+		t.Fatalf("room %s was not listed as waiting with 2 players: %v", room.Id, listed)
+	}
+
+	rec, _ = doAuthorized(t, http.MethodDelete, "/admin/rooms/"+room.Id+"/players/a2?reason=spam", "", adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("kick status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if _, ok := room.Players["a2"]; ok || roomPkg.GetRoomId("a2") != "" {
+		t.Fatal("expected player a2 to be kicked")
+	}
+	rec, envelope = doAuthorized(t, http.MethodDelete, "/admin/rooms/"+room.Id+"/players/a2", "", adminToken)
+	if rec.Code != http.StatusNotFound || errorCode(envelope) != ErrorCodeNotFound {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusNotFound, ErrorCodeNotFound)
+	}
+
+	rec, envelope = doAuthorized(t, http.MethodPost, "/admin/broadcast", `{"roomId":"","message":"Maintenance in 5 minutes"}`, adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("broadcast status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if roomIds := envelope["data"].(map[string]any)["roomIds"].([]any); len(roomIds) == 0 {
+		t.Fatal("expected the message to be sent to the room")
+	}
+	rec, envelope = doAuthorized(t, http.MethodPost, "/admin/broadcast", `{"roomId":"","message":" "}`, adminToken)
+	if rec.Code != http.StatusUnprocessableEntity || errorCode(envelope) != ErrorCodeValidation {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusUnprocessableEntity, ErrorCodeValidation)
+	}
+
+	rec, _ = doAuthorized(t, http.MethodDelete, "/admin/rooms/"+room.Id, "", adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("end status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if roomPkg.HasRoom(room.Id) || roomPkg.GetRoomId("a1") != "" {
+		t.Fatal("expected the room and its players to be removed")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	roomPkg.Configure(roomPkg.Settings{
+		TimerDuration: roomPkg.DefaultTimerDuration,
+		WinCount:      roomPkg.DefaultWinCount,
+		EventLogDir:   t.TempDir(),
+	})
+	defer roomPkg.Configure(roomPkg.Settings{
+		TimerDuration: roomPkg.DefaultTimerDuration,
+		WinCount:      roomPkg.DefaultWinCount,
+	})
+
+	room := addRoom(t)
+	rec, _ := do(t, http.MethodPost, "/room/"+room.Id+"/addPlayer", `{"playerId":"r1","playerName":"Kiri"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if err := room.AddPlayerToRoom("r2", "Mere"); err != nil {
+		t.Fatal(err)
+	}
+	room.LockRoom()
+	room.ToggleUsedLetter("A")
+	room.ToggleUsedLetter("B")
+	room.RemoveUsedLetter("B")
+	room.SetLetterUnselectable("A")
+	room.SetNextPlayerIndex()
+	room.LeaveRoom("r1")
+	want, _ := json.Marshal(room)
+	roomPkg.RemoveRoom(room.Id)
+
+	rec, envelope := do(t, http.MethodGet, "/room/"+room.Id+"/replay", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	data := envelope["data"].(map[string]any)
+	got, _ := json.Marshal(data["room"])
+	var wantRoom, gotRoom map[string]any
+	json.Unmarshal(want, &wantRoom)
+	json.Unmarshal(got, &gotRoom)
+	delete(wantRoom, "currentPlayer")
+	delete(gotRoom, "currentPlayer")
+	if !reflect.DeepEqual(gotRoom, wantRoom) {
+		t.Fatalf("replayed room = %s, want %s", got, want)
+	}
+
+	kinds := map[string]int{}
+	for _, entry := range data["entries"].([]any) {
+		kinds[entry.(map[string]any)["kind"].(string)]++
+	}
+	if kinds[roomPkg.EntryKindCommand] != 1 || kinds[roomPkg.EntryKindEvent] == 0 {
+		t.Fatalf("entries by kind = %v, want the add-player command and the events", kinds)
+	}
+
+	rec, envelope = do(t, http.MethodGet, "/room/does-not-exist/replay", "")
+	if rec.Code != http.StatusNotFound || errorCode(envelope) != ErrorCodeNotFound {
+		t.Fatalf("status = %d, code = %q, want %d %q", rec.Code, errorCode(envelope), http.StatusNotFound, ErrorCodeNotFound)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	games.Save(&history.Game{
+		Id:           "history-game",
+		RoomId:       "history-room",
+		StartedAt:    start,
+		EndedAt:      start.Add(time.Minute),
+		Participants: []history.Participant{{PlayerId: "h1", Name: "Kiri", RoundsWon: 1}, {PlayerId: "h2", Name: "Mere"}},
+		Rounds: []history.Round{{
+			Number:       1,
+			Category:     "Animals",
+			StartedAt:    start,
+			EndedAt:      start.Add(time.Minute),
+			PlayerIds:    []string{"h1", "h2"},
+			Letters:      []history.LetterChoice{{PlayerId: "h1", Letter: "K"}},
+			Eliminations: []history.Elimination{{PlayerId: "h2", SurvivedSeconds: 20}},
+			WinnerId:     "h1",
+		}},
+		WinnerId: "h1",
+	})
+
+	rec, envelope := do(t, http.MethodGet, "/players/h1/stats", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	stats := envelope["data"].(map[string]any)
+	if stats["gamesPlayed"] != 1.0 || stats["winRate"] != 1.0 || stats["averageSurvivalSeconds"] != 60.0 {
+		t.Fatalf("stats = %v, want one game won surviving the whole round", stats)
+	}
+
+	rec, envelope = do(t, http.MethodGet, "/players/h2/games", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if played := envelope["data"].(map[string]any)["games"].([]any); len(played) != 1 {
+		t.Fatalf("games = %v, want the one game", played)
+	}
+
+	rec, envelope = do(t, http.MethodGet, "/games/history-game", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if winner := envelope["data"].(map[string]any)["winnerId"]; winner != "h1" {
+		t.Fatalf("winnerId = %v, want h1", winner)
+	}
+
+	rec, envelope = do(t, http.MethodGet, "/games/missing", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if code := envelope["error"].(map[string]any)["code"]; code != ErrorCodeNotFound {
+		t.Fatalf("code = %v, want %s", code, ErrorCodeNotFound)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	rec, envelope := do(t, http.MethodPost, "/accounts", `{"username":"kiri","password":"correct horse"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	session := envelope["data"].(map[string]any)
+	accountId := session["account"].(map[string]any)["id"].(string)
+	if session["token"] == "" || session["magicToken"] != "" {
+		t.Fatalf("session = %v, want a token and no magic token", session)
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/accounts", `{"username":"Kiri","password":"correct horse"}`)
+	if rec.Code != http.StatusConflict || errorCode(envelope) != ErrorCodeConflict {
+		t.Fatalf("status = %d, code = %q, want a conflict", rec.Code, errorCode(envelope))
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/sessions", `{"username":"kiri","password":"wrong horse"}`)
+	if rec.Code != http.StatusUnauthorized || errorCode(envelope) != ErrorCodeUnauthorized {
+		t.Fatalf("status = %d, code = %q, want unauthorized", rec.Code, errorCode(envelope))
+	}
+	rec, envelope = do(t, http.MethodPost, "/sessions", `{"username":"kiri","password":"correct horse"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	token := envelope["data"].(map[string]any)["token"].(string)
+
+	rec, envelope = doAuthorized(t, http.MethodGet, "/accounts/me", "", token)
+	if rec.Code != http.StatusOK || envelope["data"].(map[string]any)["id"] != accountId {
+		t.Fatalf("status = %d, body=%s, want the account", rec.Code, rec.Body.String())
+	}
+	if rec, _ := do(t, http.MethodGet, "/accounts/me", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d without a token", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec, envelope = do(t, http.MethodPost, "/accounts", `{"username":"mere"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	magicToken := envelope["data"].(map[string]any)["magicToken"].(string)
+	if rec, _ := do(t, http.MethodPost, "/sessions", `{"username":"mere","magicToken":"`+magicToken+`"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d signing in with the magic token", rec.Code, http.StatusCreated)
+	}
+
+	room := addRoom(t)
+	defer roomPkg.RemoveRoom(room.Id)
+	rec, _ = doAuthorized(t, http.MethodPost, "/room/"+room.Id+"/addPlayer", `{"playerId":"k1","playerName":"Kiri"}`, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if got := room.Players["k1"].AccountId; got != accountId {
+		t.Fatalf("player account = %q, want %q", got, accountId)
+	}
+	rec, _ = do(t, http.MethodPost, "/room/"+room.Id+"/addPlayer", `{"playerId":"g1","playerName":"Guest"}`)
+	if rec.Code != http.StatusCreated || room.Players["g1"].AccountId != "" {
+		t.Fatalf("status = %d, want a guest seated without an account", rec.Code)
+	}
+	rec, envelope = doAuthorized(t, http.MethodPost, "/room/"+room.Id+"/addPlayer", `{"playerId":"x1","playerName":"Nope"}`, "forged")
+	if rec.Code != http.StatusUnauthorized || errorCode(envelope) != ErrorCodeUnauthorized {
+		t.Fatalf("status = %d, code = %q, want unauthorized for an invalid token", rec.Code, errorCode(envelope))
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	board.Record(&history.Game{
+		EndedAt:  time.Now(),
+		WinnerId: "l1",
+		Participants: []history.Participant{
+			{PlayerId: "l1", AccountId: "leader", Name: "Kiri"},
+			{PlayerId: "l2", AccountId: "runner-up", Name: "Mere"},
+			{PlayerId: "l3", Name: "Guest"},
+		},
+	})
+
+	for _, window := range leaderboard.Windows {
+		rec, envelope := do(t, http.MethodGet, "/leaderboard?window="+window+"&limit=1", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d, body=%s", window, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		data := envelope["data"].(map[string]any)
+		entries := data["entries"].([]any)
+		if data["sort"] != leaderboard.SortRating || len(entries) != 1 || entries[0].(map[string]any)["accountId"] != "leader" {
+			t.Fatalf("%s: leaderboard = %v, want the winner ranked by rating", window, data)
+		}
+	}
+
+	for _, query := range []string{"window=monthly", "sort=losses", "limit=0", "limit=many"} {
+		rec, envelope := do(t, http.MethodGet, "/leaderboard?"+query, "")
+		if rec.Code != http.StatusBadRequest || errorCode(envelope) != ErrorCodeBadRequest {
+			t.Fatalf("%s: status = %d, code = %q, want a bad request", query, rec.Code, errorCode(envelope))
+		}
+	}
+}
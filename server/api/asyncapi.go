@@ -0,0 +1,106 @@
+package api
+
+import (
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+type eventDirection int
+
+const (
+	clientToServer eventDirection = iota
+	serverToClient
+)
+
+type eventDoc struct {
+	Type      types.EventType
+	Direction eventDirection
+	Summary   string
+	Payload   any
+}
+
+// documentedEvents describes every types.EventType. The AsyncAPI document is
+// generated from it and TestAsyncAPICoversEventTypes keeps the two in step.
+var documentedEvents = []eventDoc{
+	{types.EventTypeConnection, clientToServer, "Built-in socket.io event fired when a client connects", nil},
+	{types.EventTypeDisconnect, clientToServer, "Built-in socket.io event fired when a client disconnects", nil},
+	{types.EventTypeJoinRoom, clientToServer, "Join the socket.io room for a room id, sent as a bare string", ""},
+	{types.EventTypeRoomJoined, serverToClient, "A player joined the room", types.RoomJoinedPayload{}},
+	{types.EventTypeDisconnected, serverToClient, "A player disconnected from the room", types.PlayerIdPayload{}},
+	{types.EventTypeRoomLocked, serverToClient, "The room no longer accepts new players", types.RoomIdPayload{}},
+	{types.EventTypeCountdownStarted, clientToServer, "Start a round", types.RoomIdPayload{}},
+	{types.EventTypeRoundStarted, serverToClient, "A round started", types.RoundStartedPayload{}},
+	{types.EventTypeCountdownTick, serverToClient, "Seconds left in the current turn. Named countdown-tick from protocol version 2", types.CountdownTickPayload{}},
+	{types.EventTypeSelectLetter, clientToServer, "Select or deselect a letter", types.SelectLetterPayload{}},
+	{types.EventTypeLetterSelected, serverToClient, "The used letters changed. Wrapped in a usedLetters object from protocol version 2", types.UsedLettersPayload{}},
+	{types.EventTypeStartTurn, serverToClient, "The next player's turn started", types.StartTurnPayload{}},
+	{types.EventTypeEndTurn, clientToServer, "End the current turn with the selected letter, and the answer in typed answers mode", types.EndTurnPayload{}},
+	{types.EventTypeResetTimer, clientToServer, "Restart the turn timer", types.RoomIdPayload{}},
+	{types.EventTypeLeaveRoom, clientToServer, "Leave the room", types.LeaveRoomPayload{}},
+	{types.EventTypePlayerEliminated, serverToClient, "The current player ran out of time", types.PlayerEliminatedPayload{}},
+	{types.EventTypeRoundEnded, serverToClient, "One player is left in the round", types.RoundEndedPayload{}},
+	{types.EventTypeGameEnded, serverToClient, "A player has won the game", types.GameEndedPayload{}},
+	{types.EventTypeJoinQueue, clientToServer, "Queue for a quick match", types.JoinQueuePayload{}},
+	{types.EventTypeLeaveQueue, clientToServer, "Leave the quick match queue", nil},
+	{types.EventTypeQueuePosition, serverToClient, "The player's position in the quick match queue", types.QueuePositionPayload{}},
+	{types.EventTypeMatchFound, serverToClient, "A room was created for the queued player", types.MatchFoundPayload{}},
+	{types.EventTypeQueueTimeout, serverToClient, "No match was found in time", types.PlayerIdPayload{}},
+	{types.EventTypeHello, clientToServer, "Negotiate a protocol version, also accepted in the handshake auth object", types.HelloPayload{}},
+	{types.EventTypeServerHello, serverToClient, "The id the client plays as, and the negotiated protocol version and features", types.ServerHelloPayload{}},
+	{types.EventTypeProtocolError, serverToClient, "The client's protocol version is not supported, the server disconnects it", types.ProtocolErrorPayload{}},
+	{types.EventTypeUnauthorized, serverToClient, "The client's session token is invalid or expired, the server disconnects it", types.UnauthorizedPayload{}},
+	{types.EventTypeRateLimited, serverToClient, "The client sent an event too often and it was dropped. Clients that keep at it are disconnected", types.RateLimitedPayload{}},
+	{types.EventTypeServerShuttingDown, serverToClient, "The server is shutting down and will disconnect the room's clients when the countdown ends", types.ServerShuttingDownPayload{}},
+	{types.EventTypeRoomClosed, serverToClient, "An operator ended the room, its clients have been removed from it", types.RoomClosedPayload{}},
+	{types.EventTypePlayerKicked, serverToClient, "An operator removed a player from the room", types.PlayerKickedPayload{}},
+	{types.EventTypeSystemMessage, serverToClient, "A message from the server's operators", types.SystemMessagePayload{}},
+	{types.EventTypeSetIsInTextMode, clientToServer, "Turn typed answers on or off before the room starts playing. Echoed to the rest of the room", types.TextModePayload{}},
+	{types.EventTypeChallengeAnswer, clientToServer, "Dispute the last typed answer, within a few seconds of it being given. The turn timer is paused while the room votes", types.RoomIdPayload{}},
+	{types.EventTypeChallengeStarted, serverToClient, "An answer was challenged and the other players still in the round are asked to vote on it. The challenger has voted to strike it and the player who gave it does not vote", types.ChallengeStartedPayload{}},
+	{types.EventTypeCastVote, clientToServer, "Vote to keep or strike a challenged answer", types.CastVotePayload{}},
+	{types.EventTypeChallengeResolved, serverToClient, "The vote on a challenged answer ended. A struck answer eliminates the player who gave it, and ties keep it", types.ChallengeResolvedPayload{}},
+	{types.EventTypeAnswerRejected, serverToClient, "The player's typed answer did not start with their letter, was already used or, when the server rejects unknown words, is not in its dictionary. Their turn carries on and the timer is not reset", types.AnswerRejectedPayload{}},
+	{types.EventTypeTurnRefused, serverToClient, "The player tried to choose a letter or end a turn when it is not their turn, or to end or reset their turn while an answer is being voted on, so it carries on", types.TurnRefusedPayload{}},
+}
+
+func AsyncAPISpec() map[string]any {
+	schemas := newSchemaRegistry("#/components/schemas/", false)
+	channels := map[string]any{}
+	for _, event := range documentedEvents {
+		description := event.Summary
+		if event.Direction == clientToServer && event.Payload != nil && event.Type != types.EventTypeJoinRoom {
+			description += ". The payload is sent as a JSON-encoded string, or as an object from protocol version 2"
+		}
+		message := map[string]any{
+			"name":        string(event.Type),
+			"summary":     event.Summary,
+			"description": description,
+		}
+		if event.Payload != nil {
+			message["payload"] = schemas.schemaOf(event.Payload)
+		}
+		operation := "subscribe"
+		if event.Direction == clientToServer {
+			operation = "publish"
+		}
+		channels[string(event.Type)] = map[string]any{
+			operation: map[string]any{
+				"operationId": string(event.Type),
+				"message":     message,
+			},
+		}
+	}
+
+	return map[string]any{
+		"asyncapi": "2.6.0",
+		"info": map[string]any{
+			"title":       "quik socket.io events",
+			"version":     APIVersion,
+			"description": "Events exchanged over socket.io at /socket.io/. publish operations are sent by clients, subscribe operations are emitted by the server.",
+		},
+		"defaultContentType": "application/json",
+		"channels":           channels,
+		"components": map[string]any{
+			"schemas": schemas.components,
+		},
+	}
+}
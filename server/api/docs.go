@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+type DocsHandler struct {
+	once     sync.Once
+	openAPI  []byte
+	asyncAPI []byte
+}
+
+func (h *DocsHandler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.build()
+	writeDocument(w, h.openAPI)
+}
+
+func (h *DocsHandler) AsyncAPI(w http.ResponseWriter, r *http.Request) {
+	h.build()
+	writeDocument(w, h.asyncAPI)
+}
+
+func (h *DocsHandler) build() {
+	h.once.Do(func() {
+		var err error
+		if h.openAPI, err = json.MarshalIndent(OpenAPISpec(), "", "  "); err != nil {
+			slog.Error("unable to encode OpenAPI document", logging.Err(err))
+		}
+		if h.asyncAPI, err = json.MarshalIndent(AsyncAPISpec(), "", "  "); err != nil {
+			slog.Error("unable to encode AsyncAPI document", logging.Err(err))
+		}
+	})
+}
+
+func writeDocument(w http.ResponseWriter, document []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+// undocumentedRoutes are registered in main.go but are not part of the JSON
+// API.
+var undocumentedRoutes = []string{
+	"/socket.io/",
+	"GET /ws",
+	"GET /metrics",
+	"GET /openapi.json",
+	"GET /asyncapi.json",
+}
+
+// registeredRoutes returns the patterns passed to router.HandleFunc and
+// router.Handle in main.go.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "../main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := []string{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") || len(call.Args) == 0 {
+			return true
+		}
+		if lit, ok := call.Args[0].(*ast.BasicLit); ok {
+			pattern, _ := strconv.Unquote(lit.Value)
+			routes = append(routes, pattern)
+		}
+		return true
+	})
+	return routes
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	documented := []string{}
+	for _, route := range documentedRoutes {
+		documented = append(documented, route.Method+" "+route.Path)
+	}
+
+	registered := []string{}
+	for _, pattern := range registeredRoutes(t) {
+		if !slices.Contains(undocumentedRoutes, pattern) {
+			registered = append(registered, pattern)
+		}
+	}
+
+	slices.Sort(documented)
+	slices.Sort(registered)
+	if !slices.Equal(documented, registered) {
+		t.Fatalf("documented routes %v do not match routes registered in main.go %v", documented, registered)
+	}
+}
+
+func TestOpenAPISchemasMatchResponses(t *testing.T) {
+	spec := OpenAPISpec()
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]Schema)
+
+	for _, route := range documentedRoutes {
+		for _, v := range []any{route.Request, route.Response} {
+			if v == nil {
+				continue
+			}
+			name := reflect.TypeOf(v).Name()
+			schema, ok := schemas[name]
+			if !ok {
+				t.Fatalf("%s %s: no schema for %s", route.Method, route.Path, name)
+			}
+			encoded, _ := json.Marshal(v)
+			var fields map[string]any
+			json.Unmarshal(encoded, &fields)
+			properties := schema["properties"].(Schema)
+			for field := range fields {
+				if _, ok := properties[field]; !ok {
+					t.Errorf("%s: field %q is missing from the schema", name, field)
+				}
+			}
+			if len(properties) != len(fields) {
+				t.Errorf("%s: schema has %d properties, type encodes %d fields", name, len(properties), len(fields))
+			}
+		}
+	}
+}
+
+func TestAsyncAPICoversEventTypes(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../types/types.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventTypes := []string{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); ok && ident.Name == "EventType" {
+				eventType, _ := strconv.Unquote(value.Values[0].(*ast.BasicLit).Value)
+				eventTypes = append(eventTypes, eventType)
+			}
+		}
+	}
+
+	channels := AsyncAPISpec()["channels"].(map[string]any)
+	for _, eventType := range eventTypes {
+		if _, ok := channels[eventType]; !ok {
+			t.Errorf("event type %q is not documented", eventType)
+		}
+	}
+	if len(channels) != len(eventTypes) {
+		t.Errorf("documented %d events, types.go declares %d", len(channels), len(eventTypes))
+	}
+	if _, ok := channels[string(types.EventTypeRoomJoined)].(map[string]any)["subscribe"]; !ok {
+		t.Error("expected room-joined to be emitted by the server")
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	handler := &DocsHandler{}
+	for path, serve := range map[string]http.HandlerFunc{
+		"/openapi.json":  handler.OpenAPI,
+		"/asyncapi.json": handler.AsyncAPI,
+	} {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", path, rec.Code)
+		}
+		var document map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &document); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !strings.Contains(rec.Body.String(), "/room/{roomId}") && path == "/openapi.json" {
+			t.Fatalf("%s: expected the room route to be documented", path)
+		}
+	}
+}
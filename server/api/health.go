@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// HealthHandler serves the liveness and readiness probes. Checks are named
+// dependencies the server needs to take new players, each returning an
+// error while it is unavailable.
+type HealthHandler struct {
+	Checks map[string]func() error
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// Healthz reports that the process is up and serving requests.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &HealthResponse{Status: "ok"})
+}
+
+// Readyz reports whether every check passes, so that load balancers stop
+// sending players to a server that is draining or has lost its room store.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.Checks))
+	for name := range h.Checks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	failures := []string{}
+	for _, name := range names {
+		if err := h.Checks[name](); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failures) > 0 {
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeNotReady,
+			errors.New("not ready: "+strings.Join(failures, "; ")))
+		return
+	}
+	writeJSON(w, http.StatusOK, &HealthResponse{Status: "ready"})
+}
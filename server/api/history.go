@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/campbell-rehu/quik-be/history"
+)
+
+// HistoryHandler serves completed games and the stats built from them.
+// Players are identified by the id they played under.
+type HistoryHandler struct {
+	Games history.Store
+}
+
+type GamesResponse struct {
+	Games []*history.Game `json:"games"`
+}
+
+// PlayerStats returns a player's games played, win rate, average survival
+// time and favourite letters.
+func (h *HistoryHandler) PlayerStats(w http.ResponseWriter, r *http.Request) {
+	playerId := r.PathValue("playerId")
+	games, err := h.Games.PlayerGames(playerId)
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history.Stats(playerId, games))
+}
+
+// PlayerGames lists the games a player took part in, newest first.
+func (h *HistoryHandler) PlayerGames(w http.ResponseWriter, r *http.Request) {
+	games, err := h.Games.PlayerGames(r.PathValue("playerId"))
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &GamesResponse{Games: games})
+}
+
+// Game returns a completed game with its rounds, letters and eliminations.
+func (h *HistoryHandler) Game(w http.ResponseWriter, r *http.Request) {
+	game, err := h.Games.Game(r.PathValue("gameId"))
+	if err != nil {
+		writeHistoryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, game)
+}
+
+func writeHistoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, history.ErrGameNotFound) {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrorCodeInternal, err)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/leaderboard"
+)
+
+// MaxLeaderboardLimit caps the entries returned by one leaderboard request.
+const MaxLeaderboardLimit = 100
+
+type LeaderboardHandler struct {
+	Board *leaderboard.Board
+}
+
+// LeaderboardResponse is a window's standings. StartsAt is the start of
+// the week for the weekly window and the zero time for all-time.
+type LeaderboardResponse struct {
+	Window   string              `json:"window"`
+	Sort     string              `json:"sort"`
+	StartsAt time.Time           `json:"startsAt"`
+	Entries  []leaderboard.Entry `json:"entries"`
+}
+
+// Leaderboard ranks accounts, with optional window (all-time or weekly),
+// sort (rating, wins or winRate) and limit query parameters.
+func (h *LeaderboardHandler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	window := query.Get("window")
+	if window == "" {
+		window = leaderboard.WindowAllTime
+	}
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = leaderboard.SortRating
+	}
+	limit := MaxLeaderboardLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLeaderboardLimit {
+			writeError(w, http.StatusBadRequest, ErrorCodeBadRequest,
+				fmt.Errorf("limit must be a number from 1 to %d", MaxLeaderboardLimit))
+			return
+		}
+		limit = n
+	}
+
+	entries, startsAt, err := h.Board.Standings(window, sortBy, limit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &LeaderboardResponse{
+		Window:   window,
+		Sort:     sortBy,
+		StartsAt: startsAt,
+		Entries:  entries,
+	})
+}
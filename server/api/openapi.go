@@ -0,0 +1,331 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/campbell-rehu/quik-be/history"
+	"github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+const APIVersion = "1.0.0"
+
+type routeDoc struct {
+	Method   string
+	Path     string
+	Summary  string
+	Request  any
+	Response any
+	Status   int
+	Errors   []int
+
+	// RequestOptional is set when the request body may be left out.
+	RequestOptional bool
+}
+
+// documentedRoutes lists every HTTP route registered in main.go. The
+// OpenAPI document is generated from it and TestOpenAPIMatchesRoutes keeps
+// the two in step.
+var documentedRoutes = []routeDoc{
+	{
+		Method:   http.MethodPost,
+		Path:     "/room",
+		Summary:  "Create a new room",
+		Response: room.Room{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/room/{roomId}",
+		Summary:  "Look up a room to join it",
+		Response: room.Room{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/room/{roomId}/addPlayer",
+		Summary:  "Seat a player in a room, kept against their account when they send a session bearer token. The name is normalized and given a numbered suffix if another player in the room has it",
+		Request:  AddPlayerRequest{},
+		Response: AddPlayerRequest{},
+		Status:   http.StatusCreated,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusNotFound,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusUnprocessableEntity,
+			http.StatusLocked,
+			http.StatusConflict,
+		},
+	},
+	{
+		Method:          http.MethodPost,
+		Path:            "/room/{roomId}/bots",
+		Summary:         "Seat a bot player in a room, for solo practice or to fill it. Not found when bots are disabled",
+		Request:         AddBotRequest{},
+		RequestOptional: true,
+		Response:        types.Player{},
+		Status:          http.StatusCreated,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusNotFound,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusUnprocessableEntity,
+			http.StatusLocked,
+			http.StatusConflict,
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/room/{roomId}/replay",
+		Summary:  "Get a room's event log and the state it rebuilds, kept on disk after the room ends when an event log directory is configured",
+		Response: ReplayResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/matchmaking",
+		Summary:  "Queue a player for a quick match, kept against their account when they send a session bearer token",
+		Request:  JoinQueueRequest{},
+		Response: JoinQueueResponse{},
+		Status:   http.StatusAccepted,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusConflict,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusUnprocessableEntity,
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/accounts",
+		Summary:  "Create a player account and sign in to it. Without a password the account signs in with the magic token returned",
+		Request:  CreateAccountRequest{},
+		Response: SessionResponse{},
+		Status:   http.StatusCreated,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusConflict,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusUnprocessableEntity,
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/accounts/me",
+		Summary:  "Get the account of the session bearer token",
+		Response: AccountResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/sessions",
+		Summary:  "Sign in to an account with its password or magic token, getting a session token",
+		Request:  CreateSessionRequest{},
+		Response: SessionResponse{},
+		Status:   http.StatusCreated,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/players/{playerId}/stats",
+		Summary:  "Get a player's games played, win rate, average survival time and favourite letters, by player id or account id",
+		Response: history.PlayerStats{},
+		Status:   http.StatusOK,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/players/{playerId}/games",
+		Summary:  "List the completed games a player took part in, newest first, by player id or account id",
+		Response: GamesResponse{},
+		Status:   http.StatusOK,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/games/{gameId}",
+		Summary:  "Get a completed game with its rounds, letters chosen and elimination order",
+		Response: history.Game{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/leaderboard",
+		Summary:  "Rank accounts by rating, wins or win rate, with optional window (all-time or weekly), sort (rating, wins or winRate) and limit query parameters",
+		Response: LeaderboardResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/healthz",
+		Summary:  "Report that the server is up",
+		Response: HealthResponse{},
+		Status:   http.StatusOK,
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/readyz",
+		Summary:  "Report whether the server can take new players, false while it is draining or its room store is unavailable",
+		Response: HealthResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusServiceUnavailable},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/admin/rooms",
+		Summary:  "List this server's rooms with their phase, players and timer. Requires the admin bearer token",
+		Response: AdminRoomsResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/admin/rooms/{roomId}",
+		Summary:  "Force-end a room, with an optional reason query parameter. Requires the admin bearer token",
+		Response: EndRoomResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/admin/rooms/{roomId}/players/{playerId}",
+		Summary:  "Kick a player from a room, with an optional reason query parameter. Requires the admin bearer token",
+		Response: KickPlayerResponse{},
+		Status:   http.StatusOK,
+		Errors:   []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/admin/broadcast",
+		Summary:  "Send a system message to a room, or to every room when roomId is empty. Requires the admin bearer token",
+		Request:  BroadcastRequest{},
+		Response: BroadcastResponse{},
+		Status:   http.StatusOK,
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusUnprocessableEntity,
+		},
+	},
+}
+
+var pathParamRegexp = regexp.MustCompile(`{([^}]+)}`)
+
+func OpenAPISpec() map[string]any {
+	schemas := newSchemaRegistry("#/components/schemas/", true)
+	schemas.components["ErrorEnvelope"] = Schema{
+		"type":       "object",
+		"properties": Schema{"error": schemas.schemaOf(ErrorResponse{})},
+		"required":   []string{"error"},
+	}
+
+	paths := map[string]map[string]any{}
+	for _, route := range documentedRoutes {
+		operation := map[string]any{
+			"summary":     route.Summary,
+			"operationId": operationId(route),
+		}
+
+		params := []map[string]any{}
+		for _, match := range pathParamRegexp.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   Schema{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": !route.RequestOptional,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schemaOf(route.Request)},
+				},
+			}
+		}
+
+		responses := map[string]any{
+			fmt.Sprint(route.Status): map[string]any{
+				"description": http.StatusText(route.Status),
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": Schema{
+							"type":       "object",
+							"properties": Schema{"data": schemas.schemaOf(route.Response)},
+							"required":   []string{"data"},
+						},
+					},
+				},
+			},
+		}
+		for _, status := range append(slices.Clone(route.Errors), http.StatusInternalServerError) {
+			responses[fmt.Sprint(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": Schema{"$ref": "#/components/schemas/ErrorEnvelope"},
+					},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]any{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "quik HTTP API",
+			"version": APIVersion,
+			"description": "Requests over the per-address rate limit are refused with 429 Too Many Requests, " +
+				"error code " + ErrorCodeRateLimited + " and a Retry-After header. " +
+				"When CSRF protection is on, POST and DELETE requests from pages on origins that are not allowed " +
+				"are refused with 403 Forbidden and error code " + ErrorCodeOriginDenied + ".",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+		},
+	}
+}
+
+func operationId(route routeDoc) string {
+	words := strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}'
+	})
+	id := strings.ToLower(route.Method)
+	for _, word := range words {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return id
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/campbell-rehu/quik-be/envelope"
+	"github.com/campbell-rehu/quik-be/names"
+	"github.com/campbell-rehu/quik-be/origins"
+	"github.com/campbell-rehu/quik-be/ratelimit"
+)
+
+// MaxRequestBodyBytes caps the size of any JSON request body.
+const MaxRequestBodyBytes = 1 << 16
+
+const (
+	ErrorCodeBadRequest      = "bad_request"
+	ErrorCodeValidation      = "validation_failed"
+	ErrorCodeNameNotAllowed  = "name_not_allowed"
+	ErrorCodeNotFound        = "not_found"
+	ErrorCodeRoomLocked      = "room_locked"
+	ErrorCodeRoomFull        = "room_full"
+	ErrorCodeTooManyRooms    = "too_many_rooms"
+	ErrorCodeConflict        = "conflict"
+	ErrorCodeUnauthorized    = "unauthorized"
+	ErrorCodeForbidden       = "forbidden"
+	ErrorCodeNotReady        = "not_ready"
+	ErrorCodeBodyTooLarge    = "body_too_large"
+	ErrorCodeInternal        = "internal_error"
+	ErrorCodeUnsupportedType = "unsupported_media_type"
+	ErrorCodeRateLimited     = ratelimit.ErrorCode
+	ErrorCodeOriginDenied    = origins.ErrorCode
+)
+
+// Response is the envelope every API route responds with. Exactly one of
+// Data and Error is set.
+type Response = envelope.Response
+
+type ErrorResponse = envelope.ErrorResponse
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	envelope.Write(w, status, &Response{Data: data})
+}
+
+func writeError(w http.ResponseWriter, status int, code string, err error) {
+	envelope.WriteError(w, status, code, err)
+}
+
+// writeValidationError reports a request that was understood but cannot be
+// used, singling out names refused by the name filter.
+func writeValidationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, names.ErrNameNotAllowed) {
+		writeError(w, http.StatusUnprocessableEntity, ErrorCodeNameNotAllowed, err)
+		return
+	}
+	writeError(w, http.StatusUnprocessableEntity, ErrorCodeValidation, err)
+}
+
+// decodeJSON reads a size-limited JSON body into v, writing an error
+// response and returning false when the body cannot be used.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		writeError(w, http.StatusUnsupportedMediaType, ErrorCodeUnsupportedType,
+			fmt.Errorf("content type %s is not supported, use application/json", contentType))
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil && decoder.More() {
+		err = errors.New("request body must contain a single JSON object")
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeError(w, http.StatusRequestEntityTooLarge, ErrorCodeBodyTooLarge,
+				fmt.Errorf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+		case errors.Is(err, io.EOF):
+			writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, errors.New("request body must not be empty"))
+		default:
+			writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, fmt.Errorf("invalid request body: %w", err))
+		}
+		return false
+	}
+	return true
+}
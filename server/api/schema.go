@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type Schema = map[string]any
+
+// schemaRegistry builds JSON schemas from Go types. Named struct types are
+// collected as components and referenced by name so that the documents stay
+// readable and match the handler and payload types exactly.
+type schemaRegistry struct {
+	refPrefix  string
+	components map[string]Schema
+	// openAPI selects the OpenAPI 3.0 dialect, which marks pointers as
+	// nullable instead of allowing a "null" type.
+	openAPI bool
+}
+
+func newSchemaRegistry(refPrefix string, openAPI bool) *schemaRegistry {
+	return &schemaRegistry{
+		refPrefix:  refPrefix,
+		components: make(map[string]Schema),
+		openAPI:    openAPI,
+	}
+}
+
+func (s *schemaRegistry) schemaOf(v any) Schema {
+	if v == nil {
+		return Schema{}
+	}
+	return s.schemaFor(reflect.TypeOf(v))
+}
+
+func (s *schemaRegistry) schemaFor(t reflect.Type) Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		// Any JSON value.
+		return Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.schemaFor(t.Elem())
+		if s.openAPI {
+			return Schema{"allOf": []Schema{schema}, "nullable": true}
+		}
+		return Schema{"oneOf": []Schema{schema, {"type": "null"}}}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate.
+			s.components[t.Name()] = Schema{}
+			s.components[t.Name()] = s.structSchema(t)
+		}
+		return Schema{"$ref": s.refPrefix + t.Name()}
+	default:
+		return Schema{}
+	}
+}
+
+func (s *schemaRegistry) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		properties[name] = s.schemaFor(field.Type)
+		if !omitempty {
+			required = append(required, name)
+		}
+	}
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func jsonFieldName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
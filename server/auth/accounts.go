@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength is the most bcrypt will hash.
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrAccountExists      = errors.New("account already exists")
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrInvalidCredentials = errors.New("invalid username or credentials")
+	ErrInvalidAccount     = errors.New("invalid account")
+)
+
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+// Account is a player's persistent identity. An account signs in either
+// with a password or, when created without one, with the magic token it
+// was issued. Only hashes of either are kept.
+type Account struct {
+	Id             string    `json:"id"`
+	Username       string    `json:"username"`
+	CreatedAt      time.Time `json:"createdAt"`
+	PasswordHash   []byte    `json:"passwordHash,omitempty"`
+	MagicTokenHash string    `json:"magicTokenHash,omitempty"`
+}
+
+// Store keeps accounts. Usernames are unique regardless of case.
+type Store interface {
+	Create(account *Account) error
+	Account(accountId string) (*Account, error)
+	AccountByUsername(username string) (*Account, error)
+}
+
+// Accounts creates accounts and checks their credentials.
+type Accounts struct {
+	Store Store
+}
+
+// Register creates an account. Without a password the account is
+// passwordless and the magic token it signs in with is returned, once.
+func (a *Accounts) Register(username, password string) (*Account, string, error) {
+	if !usernameRegexp.MatchString(username) {
+		return nil, "", fmt.Errorf("%w: username must be 3 to 32 letters, digits, _ or -", ErrInvalidAccount)
+	}
+	account := &Account{Id: newId(), Username: username, CreatedAt: time.Now().UTC()}
+	magicToken := ""
+	if password == "" {
+		magicToken = newSecret()
+		account.MagicTokenHash = hashToken(magicToken)
+	} else {
+		if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+			return nil, "", fmt.Errorf("%w: password must be %d to %d bytes", ErrInvalidAccount, MinPasswordLength, MaxPasswordLength)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, "", err
+		}
+		account.PasswordHash = hash
+	}
+	if err := a.Store.Create(account); err != nil {
+		return nil, "", err
+	}
+	return account, magicToken, nil
+}
+
+// Login checks a username against a password or magic token.
+func (a *Accounts) Login(username, password, magicToken string) (*Account, error) {
+	account, err := a.Store.AccountByUsername(username)
+	if errors.Is(err, ErrAccountNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case password != "" && account.PasswordHash != nil:
+		if bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password)) == nil {
+			return account, nil
+		}
+	case magicToken != "" && account.MagicTokenHash != "":
+		if subtle.ConstantTimeCompare([]byte(hashToken(magicToken)), []byte(account.MagicTokenHash)) == 1 {
+			return account, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+func newId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hashToken hashes a magic token. Tokens are random, so unlike passwords
+// they need no salt or slow hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryStore keeps accounts for as long as the server runs.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	accounts   map[string]*Account
+	byUsername map[string]*Account
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:   make(map[string]*Account),
+		byUsername: make(map[string]*Account),
+	}
+}
+
+func (s *MemoryStore) Create(account *Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(account)
+}
+
+func (s *MemoryStore) add(account *Account) error {
+	key := strings.ToLower(account.Username)
+	if _, ok := s.byUsername[key]; ok {
+		return fmt.Errorf("%w: username=%s", ErrAccountExists, account.Username)
+	}
+	s.accounts[account.Id] = account
+	s.byUsername[key] = account
+	return nil
+}
+
+func (s *MemoryStore) Account(accountId string) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if account, ok := s.accounts[accountId]; ok {
+		return account, nil
+	}
+	return nil, fmt.Errorf("%w: id=%s", ErrAccountNotFound, accountId)
+}
+
+func (s *MemoryStore) AccountByUsername(username string) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if account, ok := s.byUsername[strings.ToLower(username)]; ok {
+		return account, nil
+	}
+	return nil, fmt.Errorf("%w: username=%s", ErrAccountNotFound, username)
+}
+
+// FileStore appends accounts to a JSON Lines file and keeps them in memory
+// for lookups, loading the file's accounts when it is opened.
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var account Account
+		if err := json.Unmarshal(scanner.Bytes(), &account); err != nil {
+			return nil, fmt.Errorf("%s is corrupt at line %d: %w", path, line, err)
+		}
+		if err := s.add(&account); err != nil {
+			return nil, fmt.Errorf("%s is corrupt at line %d: %w", path, line, err)
+		}
+	}
+	return s, scanner.Err()
+}
+
+func (s *FileStore) Create(account *Account) error {
+	line, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byUsername[strings.ToLower(account.Username)]; ok {
+		return fmt.Errorf("%w: username=%s", ErrAccountExists, account.Username)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.add(account)
+}
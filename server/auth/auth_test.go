@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccounts(t *testing.T) {
+	accounts := &Accounts{Store: NewMemoryStore()}
+	account, magicToken, err := accounts.Register("kiri", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if magicToken != "" || string(account.PasswordHash) == "correct horse" {
+		t.Fatalf("account = %+v, magic token = %q, want a hashed password and no magic token", account, magicToken)
+	}
+	if _, _, err := accounts.Register("KIRI", "another one"); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("err = %v, want ErrAccountExists", err)
+	}
+	if _, err := accounts.Login("Kiri", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accounts.Login("kiri", "wrong horse", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	passwordless, magicToken, err := accounts.Register("mere", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if magicToken == "" {
+		t.Fatal("want a magic token for a passwordless account")
+	}
+	if got, err := accounts.Login("mere", "", magicToken); err != nil || got.Id != passwordless.Id {
+		t.Fatalf("login = %v, %v, want the account", got, err)
+	}
+	if _, err := accounts.Login("mere", "", "guess"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := accounts.Login("nobody", "correct horse", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	for _, username := range []string{"ab", "has space", ""} {
+		if _, _, err := accounts.Register(username, "correct horse"); !errors.Is(err, ErrInvalidAccount) {
+			t.Errorf("username %q: err = %v, want ErrInvalidAccount", username, err)
+		}
+	}
+	if _, _, err := accounts.Register("short", "abc"); !errors.Is(err, ErrInvalidAccount) {
+		t.Fatalf("err = %v, want ErrInvalidAccount", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	sessions := NewSessions("secret", time.Hour)
+	account := &Account{Id: "a1", Username: "kiri"}
+	token, expiresAt, err := sessions.Issue(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := sessions.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.AccountId != "a1" || claims.Username != "kiri" || claims.ExpiresAt != expiresAt.Unix() {
+		t.Fatalf("claims = %+v, want the account's", claims)
+	}
+
+	if _, err := NewSessions("other", time.Hour).Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken for another secret", err)
+	}
+	if _, err := sessions.Verify(token + "x"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken for a tampered token", err)
+	}
+	sessions.now = func() time.Time { return expiresAt }
+	if _, err := sessions.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("err = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	sessions := NewSessions("secret", time.Hour)
+	token, _, _ := sessions.Issue(&Account{Id: "a1", Username: "kiri"})
+	tests := []struct {
+		name    string
+		header  string
+		account string
+		err     error
+	}{
+		{"guest", "", "", nil},
+		{"signed in", "Bearer " + token, "a1", nil},
+		{"not a bearer token", "Basic " + token, "", ErrInvalidToken},
+		{"invalid token", "Bearer nope", "", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			claims, err := sessions.FromRequest(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			got := ""
+			if claims != nil {
+				got = claims.AccountId
+			}
+			if got != tt.account {
+				t.Fatalf("account = %q, want %q", got, tt.account)
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := &Accounts{Store: store}
+	account, _, err := accounts.Register("kiri", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Account(account.Id); err != nil || got.Username != "kiri" {
+		t.Fatalf("account = %v, %v, want it as saved", got, err)
+	}
+	if _, err := (&Accounts{Store: reopened}).Login("kiri", "correct horse", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Create(&Account{Id: "other", Username: "Kiri"}); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("err = %v, want ErrAccountExists", err)
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultSessionTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidToken = errors.New("invalid session token")
+	ErrTokenExpired = errors.New("session token expired")
+)
+
+// Claims identify the account a session token was issued to.
+type Claims struct {
+	AccountId string `json:"sub"`
+	Username  string `json:"name"`
+	// ExpiresAt is in Unix seconds.
+	ExpiresAt int64 `json:"exp"`
+}
+
+// Sessions issues and verifies session tokens, which are the claims in
+// base64url-encoded JSON followed by their HMAC-SHA256 signature. Every
+// server that verifies tokens must share the secret.
+type Sessions struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSessions returns Sessions signing with secret. Without a secret a
+// random one is used, so tokens only last until the server restarts.
+func NewSessions(secret string, ttl time.Duration) *Sessions {
+	key := []byte(secret)
+	if secret == "" {
+		key = []byte(newSecret())
+	}
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &Sessions{secret: key, ttl: ttl, now: time.Now}
+}
+
+// Issue signs a session token for the account, returning it with its
+// expiry.
+func (s *Sessions) Issue(account *Account) (string, time.Time, error) {
+	expiresAt := s.now().Add(s.ttl).Truncate(time.Second)
+	payload, err := json.Marshal(&Claims{
+		AccountId: account.Id,
+		Username:  account.Username,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), expiresAt, nil
+}
+
+// Verify checks a session token's signature and expiry, returning its
+// claims.
+func (s *Sessions) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.AccountId == "" {
+		return nil, ErrInvalidToken
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, fmt.Errorf("%w: account=%s", ErrTokenExpired, claims.AccountId)
+	}
+	return &claims, nil
+}
+
+// FromRequest verifies the bearer token a request carries. Requests without
+// one are from guests and return nil claims with no error.
+func (s *Sessions) FromRequest(r *http.Request) (*Claims, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, nil
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return s.Verify(token)
+}
+
+func (s *Sessions) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,143 @@
+// Package bot decides the moves of the players the server plays itself.
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/words"
+)
+
+// Difficulties bots can play at.
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// IdPrefix starts the player id of every bot.
+const IdPrefix = "bot-"
+
+var (
+	ErrUnknownDifficulty = errors.New("unknown bot difficulty")
+	ErrDisabled          = errors.New("bots are not enabled")
+)
+
+// Level is how well a bot plays. The time it takes to answer is drawn
+// from a normal distribution, and it answers no sooner than MinDelay.
+type Level struct {
+	MeanDelay   time.Duration `yaml:"meanDelay" toml:"meanDelay"`
+	DelayStdDev time.Duration `yaml:"delayStdDev" toml:"delayStdDev"`
+	MinDelay    time.Duration `yaml:"minDelay" toml:"minDelay"`
+	// FailChance is the chance, from 0 to 1, that the bot cannot think of
+	// an answer and runs out of time.
+	FailChance float64 `yaml:"failChance" toml:"failChance"`
+}
+
+// DefaultLevels are the built-in difficulties, for 10 second turns.
+func DefaultLevels() map[string]Level {
+	return map[string]Level{
+		DifficultyEasy:   {MeanDelay: 6 * time.Second, DelayStdDev: 2 * time.Second, MinDelay: 2 * time.Second, FailChance: 0.25},
+		DifficultyMedium: {MeanDelay: 4 * time.Second, DelayStdDev: 1500 * time.Millisecond, MinDelay: 1500 * time.Millisecond, FailChance: 0.1},
+		DifficultyHard:   {MeanDelay: 2500 * time.Millisecond, DelayStdDev: 800 * time.Millisecond, MinDelay: time.Second, FailChance: 0.03},
+	}
+}
+
+// names are given to bots in turn.
+var names = []string{"Botany", "Sprocket", "Gizmo", "Widget", "Pixel", "Circuit", "Cog", "Dynamo"}
+
+// Bot is one bot player.
+type Bot struct {
+	Id         string
+	Name       string
+	Difficulty string
+	level      Level
+	words      *words.List
+
+	mu   sync.Mutex
+	rand *mathrand.Rand
+}
+
+// Move is what a bot does on its turn: after Delay it answers with Word,
+// choosing Letter. Failed moves have no answer, and the bot waits for its
+// time to run out.
+type Move struct {
+	Letter string
+	Word   string
+	Delay  time.Duration
+	Failed bool
+}
+
+// New creates a bot playing at one of levels, answering with words from
+// list.
+func New(difficulty string, levels map[string]Level, list *words.List) (*Bot, error) {
+	level, ok := levels[difficulty]
+	if !ok {
+		return nil, fmt.Errorf("%w: difficulty=%s", ErrUnknownDifficulty, difficulty)
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	r := mathrand.New(mathrand.NewSource(int64(binary.LittleEndian.Uint64(b[4:]))))
+	return &Bot{
+		Id:         IdPrefix + hex.EncodeToString(b[:4]),
+		Name:       names[r.Intn(len(names))],
+		Difficulty: difficulty,
+		level:      level,
+		words:      list,
+		rand:       r,
+	}, nil
+}
+
+// IsBot reports whether a player id belongs to a bot.
+func IsBot(playerId string) bool {
+	return strings.HasPrefix(playerId, IdPrefix)
+}
+
+// Move picks the bot's answer for a turn in the category, from the letters
+// still free to choose. It fails when the bot knows no word for any of
+// them.
+func (b *Bot) Move(category string, free []string) Move {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	move := Move{Delay: b.delay()}
+	if b.rand.Float64() < b.level.FailChance {
+		move.Failed = true
+		return move
+	}
+	letters := slices.Clone(free)
+	b.rand.Shuffle(len(letters), func(i, j int) { letters[i], letters[j] = letters[j], letters[i] })
+	for _, letter := range letters {
+		if words := b.words.Starting(category, letter); len(words) > 0 {
+			move.Letter = letter
+			move.Word = words[b.rand.Intn(len(words))]
+			return move
+		}
+	}
+	move.Failed = true
+	return move
+}
+
+// Vouch reports whether the bot knows a challenged answer in the category,
+// and so votes to keep it, and how long it takes to vote. Bots do not vote
+// on answers they do not know.
+func (b *Bot) Vouch(category, answer string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	known := slices.ContainsFunc(b.words.Words(category), func(word string) bool {
+		return strings.EqualFold(word, answer)
+	})
+	return b.delay(), known
+}
+
+func (b *Bot) delay() time.Duration {
+	d := time.Duration(b.rand.NormFloat64()*float64(b.level.DelayStdDev)) + b.level.MeanDelay
+	return max(d, b.level.MinDelay)
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/words"
+)
+
+func newBot(t *testing.T, level Level) *Bot {
+	t.Helper()
+	list := words.New()
+	list.Add("Fruit", "Apple", "Banana", "Cherry")
+	b, err := New("test", map[string]Level{"test": level}, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNew(t *testing.T) {
+	b := newBot(t, Level{})
+	if !IsBot(b.Id) || b.Name == "" || b.Difficulty != "test" {
+		t.Fatalf("bot = %+v, want a named bot with a bot id", b)
+	}
+	if _, err := New("impossible", DefaultLevels(), words.New()); !errors.Is(err, ErrUnknownDifficulty) {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownDifficulty)
+	}
+}
+
+func TestMove(t *testing.T) {
+	b := newBot(t, Level{MeanDelay: time.Second, DelayStdDev: time.Second, MinDelay: 800 * time.Millisecond})
+	for i := 0; i < 50; i++ {
+		move := b.Move("fruit", []string{"B", "C", "D"})
+		if move.Failed {
+			t.Fatalf("move = %+v, want an answer", move)
+		}
+		if !slices.Contains([]string{"B", "C"}, move.Letter) || words.Initial(move.Word) != move.Letter {
+			t.Fatalf("move = %+v, want a known word on a free letter", move)
+		}
+		if move.Delay < 800*time.Millisecond {
+			t.Fatalf("delay = %s, want at least the minimum", move.Delay)
+		}
+	}
+}
+
+func TestMoveFails(t *testing.T) {
+	b := newBot(t, Level{})
+	if move := b.Move("Fruit", []string{"D", "E"}); !move.Failed {
+		t.Fatalf("move = %+v, want a failure with no known words", move)
+	}
+	b = newBot(t, Level{FailChance: 1})
+	if move := b.Move("Fruit", []string{"A"}); !move.Failed {
+		t.Fatalf("move = %+v, want a failure at a fail chance of 1", move)
+	}
+}
+
+func TestVouch(t *testing.T) {
+	b := newBot(t, Level{MinDelay: time.Second})
+	if delay, ok := b.Vouch("fruit", "banana"); !ok || delay < time.Second {
+		t.Fatalf("Vouch = %s, %v, want a vote for a known word after the minimum delay", delay, ok)
+	}
+	if _, ok := b.Vouch("Fruit", "Durian"); ok {
+		t.Fatal("Vouch = true, want no vote for an unknown word")
+	}
+}
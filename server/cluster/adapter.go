@@ -0,0 +1,127 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	BroadcastChannel = "quik:broadcast"
+	// EventsChannel carries socket events that any node may handle, such as
+	// disconnects from players whose room lives elsewhere.
+	EventsChannel   = "quik:events"
+	DefaultLeaseTTL = 15 * time.Second
+)
+
+var ErrNotFound = errors.New("not found")
+
+// NodeInfo identifies a replica and the address other replicas forward
+// HTTP requests to.
+type NodeInfo struct {
+	Id   string `json:"id"`
+	Addr string `json:"addr"`
+}
+
+// Store persists room snapshots so that another node can adopt a room when
+// its owner stops heartbeating.
+type Store interface {
+	SaveRoom(roomId string, snapshot []byte) error
+	// LoadRoom returns ErrNotFound when no snapshot is stored for the room.
+	LoadRoom(roomId string) ([]byte, error)
+	DeleteRoom(roomId string) error
+	RoomIds() ([]string, error)
+}
+
+// Adapter lets replicas of the server share room broadcasts and agree on
+// which replica owns each room.
+type Adapter interface {
+	Publish(channel string, message []byte) error
+	// Subscribe calls handler for every message published to channel,
+	// including messages published by this replica.
+	Subscribe(channel string, handler func(message []byte)) (unsubscribe func(), err error)
+	// ClaimRoom makes nodeId the owner of the room for ttl if the room has no
+	// owner or is already owned by nodeId, reporting whether it succeeded.
+	ClaimRoom(roomId, nodeId string, ttl time.Duration) (bool, error)
+	// ReleaseRoom gives up ownership of the room if nodeId owns it.
+	ReleaseRoom(roomId, nodeId string) error
+	// RoomOwner returns the id of the node owning the room, or "" if none.
+	RoomOwner(roomId string) (string, error)
+	// Heartbeat records that the node is alive for ttl.
+	Heartbeat(node NodeInfo, ttl time.Duration) error
+	// Nodes returns the nodes with an unexpired heartbeat.
+	Nodes() ([]NodeInfo, error)
+	Store
+	// Ping reports whether the shared store can be reached.
+	Ping() error
+	Close() error
+}
+
+const (
+	BroadcastOpEmit  = "emit"
+	BroadcastOpJoin  = "join"
+	BroadcastOpLeave = "leave"
+	BroadcastOpClose = "close"
+)
+
+// Broadcast is an event to emit to every client in a room, on whichever
+// replica the clients are connected to. The join and leave ops instead make
+// the clients in Room join or leave the room named in Event, and the close
+// op makes every client in Room leave it.
+type Broadcast struct {
+	Op      string          `json:"op,omitempty"`
+	Room    string          `json:"room"`
+	Except  string          `json:"except,omitempty"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ForwardedEvent is a socket event received by one node and handled by the
+// node owning its room.
+type ForwardedEvent struct {
+	ClientId      string `json:"clientId"`
+	RemoteAddress string `json:"remoteAddress"`
+	// ProtocolVersion is the protocol the client negotiated with the node
+	// it is connected to.
+	ProtocolVersion int             `json:"protocolVersion"`
+	Event           string          `json:"event"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// NewNodeId returns a node id made from the host name and a random suffix
+// so that restarts are distinct nodes.
+func NewNodeId() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "node"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// NodeEventsChannel carries socket events forwarded to the node owning
+// their room.
+func NodeEventsChannel(nodeId string) string {
+	return "quik:events:" + nodeId
+}
+
+func roomOwnerKey(roomId string) string {
+	return "quik:room-owner:" + roomId
+}
+
+func roomSnapshotKey(roomId string) string {
+	return "quik:room:" + roomId
+}
+
+func nodeKey(nodeId string) string {
+	return "quik:node:" + nodeId
+}
+
+const (
+	roomsKey = "quik:rooms"
+	nodesKey = "quik:nodes"
+)
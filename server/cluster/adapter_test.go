@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func adapters(t *testing.T) map[string]Adapter {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redisAdapter, err := NewRedisAdapter(server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { redisAdapter.Close() })
+	return map[string]Adapter{
+		"memory": NewMemoryAdapter(),
+		"redis":  redisAdapter,
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	for name, adapter := range adapters(t) {
+		t.Run(name, func(t *testing.T) {
+			received := make(chan string, 1)
+			unsubscribe, err := adapter.Subscribe(BroadcastChannel, func(message []byte) {
+				received <- string(message)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer unsubscribe()
+
+			if err := adapter.Publish(BroadcastChannel, []byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case message := <-received:
+				if message != "hello" {
+					t.Fatalf("received %q, want hello", message)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for the published message")
+			}
+		})
+	}
+}
+
+func TestRoomOwnership(t *testing.T) {
+	for name, adapter := range adapters(t) {
+		t.Run(name, func(t *testing.T) {
+			claimed, err := adapter.ClaimRoom("room", "a", time.Minute)
+			if err != nil || !claimed {
+				t.Fatalf("node a claim = %v, %v, want true", claimed, err)
+			}
+			if claimed, _ := adapter.ClaimRoom("room", "b", time.Minute); claimed {
+				t.Fatal("node b claimed a room owned by node a")
+			}
+			if claimed, _ := adapter.ClaimRoom("room", "a", time.Minute); !claimed {
+				t.Fatal("node a could not renew its lease")
+			}
+			if owner, _ := adapter.RoomOwner("room"); owner != "a" {
+				t.Fatalf("owner = %q, want a", owner)
+			}
+
+			if err := adapter.ReleaseRoom("room", "b"); err != nil {
+				t.Fatal(err)
+			}
+			if owner, _ := adapter.RoomOwner("room"); owner != "a" {
+				t.Fatal("node b released a room it does not own")
+			}
+
+			if err := adapter.ReleaseRoom("room", "a"); err != nil {
+				t.Fatal(err)
+			}
+			if owner, _ := adapter.RoomOwner("room"); owner != "" {
+				t.Fatalf("owner = %q after release, want none", owner)
+			}
+			if claimed, _ := adapter.ClaimRoom("room", "b", time.Minute); !claimed {
+				t.Fatal("node b could not claim a released room")
+			}
+		})
+	}
+}
+
+func TestExpiredLeaseCanBeClaimed(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	adapter.ClaimRoom("room", "a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if owner, _ := adapter.RoomOwner("room"); owner != "" {
+		t.Fatalf("owner = %q after the lease expired, want none", owner)
+	}
+	if claimed, _ := adapter.ClaimRoom("room", "b", time.Minute); !claimed {
+		t.Fatal("node b could not claim a room with an expired lease")
+	}
+}
+
+func TestNodeOwnsOneReplicaAtATime(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	a := NewNode(NodeInfo{Id: "a"}, adapter)
+	b := NewNode(NodeInfo{Id: "b"}, adapter)
+	if !a.Own("room") {
+		t.Fatal("node a could not own the room")
+	}
+	if b.Own("room") {
+		t.Fatal("node b owns a room node a owns")
+	}
+	if !a.Owns("room") || b.Owns("room") {
+		t.Fatal("expected only node a to own the room")
+	}
+	a.Disown("room")
+	if !b.Own("room") {
+		t.Fatal("node b could not own the room after node a disowned it")
+	}
+}
+
+func TestPing(t *testing.T) {
+	for name, adapter := range adapters(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := adapter.Ping(); err != nil {
+				t.Fatalf("ping failed: %s", err)
+			}
+		})
+	}
+
+	server := miniredis.RunT(t)
+	adapter, err := NewRedisAdapter(server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+	server.Close()
+	if err := adapter.Ping(); err == nil {
+		t.Fatal("expected ping to fail once redis is unavailable")
+	}
+}
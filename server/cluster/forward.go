@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/tracing"
+)
+
+// ForwardedHeader marks requests proxied between nodes so they are never
+// forwarded twice.
+const ForwardedHeader = "X-Quik-Forwarded-By"
+
+// ForwardRoom proxies the request to the node owning the room when that is
+// another node, reporting whether it did.
+func (n *Node) ForwardRoom(w http.ResponseWriter, r *http.Request, roomId string) bool {
+	if r.Header.Get(ForwardedHeader) != "" {
+		return false
+	}
+	owner, ok := n.RemoteOwner(roomId)
+	if !ok {
+		return false
+	}
+	target, err := url.Parse(owner.Addr)
+	if err != nil {
+		n.logger().Error("invalid node address", "addr", owner.Addr, logging.Err(err))
+		return false
+	}
+	n.logger().InfoContext(r.Context(), "forwarding request to room owner",
+		"method", r.Method,
+		"path", r.URL.Path,
+		logging.KeyRoomId, roomId,
+		"owner", owner.Id,
+	)
+	r.Header.Set(ForwardedHeader, n.Id)
+	tracing.Inject(r.Context(), r.Header)
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+	return true
+}
+
+// ForwardEvent hands a socket event to the node owning its room.
+func (n *Node) ForwardEvent(owner NodeInfo, event *ForwardedEvent) error {
+	return n.publishEvent(NodeEventsChannel(owner.Id), event)
+}
+
+// BroadcastEvent hands a socket event to every node.
+func (n *Node) BroadcastEvent(event *ForwardedEvent) error {
+	return n.publishEvent(EventsChannel, event)
+}
+
+func (n *Node) publishEvent(channel string, event *ForwardedEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return n.adapter.Publish(channel, b)
+}
@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+type lease struct {
+	nodeId  string
+	expires time.Time
+}
+
+type subscription struct {
+	handler func(message []byte)
+}
+
+type heartbeat struct {
+	node    NodeInfo
+	expires time.Time
+}
+
+// MemoryAdapter is the Adapter for a single replica.
+type MemoryAdapter struct {
+	mu          sync.Mutex
+	subscribers map[string][]*subscription
+	leases      map[string]lease
+	heartbeats  map[string]heartbeat
+	snapshots   map[string][]byte
+}
+
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{
+		subscribers: make(map[string][]*subscription),
+		leases:      make(map[string]lease),
+		heartbeats:  make(map[string]heartbeat),
+		snapshots:   make(map[string][]byte),
+	}
+}
+
+func (a *MemoryAdapter) Publish(channel string, message []byte) error {
+	a.mu.Lock()
+	subscribers := append([]*subscription{}, a.subscribers[channel]...)
+	a.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.handler(message)
+	}
+	return nil
+}
+
+func (a *MemoryAdapter) Subscribe(channel string, handler func(message []byte)) (func(), error) {
+	sub := &subscription{handler: handler}
+	a.mu.Lock()
+	a.subscribers[channel] = append(a.subscribers[channel], sub)
+	a.mu.Unlock()
+
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		subscribers := a.subscribers[channel]
+		for i, s := range subscribers {
+			if s == sub {
+				a.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}, nil
+}
+
+func (a *MemoryAdapter) ClaimRoom(roomId, nodeId string, ttl time.Duration) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	current, ok := a.leases[roomId]
+	if ok && current.nodeId != nodeId && time.Now().Before(current.expires) {
+		return false, nil
+	}
+	a.leases[roomId] = lease{nodeId: nodeId, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (a *MemoryAdapter) ReleaseRoom(roomId, nodeId string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if current, ok := a.leases[roomId]; ok && current.nodeId == nodeId {
+		delete(a.leases, roomId)
+	}
+	return nil
+}
+
+func (a *MemoryAdapter) RoomOwner(roomId string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	current, ok := a.leases[roomId]
+	if !ok || time.Now().After(current.expires) {
+		return "", nil
+	}
+	return current.nodeId, nil
+}
+
+func (a *MemoryAdapter) Heartbeat(node NodeInfo, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.heartbeats[node.Id] = heartbeat{node: node, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (a *MemoryAdapter) Nodes() ([]NodeInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	nodes := []NodeInfo{}
+	for id, hb := range a.heartbeats {
+		if time.Now().After(hb.expires) {
+			delete(a.heartbeats, id)
+			continue
+		}
+		nodes = append(nodes, hb.node)
+	}
+	return nodes, nil
+}
+
+func (a *MemoryAdapter) SaveRoom(roomId string, snapshot []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.snapshots[roomId] = snapshot
+	return nil
+}
+
+func (a *MemoryAdapter) LoadRoom(roomId string) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	snapshot, ok := a.snapshots[roomId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return snapshot, nil
+}
+
+func (a *MemoryAdapter) DeleteRoom(roomId string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.snapshots, roomId)
+	return nil
+}
+
+func (a *MemoryAdapter) RoomIds() ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	roomIds := make([]string, 0, len(a.snapshots))
+	for roomId := range a.snapshots {
+		roomIds = append(roomIds, roomId)
+	}
+	return roomIds, nil
+}
+
+func (a *MemoryAdapter) Ping() error {
+	return nil
+}
+
+func (a *MemoryAdapter) Close() error {
+	return nil
+}
@@ -0,0 +1,293 @@
+package cluster
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+// RoomState snapshots and restores the rooms held in this process.
+type RoomState interface {
+	Snapshot(roomId string) ([]byte, error)
+	Restore(snapshot []byte) (roomId string, err error)
+}
+
+// Node is this replica's view of the cluster. It heartbeats so other nodes
+// know it is alive, owns the rooms created on it, keeps their leases and
+// snapshots fresh, and adopts rooms whose owner stopped heartbeating.
+type Node struct {
+	NodeInfo
+	adapter  Adapter
+	leaseTTL time.Duration
+	state    RoomState
+	mu       sync.Mutex
+	owned    map[string]bool
+	members  map[string]NodeInfo
+	ring     *Ring
+	// leaseLost is told about rooms whose lease another node took.
+	leaseLost func(roomId string)
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func NewNode(info NodeInfo, adapter Adapter) *Node {
+	return &Node{
+		NodeInfo: info,
+		adapter:  adapter,
+		leaseTTL: DefaultLeaseTTL,
+		owned:    make(map[string]bool),
+		members:  map[string]NodeInfo{info.Id: info},
+		ring:     NewRing(info.Id),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (n *Node) logger() *slog.Logger {
+	return slog.With(logging.KeyNodeId, n.Id)
+}
+
+func (n *Node) Adapter() Adapter {
+	return n.adapter
+}
+
+func (n *Node) SetRoomState(state RoomState) {
+	n.state = state
+}
+
+// SetLeaseLostHandler sets what is done with a room whose lease another
+// node took, which must stop the room running here since that node now
+// runs it.
+func (n *Node) SetLeaseLostHandler(f func(roomId string)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.leaseLost = f
+}
+
+// Own claims the room for this node, reporting whether it is now the owner.
+func (n *Node) Own(roomId string) bool {
+	claimed, err := n.adapter.ClaimRoom(roomId, n.Id, n.leaseTTL)
+	if err != nil {
+		n.logger().Error("unable to claim room", logging.KeyRoomId, roomId, logging.Err(err))
+		return false
+	}
+	if claimed {
+		n.mu.Lock()
+		n.owned[roomId] = true
+		n.mu.Unlock()
+		n.save(roomId)
+	}
+	return claimed
+}
+
+// Disown releases a room that was removed and deletes its snapshot.
+func (n *Node) Disown(roomId string) {
+	n.mu.Lock()
+	delete(n.owned, roomId)
+	n.mu.Unlock()
+	if err := n.adapter.ReleaseRoom(roomId, n.Id); err != nil {
+		n.logger().Error("unable to release room", logging.KeyRoomId, roomId, logging.Err(err))
+	}
+	if err := n.adapter.DeleteRoom(roomId); err != nil {
+		n.logger().Error("unable to delete room snapshot", logging.KeyRoomId, roomId, logging.Err(err))
+	}
+}
+
+// Owns reports whether this node currently holds the lease for the room.
+func (n *Node) Owns(roomId string) bool {
+	owner, err := n.adapter.RoomOwner(roomId)
+	if err != nil {
+		n.logger().Error("unable to look up room owner", logging.KeyRoomId, roomId, logging.Err(err))
+		return false
+	}
+	return owner == n.Id
+}
+
+// Prefers reports whether consistent hashing assigns the room to this node.
+func (n *Node) Prefers(roomId string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ring.Lookup(roomId) == n.Id
+}
+
+// RemoteOwner returns the node owning the room when that is another live
+// node.
+func (n *Node) RemoteOwner(roomId string) (NodeInfo, bool) {
+	owner, err := n.adapter.RoomOwner(roomId)
+	if err != nil {
+		n.logger().Error("unable to look up room owner", logging.KeyRoomId, roomId, logging.Err(err))
+		return NodeInfo{}, false
+	}
+	if owner == "" || owner == n.Id {
+		return NodeInfo{}, false
+	}
+	n.mu.Lock()
+	info, ok := n.members[owner]
+	n.mu.Unlock()
+	return info, ok
+}
+
+// Run heartbeats, renews leases and adopts orphaned rooms until Close is
+// called.
+func (n *Node) Run() {
+	n.tick()
+	ticker := time.NewTicker(n.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.tick()
+		}
+	}
+}
+
+func (n *Node) tick() {
+	if err := n.adapter.Heartbeat(n.NodeInfo, n.leaseTTL); err != nil {
+		n.logger().Error("unable to heartbeat", logging.Err(err))
+	}
+	n.refreshMembers()
+	n.renew()
+	n.adopt()
+}
+
+func (n *Node) refreshMembers() {
+	nodes, err := n.adapter.Nodes()
+	if err != nil {
+		n.logger().Error("unable to list nodes", logging.Err(err))
+		return
+	}
+	members := map[string]NodeInfo{n.Id: n.NodeInfo}
+	ids := []string{n.Id}
+	for _, node := range nodes {
+		if _, ok := members[node.Id]; !ok {
+			ids = append(ids, node.Id)
+		}
+		members[node.Id] = node
+	}
+	n.mu.Lock()
+	n.members = members
+	n.ring = NewRing(ids...)
+	n.mu.Unlock()
+}
+
+func (n *Node) ownedRoomIds() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	roomIds := make([]string, 0, len(n.owned))
+	for roomId := range n.owned {
+		roomIds = append(roomIds, roomId)
+	}
+	return roomIds
+}
+
+func (n *Node) renew() {
+	for _, roomId := range n.ownedRoomIds() {
+		claimed, err := n.adapter.ClaimRoom(roomId, n.Id, n.leaseTTL)
+		if err != nil {
+			n.logger().Error("unable to renew room lease", logging.KeyRoomId, roomId, logging.Err(err))
+			continue
+		}
+		if !claimed {
+			n.logger().Warn("lost the lease on room", logging.KeyRoomId, roomId)
+			n.mu.Lock()
+			delete(n.owned, roomId)
+			leaseLost := n.leaseLost
+			n.mu.Unlock()
+			if leaseLost != nil {
+				leaseLost(roomId)
+			}
+			continue
+		}
+		n.save(roomId)
+	}
+}
+
+// SaveAll snapshots every room this node owns to the store.
+func (n *Node) SaveAll() {
+	for _, roomId := range n.ownedRoomIds() {
+		n.save(roomId)
+	}
+}
+
+func (n *Node) save(roomId string) {
+	if n.state == nil {
+		return
+	}
+	snapshot, err := n.state.Snapshot(roomId)
+	if err != nil {
+		n.logger().Error("unable to snapshot room", logging.KeyRoomId, roomId, logging.Err(err))
+		return
+	}
+	if err := n.adapter.SaveRoom(roomId, snapshot); err != nil {
+		n.logger().Error("unable to save room snapshot", logging.KeyRoomId, roomId, logging.Err(err))
+	}
+}
+
+// adopt takes over stored rooms that have no owner and that consistent
+// hashing assigns to this node.
+func (n *Node) adopt() {
+	if n.state == nil {
+		return
+	}
+	roomIds, err := n.adapter.RoomIds()
+	if err != nil {
+		n.logger().Error("unable to list stored rooms", logging.Err(err))
+		return
+	}
+	for _, roomId := range roomIds {
+		n.mu.Lock()
+		_, owned := n.owned[roomId]
+		n.mu.Unlock()
+		if owned || !n.Prefers(roomId) {
+			continue
+		}
+		owner, err := n.adapter.RoomOwner(roomId)
+		if err != nil || owner != "" {
+			continue
+		}
+		snapshot, err := n.adapter.LoadRoom(roomId)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			n.logger().Error("unable to load room snapshot", logging.KeyRoomId, roomId, logging.Err(err))
+			continue
+		}
+		claimed, err := n.adapter.ClaimRoom(roomId, n.Id, n.leaseTTL)
+		if err != nil || !claimed {
+			continue
+		}
+		if _, err := n.state.Restore(snapshot); err != nil {
+			n.logger().Error("unable to restore room snapshot", logging.KeyRoomId, roomId, logging.Err(err))
+			n.adapter.ReleaseRoom(roomId, n.Id)
+			continue
+		}
+		n.mu.Lock()
+		n.owned[roomId] = true
+		n.mu.Unlock()
+		n.logger().Info("adopted room", logging.KeyRoomId, roomId)
+	}
+}
+
+// Handoff snapshots every room this node owns and releases its leases, so
+// that other nodes adopt the rooms as soon as this node stops heartbeating.
+// Call it after Close so the leases are not renewed again.
+func (n *Node) Handoff() {
+	for _, roomId := range n.ownedRoomIds() {
+		n.save(roomId)
+		if err := n.adapter.ReleaseRoom(roomId, n.Id); err != nil {
+			n.logger().Error("unable to release room", logging.KeyRoomId, roomId, logging.Err(err))
+		}
+		n.mu.Lock()
+		delete(n.owned, roomId)
+		n.mu.Unlock()
+	}
+}
+
+func (n *Node) Close() {
+	n.stopOnce.Do(func() { close(n.stop) })
+}
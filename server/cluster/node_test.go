@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeRoomState struct {
+	rooms map[string][]byte
+}
+
+func (s *fakeRoomState) Snapshot(roomId string) ([]byte, error) {
+	snapshot, ok := s.rooms[roomId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return snapshot, nil
+}
+
+func (s *fakeRoomState) Restore(snapshot []byte) (string, error) {
+	roomId := string(snapshot)
+	s.rooms[roomId] = snapshot
+	return roomId, nil
+}
+
+func TestRingIsStable(t *testing.T) {
+	ring := NewRing("a", "b", "c")
+	moved := 0
+	grown := NewRing("a", "b", "c", "d")
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("room-%d", i)
+		if ring.Lookup(key) != ring.Lookup(key) {
+			t.Fatal("lookup is not deterministic")
+		}
+		if before, after := ring.Lookup(key), grown.Lookup(key); before != after {
+			if after != "d" {
+				t.Fatalf("key %s moved from %s to %s, only moves to the new node are expected", key, before, after)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 500 {
+		t.Fatalf("%d of 1000 keys moved to the new node", moved)
+	}
+	if NewRing().Lookup("room") != "" {
+		t.Fatal("expected an empty ring to have no owner")
+	}
+}
+
+func TestNodeAdoptsOrphanedRoom(t *testing.T) {
+	adapter := NewMemoryAdapter()
+
+	a := NewNode(NodeInfo{Id: "a", Addr: "http://a"}, adapter)
+	a.leaseTTL = 100 * time.Millisecond
+	a.SetRoomState(&fakeRoomState{rooms: map[string][]byte{"room": []byte("room")}})
+	if !a.Own("room") {
+		t.Fatal("node a could not own the room")
+	}
+	a.tick()
+
+	bState := &fakeRoomState{rooms: map[string][]byte{}}
+	b := NewNode(NodeInfo{Id: "b", Addr: "http://b"}, adapter)
+	b.SetRoomState(bState)
+
+	b.tick()
+	if owner, ok := b.RemoteOwner("room"); !ok || owner.Id != "a" {
+		t.Fatalf("remote owner = %v, %v, want node a", owner, ok)
+	}
+	if _, adopted := bState.rooms["room"]; adopted {
+		t.Fatal("node b adopted a room node a still owns")
+	}
+
+	// Node a stops heartbeating and renewing its lease.
+	time.Sleep(200 * time.Millisecond)
+	b.tick()
+	if _, adopted := bState.rooms["room"]; !adopted {
+		t.Fatal("node b did not adopt the orphaned room")
+	}
+	if !b.Owns("room") {
+		t.Fatal("expected node b to own the adopted room")
+	}
+}
+
+func TestNodeHandoffReleasesRooms(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	a := NewNode(NodeInfo{Id: "a"}, adapter)
+	a.SetRoomState(&fakeRoomState{rooms: map[string][]byte{"room": []byte("room")}})
+	if !a.Own("room") {
+		t.Fatal("node a could not own the room")
+	}
+
+	a.Close()
+	a.Handoff()
+
+	if owner, _ := adapter.RoomOwner("room"); owner != "" {
+		t.Fatalf("owner = %q after handoff, want none", owner)
+	}
+	if snapshot, err := adapter.LoadRoom("room"); err != nil || string(snapshot) != "room" {
+		t.Fatalf("snapshot = %q, %v, want the room kept for adoption", snapshot, err)
+	}
+}
+
+func TestNodeLosesLease(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	a := NewNode(NodeInfo{Id: "a"}, adapter)
+	a.leaseTTL = 50 * time.Millisecond
+	a.SetRoomState(&fakeRoomState{rooms: map[string][]byte{"room": []byte("room")}})
+	lost := []string{}
+	a.SetLeaseLostHandler(func(roomId string) { lost = append(lost, roomId) })
+	if !a.Own("room") {
+		t.Fatal("node a could not own the room")
+	}
+
+	// Node a stalls past its lease and node b takes the room.
+	time.Sleep(100 * time.Millisecond)
+	if claimed, err := adapter.ClaimRoom("room", "b", time.Minute); err != nil || !claimed {
+		t.Fatalf("ClaimRoom = %v, %v, want node b to claim the room", claimed, err)
+	}
+	a.renew()
+
+	if len(lost) != 1 || lost[0] != "room" {
+		t.Fatalf("lost leases = %v, want the room", lost)
+	}
+	if len(a.ownedRoomIds()) != 0 {
+		t.Fatalf("node a still owns %v", a.ownedRoomIds())
+	}
+	a.renew()
+	if len(lost) != 1 {
+		t.Fatalf("lost leases = %v, want the room reported once", lost)
+	}
+}
@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// claimScript sets the owner of a room unless another node holds an
+// unexpired lease on it.
+var claimScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == false or owner == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the owner of a room only if it is still ARGV[1].
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisAdapter shares broadcasts and room ownership through any server
+// speaking the Redis protocol.
+type RedisAdapter struct {
+	client *redis.Client
+}
+
+func NewRedisAdapter(addr string) (*RedisAdapter, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisAdapter{client: client}, nil
+}
+
+func (a *RedisAdapter) Publish(channel string, message []byte) error {
+	return a.client.Publish(context.Background(), channel, message).Err()
+}
+
+func (a *RedisAdapter) Subscribe(channel string, handler func(message []byte)) (func(), error) {
+	ctx := context.Background()
+	pubsub := a.client.Subscribe(ctx, channel)
+	// Wait for the subscription to be confirmed so no message published
+	// after Subscribe returns is missed.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return func() {
+		if err := pubsub.Close(); err != nil {
+			slog.Error("unable to unsubscribe from redis channel", "channel", channel, logging.Err(err))
+		}
+	}, nil
+}
+
+func (a *RedisAdapter) ClaimRoom(roomId, nodeId string, ttl time.Duration) (bool, error) {
+	claimed, err := claimScript.Run(
+		context.Background(),
+		a.client,
+		[]string{roomOwnerKey(roomId)},
+		nodeId,
+		ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return claimed == 1, nil
+}
+
+func (a *RedisAdapter) ReleaseRoom(roomId, nodeId string) error {
+	return releaseScript.Run(
+		context.Background(),
+		a.client,
+		[]string{roomOwnerKey(roomId)},
+		nodeId,
+	).Err()
+}
+
+func (a *RedisAdapter) RoomOwner(roomId string) (string, error) {
+	owner, err := a.client.Get(context.Background(), roomOwnerKey(roomId)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return owner, err
+}
+
+func (a *RedisAdapter) Heartbeat(node NodeInfo, ttl time.Duration) error {
+	ctx := context.Background()
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, nodeKey(node.Id), node.Addr, ttl)
+		pipe.SAdd(ctx, nodesKey, node.Id)
+		return nil
+	})
+	return err
+}
+
+func (a *RedisAdapter) Nodes() ([]NodeInfo, error) {
+	ctx := context.Background()
+	ids, err := a.client.SMembers(ctx, nodesKey).Result()
+	if err != nil || len(ids) == 0 {
+		return []NodeInfo{}, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = nodeKey(id)
+	}
+	addrs, err := a.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []NodeInfo{}
+	for i, addr := range addrs {
+		if addr == nil {
+			// The heartbeat expired, forget the node.
+			a.client.SRem(ctx, nodesKey, ids[i])
+			continue
+		}
+		nodes = append(nodes, NodeInfo{Id: ids[i], Addr: addr.(string)})
+	}
+	return nodes, nil
+}
+
+func (a *RedisAdapter) SaveRoom(roomId string, snapshot []byte) error {
+	ctx := context.Background()
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, roomSnapshotKey(roomId), snapshot, 0)
+		pipe.SAdd(ctx, roomsKey, roomId)
+		return nil
+	})
+	return err
+}
+
+func (a *RedisAdapter) LoadRoom(roomId string) ([]byte, error) {
+	snapshot, err := a.client.Get(context.Background(), roomSnapshotKey(roomId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return snapshot, err
+}
+
+func (a *RedisAdapter) DeleteRoom(roomId string) error {
+	ctx := context.Background()
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, roomSnapshotKey(roomId))
+		pipe.SRem(ctx, roomsKey, roomId)
+		return nil
+	})
+	return err
+}
+
+func (a *RedisAdapter) RoomIds() ([]string, error) {
+	return a.client.SMembers(context.Background(), roomsKey).Result()
+}
+
+func (a *RedisAdapter) Ping() error {
+	return a.client.Ping(context.Background()).Err()
+}
+
+func (a *RedisAdapter) Close() error {
+	return a.client.Close()
+}
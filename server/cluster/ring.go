@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"hash/crc32"
+	"slices"
+	"strconv"
+)
+
+const defaultVirtualNodes = 64
+
+// Ring maps room ids onto nodes with consistent hashing, so that adding or
+// removing a node only moves the rooms that hashed to it.
+type Ring struct {
+	hashes []uint32
+	nodes  map[uint32]string
+}
+
+func NewRing(nodeIds ...string) *Ring {
+	r := &Ring{nodes: make(map[uint32]string)}
+	for _, nodeId := range nodeIds {
+		for i := 0; i < defaultVirtualNodes; i++ {
+			hash := crc32.ChecksumIEEE([]byte(nodeId + "#" + strconv.Itoa(i)))
+			r.nodes[hash] = nodeId
+			r.hashes = append(r.hashes, hash)
+		}
+	}
+	slices.Sort(r.hashes)
+	return r
+}
+
+// Lookup returns the node responsible for the key, or "" for an empty ring.
+func (r *Ring) Lookup(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i, _ := slices.BinarySearch(r.hashes, hash)
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]]
+}
@@ -0,0 +1,641 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/bot"
+	"github.com/campbell-rehu/quik-be/ratelimit"
+	"github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/words"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+var (
+	LogLevels  = []string{"debug", "info", "warn", "error"}
+	LogFormats = []string{"text", "json"}
+)
+
+type TLSConfig struct {
+	CertFile string `yaml:"certFile" toml:"certFile"`
+	KeyFile  string `yaml:"keyFile" toml:"keyFile"`
+	// ReloadInterval is how often the certificate and key files are
+	// checked for changes, 0 to never reload them.
+	ReloadInterval time.Duration `yaml:"reloadInterval" toml:"reloadInterval"`
+	// RedirectAddr is an address to listen on for plain HTTP requests and
+	// redirect them to HTTPS, such as :80. Nothing listens while it is
+	// empty.
+	RedirectAddr string `yaml:"redirectAddr" toml:"redirectAddr"`
+	// HTTP2 serves HTTP/2 to clients that support it.
+	HTTP2 bool `yaml:"http2" toml:"http2"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+type GameConfig struct {
+	// TimerDuration is the length of a turn in seconds.
+	TimerDuration int `yaml:"timerDuration" toml:"timerDuration"`
+	// WinCount is the number of rounds a player must win to win the game.
+	WinCount int `yaml:"winCount" toml:"winCount"`
+	// ChallengeWindow is how many seconds players have to challenge a
+	// typed answer, 0 to turn challenges off. VoteDuration is how many
+	// seconds they then have to vote on it.
+	ChallengeWindow int `yaml:"challengeWindow" toml:"challengeWindow"`
+	VoteDuration    int `yaml:"voteDuration" toml:"voteDuration"`
+}
+
+type RoomsConfig struct {
+	// MaxRooms caps the rooms held by one server, 0 for no limit.
+	MaxRooms int `yaml:"maxRooms" toml:"maxRooms"`
+	// MaxPlayers caps the players in one room, 0 for no limit.
+	MaxPlayers int `yaml:"maxPlayers" toml:"maxPlayers"`
+	// EventLogDir is where each room's event log is written as JSON
+	// Lines, so it can be replayed after the room ends. Logs are kept in
+	// memory only while it is empty.
+	EventLogDir string `yaml:"eventLogDir" toml:"eventLogDir"`
+	// HistoryFile is where completed games are written as JSON Lines for
+	// player stats. Games are kept in memory only while it is empty.
+	HistoryFile string `yaml:"historyFile" toml:"historyFile"`
+}
+
+type NamesConfig struct {
+	// Filter blocks player names containing profanity from the built-in
+	// word list and BlocklistFile.
+	Filter bool `yaml:"filter" toml:"filter"`
+	// BlocklistFile is a list of further words to block, one per line.
+	BlocklistFile string `yaml:"blocklistFile" toml:"blocklistFile"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+	// RedactNames keeps player names out of the logs.
+	RedactNames bool `yaml:"redactNames" toml:"redactNames"`
+}
+
+type ClusterConfig struct {
+	NodeId string `yaml:"nodeId" toml:"nodeId"`
+	// NodeAddr is the address other replicas use to reach this one.
+	NodeAddr  string `yaml:"nodeAddr" toml:"nodeAddr"`
+	RedisAddr string `yaml:"redisAddr" toml:"redisAddr"`
+}
+
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector spans are exported to, e.g.
+	// http://localhost:4318. Tracing is off when it is empty.
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// SampleRatio is the fraction of traces recorded, from 0 to 1.
+	SampleRatio float64 `yaml:"sampleRatio" toml:"sampleRatio"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// TrustProxy takes client addresses from X-Forwarded-For, which is
+	// only safe behind a proxy that sets it.
+	TrustProxy bool `yaml:"trustProxy" toml:"trustProxy"`
+	// HTTP limits the requests from each address to each route.
+	HTTP ratelimit.Rate `yaml:"http" toml:"http"`
+	// Events limits each event type a socket sends, and EventRates
+	// overrides it for particular event types.
+	Events     ratelimit.Rate            `yaml:"events" toml:"events"`
+	EventRates map[string]ratelimit.Rate `yaml:"eventRates" toml:"eventRates"`
+	// MaxViolations is how many events over the limit a socket may send in
+	// a minute before it is disconnected, 0 to never disconnect.
+	MaxViolations int `yaml:"maxViolations" toml:"maxViolations"`
+	// RoomsPerAddr caps the open rooms created from one address, 0 for no
+	// limit.
+	RoomsPerAddr int `yaml:"roomsPerAddr" toml:"roomsPerAddr"`
+}
+
+type AuthConfig struct {
+	// SessionSecret signs players' session tokens and must be shared by
+	// every replica. Without one a random secret is used and players are
+	// signed out when the server restarts.
+	SessionSecret string `yaml:"sessionSecret" toml:"sessionSecret"`
+	// SessionTTL is how long a session token stays valid.
+	SessionTTL time.Duration `yaml:"sessionTTL" toml:"sessionTTL"`
+	// AccountsFile is where player accounts are written as JSON Lines.
+	// Accounts are kept in memory only while it is empty.
+	AccountsFile string `yaml:"accountsFile" toml:"accountsFile"`
+}
+
+type BotsConfig struct {
+	// Enabled lets players seat bots in their rooms.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Difficulty is the level bots play at unless asked for another.
+	Difficulty string `yaml:"difficulty" toml:"difficulty"`
+	// Levels adds difficulties or replaces the built-in easy, medium and
+	// hard ones.
+	Levels map[string]bot.Level `yaml:"levels" toml:"levels"`
+	// WordList is a file of further words for bots to answer with, added
+	// to the built-in list. See words.Parse for its format.
+	WordList string `yaml:"wordList" toml:"wordList"`
+	// FillQueue seats a player who waits in the matchmaking queue without
+	// finding a match in a room with bots instead.
+	FillQueue bool `yaml:"fillQueue" toml:"fillQueue"`
+}
+
+type DictionaryConfig struct {
+	// Dir holds .txt word lists, added to the built-in list, that typed
+	// answers are looked up in. See words.LoadDir for their format.
+	Dir string `yaml:"dir" toml:"dir"`
+	// Strictness is what happens to typed answers that are not in the
+	// dictionary: off, warn or reject.
+	Strictness string `yaml:"strictness" toml:"strictness"`
+}
+
+type AdminConfig struct {
+	// Token is the bearer token required by the admin API, which is
+	// disabled while it is empty.
+	Token string `yaml:"token" toml:"token"`
+}
+
+// Config is the server's configuration. Settings are read from, in order of
+// increasing precedence, the defaults, a YAML or TOML config file, QUIK_*
+// environment variables and command line flags.
+type Config struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	// AllowedOrigins are the web origins whose pages may call the API and
+	// connect sockets, such as https://quik.example. A * matches any part
+	// of an origin, and * alone allows every origin.
+	AllowedOrigins []string `yaml:"allowedOrigins" toml:"allowedOrigins"`
+	// CSRF refuses POST and DELETE requests made by pages on origins that
+	// are not allowed.
+	CSRF       bool             `yaml:"csrf" toml:"csrf"`
+	TLS        TLSConfig        `yaml:"tls" toml:"tls"`
+	Game       GameConfig       `yaml:"game" toml:"game"`
+	Rooms      RoomsConfig      `yaml:"rooms" toml:"rooms"`
+	Names      NamesConfig      `yaml:"names" toml:"names"`
+	Log        LogConfig        `yaml:"log" toml:"log"`
+	Cluster    ClusterConfig    `yaml:"cluster" toml:"cluster"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing"`
+	RateLimit  RateLimitConfig  `yaml:"rateLimit" toml:"rateLimit"`
+	Auth       AuthConfig       `yaml:"auth" toml:"auth"`
+	Bots       BotsConfig       `yaml:"bots" toml:"bots"`
+	Dictionary DictionaryConfig `yaml:"dictionary" toml:"dictionary"`
+	Admin      AdminConfig      `yaml:"admin" toml:"admin"`
+	// DrainTimeout is how long players are given to finish up when the
+	// server shuts down.
+	DrainTimeout time.Duration `yaml:"drainTimeout" toml:"drainTimeout"`
+
+	// PrintConfig asks for the resolved config to be printed instead of
+	// starting the server.
+	PrintConfig bool `yaml:"-" toml:"-"`
+}
+
+func Default() *Config {
+	return &Config{
+		Addr:           ":9191",
+		AllowedOrigins: []string{"http://localhost:3000"},
+		TLS: TLSConfig{
+			ReloadInterval: time.Minute,
+			HTTP2:          true,
+		},
+		Game: GameConfig{
+			TimerDuration:   room.DefaultTimerDuration,
+			WinCount:        room.DefaultWinCount,
+			ChallengeWindow: room.DefaultChallengeWindow,
+			VoteDuration:    room.DefaultVoteDuration,
+		},
+		Names: NamesConfig{
+			Filter: true,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:       true,
+			HTTP:          ratelimit.Rate{PerSecond: 10, Burst: 20},
+			Events:        ratelimit.Rate{PerSecond: 10, Burst: 20},
+			MaxViolations: 50,
+			RoomsPerAddr:  5,
+		},
+		Auth: AuthConfig{
+			SessionTTL: auth.DefaultSessionTTL,
+		},
+		Bots: BotsConfig{
+			Enabled:    true,
+			Difficulty: bot.DifficultyMedium,
+			Levels:     bot.DefaultLevels(),
+		},
+		Dictionary: DictionaryConfig{
+			Strictness: string(words.StrictnessOff),
+		},
+		DrainTimeout: 10 * time.Second,
+	}
+}
+
+// setting is a config value that can be set by flag and environment
+// variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"addr", "QUIK_ADDR", "address to listen on", func(c *Config, v string) error {
+		c.Addr = v
+		return nil
+	}},
+	{"allowed-origins", "QUIK_ALLOWED_ORIGINS", "comma separated origins allowed to connect", func(c *Config, v string) error {
+		c.AllowedOrigins = splitList(v)
+		return nil
+	}},
+	{"csrf", "QUIK_CSRF", "refuse POST and DELETE requests from pages on origins that are not allowed: true or false", func(c *Config, v string) error {
+		return setBool(&c.CSRF, v)
+	}},
+	{"tls-cert", "QUIK_TLS_CERT", "TLS certificate file", func(c *Config, v string) error {
+		c.TLS.CertFile = v
+		return nil
+	}},
+	{"tls-key", "QUIK_TLS_KEY", "TLS private key file", func(c *Config, v string) error {
+		c.TLS.KeyFile = v
+		return nil
+	}},
+	{"tls-reload-interval", "QUIK_TLS_RELOAD_INTERVAL", "how often to check the TLS files for a renewed certificate, e.g. 1m, 0 to never reload", func(c *Config, v string) error {
+		return setDuration(&c.TLS.ReloadInterval, v)
+	}},
+	{"tls-redirect-addr", "QUIK_TLS_REDIRECT_ADDR", "address to redirect plain HTTP requests to HTTPS from, e.g. :80", func(c *Config, v string) error {
+		c.TLS.RedirectAddr = v
+		return nil
+	}},
+	{"http2", "QUIK_HTTP2", "serve HTTP/2 over TLS: true or false", func(c *Config, v string) error {
+		return setBool(&c.TLS.HTTP2, v)
+	}},
+	{"timer-duration", "QUIK_TIMER_DURATION", "turn length in seconds", func(c *Config, v string) error {
+		return setInt(&c.Game.TimerDuration, v)
+	}},
+	{"win-count", "QUIK_WIN_COUNT", "rounds needed to win a game", func(c *Config, v string) error {
+		return setInt(&c.Game.WinCount, v)
+	}},
+	{"challenge-window", "QUIK_CHALLENGE_WINDOW", "seconds players have to challenge a typed answer, 0 to turn challenges off", func(c *Config, v string) error {
+		return setInt(&c.Game.ChallengeWindow, v)
+	}},
+	{"vote-duration", "QUIK_VOTE_DURATION", "seconds players have to vote on a challenged answer", func(c *Config, v string) error {
+		return setInt(&c.Game.VoteDuration, v)
+	}},
+	{"max-rooms", "QUIK_MAX_ROOMS", "maximum rooms on this server, 0 for no limit", func(c *Config, v string) error {
+		return setInt(&c.Rooms.MaxRooms, v)
+	}},
+	{"max-players", "QUIK_MAX_PLAYERS", "maximum players in a room, 0 for no limit", func(c *Config, v string) error {
+		return setInt(&c.Rooms.MaxPlayers, v)
+	}},
+	{"event-log-dir", "QUIK_EVENT_LOG_DIR", "directory to write room event logs to", func(c *Config, v string) error {
+		c.Rooms.EventLogDir = v
+		return nil
+	}},
+	{"history-file", "QUIK_HISTORY_FILE", "file to write completed games to", func(c *Config, v string) error {
+		c.Rooms.HistoryFile = v
+		return nil
+	}},
+	{"name-filter", "QUIK_NAME_FILTER", "block player names containing profanity: true or false", func(c *Config, v string) error {
+		return setBool(&c.Names.Filter, v)
+	}},
+	{"name-blocklist", "QUIK_NAME_BLOCKLIST", "file of further words to block in player names, one per line", func(c *Config, v string) error {
+		c.Names.BlocklistFile = v
+		return nil
+	}},
+	{"log-level", "QUIK_LOG_LEVEL", "log level: " + strings.Join(LogLevels, ", "), func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+	{"log-format", "QUIK_LOG_FORMAT", "log format: " + strings.Join(LogFormats, ", "), func(c *Config, v string) error {
+		c.Log.Format = v
+		return nil
+	}},
+	{"log-redact-names", "QUIK_LOG_REDACT_NAMES", "keep player names out of the logs: true or false", func(c *Config, v string) error {
+		return setBool(&c.Log.RedactNames, v)
+	}},
+	{"node-id", "QUIK_NODE_ID", "id of this replica in the cluster", func(c *Config, v string) error {
+		c.Cluster.NodeId = v
+		return nil
+	}},
+	{"node-addr", "QUIK_NODE_ADDR", "address other replicas use to reach this one", func(c *Config, v string) error {
+		c.Cluster.NodeAddr = v
+		return nil
+	}},
+	{"redis-addr", "QUIK_REDIS_ADDR", "redis address shared by replicas", func(c *Config, v string) error {
+		c.Cluster.RedisAddr = v
+		return nil
+	}},
+	{"otlp-endpoint", "QUIK_OTLP_ENDPOINT", "OTLP/HTTP collector to export traces to, e.g. http://localhost:4318", func(c *Config, v string) error {
+		c.Tracing.Endpoint = v
+		return nil
+	}},
+	{"trace-sample-ratio", "QUIK_TRACE_SAMPLE_RATIO", "fraction of traces to record, from 0 to 1", func(c *Config, v string) error {
+		return setFloat(&c.Tracing.SampleRatio, v)
+	}},
+	{"rate-limit", "QUIK_RATE_LIMIT", "limit requests and socket events per client: true or false", func(c *Config, v string) error {
+		return setBool(&c.RateLimit.Enabled, v)
+	}},
+	{"trust-proxy", "QUIK_TRUST_PROXY", "take client addresses from X-Forwarded-For: true or false", func(c *Config, v string) error {
+		return setBool(&c.RateLimit.TrustProxy, v)
+	}},
+	{"http-rate", "QUIK_HTTP_RATE", "HTTP requests per second allowed from an address to a route, 0 for no limit", func(c *Config, v string) error {
+		return setFloat(&c.RateLimit.HTTP.PerSecond, v)
+	}},
+	{"http-burst", "QUIK_HTTP_BURST", "HTTP requests an address may make to a route at once", func(c *Config, v string) error {
+		return setInt(&c.RateLimit.HTTP.Burst, v)
+	}},
+	{"event-rate", "QUIK_EVENT_RATE", "socket events of a type per second allowed from a client, 0 for no limit", func(c *Config, v string) error {
+		return setFloat(&c.RateLimit.Events.PerSecond, v)
+	}},
+	{"event-burst", "QUIK_EVENT_BURST", "socket events of a type a client may send at once", func(c *Config, v string) error {
+		return setInt(&c.RateLimit.Events.Burst, v)
+	}},
+	{"max-violations", "QUIK_MAX_VIOLATIONS", "events over the limit a client may send in a minute before it is disconnected, 0 to never disconnect", func(c *Config, v string) error {
+		return setInt(&c.RateLimit.MaxViolations, v)
+	}},
+	{"rooms-per-addr", "QUIK_ROOMS_PER_ADDR", "open rooms one address may create, 0 for no limit", func(c *Config, v string) error {
+		return setInt(&c.RateLimit.RoomsPerAddr, v)
+	}},
+	{"session-secret", "QUIK_SESSION_SECRET", "secret signing session tokens, shared by every replica", func(c *Config, v string) error {
+		c.Auth.SessionSecret = v
+		return nil
+	}},
+	{"session-ttl", "QUIK_SESSION_TTL", "how long session tokens stay valid, e.g. 24h", func(c *Config, v string) error {
+		return setDuration(&c.Auth.SessionTTL, v)
+	}},
+	{"accounts-file", "QUIK_ACCOUNTS_FILE", "file to write player accounts to", func(c *Config, v string) error {
+		c.Auth.AccountsFile = v
+		return nil
+	}},
+	{"bots", "QUIK_BOTS", "let players seat bots in their rooms: true or false", func(c *Config, v string) error {
+		return setBool(&c.Bots.Enabled, v)
+	}},
+	{"bot-difficulty", "QUIK_BOT_DIFFICULTY", "level bots play at by default, e.g. easy, medium or hard", func(c *Config, v string) error {
+		c.Bots.Difficulty = v
+		return nil
+	}},
+	{"bot-word-list", "QUIK_BOT_WORD_LIST", "file of further words for bots to answer with", func(c *Config, v string) error {
+		c.Bots.WordList = v
+		return nil
+	}},
+	{"bot-fill-queue", "QUIK_BOT_FILL_QUEUE", "seat players who find no match in the queue with bots: true or false", func(c *Config, v string) error {
+		return setBool(&c.Bots.FillQueue, v)
+	}},
+	{"dictionary-dir", "QUIK_DICTIONARY_DIR", "directory of word lists to check typed answers against", func(c *Config, v string) error {
+		c.Dictionary.Dir = v
+		return nil
+	}},
+	{"answer-strictness", "QUIK_ANSWER_STRICTNESS", "what happens to typed answers not in the dictionary: off, warn or reject", func(c *Config, v string) error {
+		c.Dictionary.Strictness = v
+		return nil
+	}},
+	{"admin-token", "QUIK_ADMIN_TOKEN", "bearer token for the admin API, which is disabled without one", func(c *Config, v string) error {
+		c.Admin.Token = v
+		return nil
+	}},
+	{"drain-timeout", "QUIK_DRAIN_TIMEOUT", "time given to players when shutting down, e.g. 30s", func(c *Config, v string) error {
+		return setDuration(&c.DrainTimeout, v)
+	}},
+}
+
+// Load reads the config from the command line arguments, the environment
+// and the config file named by --config or QUIK_CONFIG.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	fs := flag.NewFlagSet("quik", flag.ContinueOnError)
+	path := fs.String("config", getenv("QUIK_CONFIG"), "YAML or TOML config file (QUIK_CONFIG)")
+	printConfig := fs.Bool("print-config", false, "print the resolved config and exit")
+	flags := make(map[string]string)
+	for _, s := range settings {
+		fs.Func(s.flag, fmt.Sprintf("%s (%s)", s.usage, s.env), func(value string) error {
+			flags[s.flag] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	c := Default()
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := s.set(c, value); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := flags[s.flag]; ok {
+			if err := s.set(c, value); err != nil {
+				return nil, fmt.Errorf("%w: --%s: %w", ErrInvalidConfig, s.flag, err)
+			}
+		}
+	}
+	c.PrintConfig = *printConfig
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, c)
+	case ".toml":
+		err = toml.Unmarshal(b, c)
+	default:
+		return fmt.Errorf("%w: unsupported config file type %q, expected .yaml, .yml or .toml", ErrInvalidConfig, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr: %w", err))
+	}
+	if len(c.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("allowedOrigins must not be empty, use * to allow every origin"))
+	}
+	if c.CSRF && slices.Contains(c.AllowedOrigins, "*") {
+		errs = append(errs, errors.New("csrf needs allowedOrigins to list origins rather than allow every origin"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.certFile and tls.keyFile must be set together"))
+	}
+	if c.TLS.ReloadInterval < 0 {
+		errs = append(errs, errors.New("tls.reloadInterval must not be negative"))
+	}
+	if c.TLS.RedirectAddr != "" {
+		if !c.TLS.Enabled() {
+			errs = append(errs, errors.New("tls.redirectAddr needs tls.certFile and tls.keyFile to be set"))
+		} else if _, _, err := net.SplitHostPort(c.TLS.RedirectAddr); err != nil {
+			errs = append(errs, fmt.Errorf("tls.redirectAddr: %w", err))
+		}
+	}
+	if c.Game.TimerDuration < 1 {
+		errs = append(errs, errors.New("game.timerDuration must be at least 1 second"))
+	}
+	if c.Game.WinCount < 1 {
+		errs = append(errs, errors.New("game.winCount must be at least 1"))
+	}
+	if c.Game.ChallengeWindow < 0 || c.Game.ChallengeWindow >= c.Game.TimerDuration {
+		errs = append(errs, errors.New("game.challengeWindow must be 0 or more and shorter than game.timerDuration"))
+	}
+	if c.Game.VoteDuration < 1 {
+		errs = append(errs, errors.New("game.voteDuration must be at least 1 second"))
+	}
+	if c.Rooms.MaxRooms < 0 {
+		errs = append(errs, errors.New("rooms.maxRooms must not be negative"))
+	}
+	if c.Rooms.MaxPlayers < 0 || c.Rooms.MaxPlayers == 1 {
+		errs = append(errs, errors.New("rooms.maxPlayers must be 0 or at least 2"))
+	}
+	if !slices.Contains(LogLevels, c.Log.Level) {
+		errs = append(errs, fmt.Errorf("log.level must be one of %s", strings.Join(LogLevels, ", ")))
+	}
+	if !slices.Contains(LogFormats, c.Log.Format) {
+		errs = append(errs, fmt.Errorf("log.format must be one of %s", strings.Join(LogFormats, ", ")))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sampleRatio must be between 0 and 1"))
+	}
+	rates := map[string]ratelimit.Rate{"rateLimit.http": c.RateLimit.HTTP, "rateLimit.events": c.RateLimit.Events}
+	for eventType, rate := range c.RateLimit.EventRates {
+		rates["rateLimit.eventRates."+eventType] = rate
+	}
+	rateNames := make([]string, 0, len(rates))
+	for name := range rates {
+		rateNames = append(rateNames, name)
+	}
+	slices.Sort(rateNames)
+	for _, name := range rateNames {
+		if rates[name].PerSecond < 0 || rates[name].Burst < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+	if c.RateLimit.MaxViolations < 0 || c.RateLimit.RoomsPerAddr < 0 {
+		errs = append(errs, errors.New("rateLimit.maxViolations and rateLimit.roomsPerAddr must not be negative"))
+	}
+	if c.Auth.SessionTTL <= 0 {
+		errs = append(errs, errors.New("auth.sessionTTL must be positive"))
+	}
+	if _, ok := c.Bots.Levels[c.Bots.Difficulty]; !ok {
+		errs = append(errs, fmt.Errorf("bots.difficulty %q is not one of bots.levels", c.Bots.Difficulty))
+	}
+	difficulties := make([]string, 0, len(c.Bots.Levels))
+	for difficulty := range c.Bots.Levels {
+		difficulties = append(difficulties, difficulty)
+	}
+	slices.Sort(difficulties)
+	for _, difficulty := range difficulties {
+		level := c.Bots.Levels[difficulty]
+		if level.MeanDelay < 0 || level.DelayStdDev < 0 || level.MinDelay < 0 {
+			errs = append(errs, fmt.Errorf("bots.levels.%s delays must not be negative", difficulty))
+		}
+		if level.FailChance < 0 || level.FailChance > 1 {
+			errs = append(errs, fmt.Errorf("bots.levels.%s.failChance must be between 0 and 1", difficulty))
+		}
+	}
+	if !slices.Contains(words.Strictnesses, c.Dictionary.Strictness) {
+		errs = append(errs, fmt.Errorf("dictionary.strictness must be one of %s", strings.Join(words.Strictnesses, ", ")))
+	}
+	if c.DrainTimeout < 0 {
+		errs = append(errs, errors.New("drainTimeout must not be negative"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Port is the port from Addr.
+func (c *Config) Port() string {
+	_, port, _ := net.SplitHostPort(c.Addr)
+	return port
+}
+
+// YAML encodes the config as it would be written in a config file, with
+// secrets redacted.
+func (c *Config) YAML() ([]byte, error) {
+	redacted := *c
+	if redacted.Admin.Token != "" {
+		redacted.Admin.Token = "[redacted]"
+	}
+	if redacted.Auth.SessionSecret != "" {
+		redacted.Auth.SessionSecret = "[redacted]"
+	}
+	return yaml.Marshal(&redacted)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setBool(target *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", value)
+	}
+	*target = b
+	return nil
+}
+
+func setFloat(target *float64, value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+	*target = f
+	return nil
+}
+
+func setDuration(target *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*target = d
+	return nil
+}
+
+func setInt(target *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a whole number", value)
+	}
+	*target = n
+	return nil
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Fatalf("config = %+v, want the defaults %+v", c, Default())
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "quik.yaml", `
+addr: ":8000"
+game:
+  timerDuration: 15
+  winCount: 4
+rooms:
+  maxRooms: 10
+drainTimeout: 30s
+`)
+	c, err := Load(
+		[]string{"--config", path, "--win-count", "6"},
+		env(map[string]string{"QUIK_WIN_COUNT": "5", "QUIK_TIMER_DURATION": "20", "QUIK_ALLOWED_ORIGINS": "https://a.example, https://b.example"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":8000" || c.Rooms.MaxRooms != 10 || c.DrainTimeout != 30*time.Second {
+		t.Fatalf("file settings were not applied: %+v", c)
+	}
+	if c.Game.TimerDuration != 20 {
+		t.Fatalf("timerDuration = %d, want the environment to override the file", c.Game.TimerDuration)
+	}
+	if c.Game.WinCount != 6 {
+		t.Fatalf("winCount = %d, want the flag to override the environment", c.Game.WinCount)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(c.AllowedOrigins, want) {
+		t.Fatalf("allowedOrigins = %v, want %v", c.AllowedOrigins, want)
+	}
+	if c.Log != Default().Log {
+		t.Fatalf("log = %+v, want the defaults for unset settings", c.Log)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "quik.toml", `
+addr = ":8001"
+allowedOrigins = ["https://quik.example"]
+
+[tls]
+certFile = "cert.pem"
+keyFile = "key.pem"
+
+[log]
+level = "debug"
+format = "json"
+`)
+	c, err := Load(nil, env(map[string]string{"QUIK_CONFIG": path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":8001" || !c.TLS.Enabled() || c.Log.Format != "json" || c.Log.Level != "debug" {
+		t.Fatalf("config = %+v, want the TOML file applied", c)
+	}
+}
+
+func TestLoadBotLevels(t *testing.T) {
+	path := writeFile(t, "quik.yaml", `
+bots:
+  difficulty: expert
+  levels:
+    expert:
+      meanDelay: 1s
+      delayStdDev: 200ms
+      minDelay: 500ms
+      failChance: 0.01
+`)
+	c, err := Load([]string{"--config", path}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Bots.Levels["expert"]; got.MeanDelay != time.Second || got.MinDelay != 500*time.Millisecond || got.FailChance != 0.01 {
+		t.Fatalf("expert level = %+v, want the file applied", got)
+	}
+	if _, ok := c.Bots.Levels["easy"]; !ok {
+		t.Fatalf("levels = %v, want the built-in levels kept", c.Bots.Levels)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"unknown flag", []string{"--nope"}, nil},
+		{"not a number", nil, map[string]string{"QUIK_WIN_COUNT": "three"}},
+		{"bad duration", []string{"--drain-timeout", "soon"}, nil},
+		{"bad addr", []string{"--addr", "9191"}, nil},
+		{"zero timer", []string{"--timer-duration", "0"}, nil},
+		{"one player", []string{"--max-players", "1"}, nil},
+		{"key without cert", []string{"--tls-key", "key.pem"}, nil},
+		{"redirect without tls", []string{"--tls-redirect-addr", ":80"}, nil},
+		{"csrf with every origin", []string{"--csrf", "true", "--allowed-origins", "*"}, nil},
+		{"challenge window as long as a turn", []string{"--timer-duration", "5", "--challenge-window", "5"}, nil},
+		{"unknown bot difficulty", []string{"--bot-difficulty", "impossible"}, nil},
+		{"unknown answer strictness", []string{"--answer-strictness", "strict"}, nil},
+		{"unknown log level", nil, map[string]string{"QUIK_LOG_LEVEL": "loud"}},
+		{"sample ratio above one", []string{"--trace-sample-ratio", "1.5"}, nil},
+		{"zero session ttl", []string{"--session-ttl", "0s"}, nil},
+		{"negative http rate", []string{"--http-rate", "-1"}, nil},
+		{"missing file", []string{"--config", "does-not-exist.yaml"}, nil},
+		{"unsupported file", []string{"--config", "quik.ini"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.args, env(tt.env))
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestYAMLRedactsSecrets(t *testing.T) {
+	c, err := Load([]string{"--admin-token", "s3cret", "--session-secret", "s1gning"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "s3cret") {
+		t.Fatalf("admin token was printed:\n%s", b)
+	}
+	if strings.Contains(string(b), "s1gning") {
+		t.Fatalf("session secret was printed:\n%s", b)
+	}
+	if c.Admin.Token != "s3cret" || c.Auth.SessionSecret != "s1gning" {
+		t.Fatal("YAML must not change the config")
+	}
+}
@@ -0,0 +1,41 @@
+// Package envelope writes the JSON envelope every HTTP response from the
+// server is wrapped in, so that routes and the middleware in front of them
+// respond alike.
+package envelope
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+// Response is the envelope. Exactly one of Data and Error is set.
+type Response struct {
+	Data  any            `json:"data,omitempty"`
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func Write(w http.ResponseWriter, status int, res *Response) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		slog.Error("unable to encode response", logging.Err(err))
+		status = http.StatusInternalServerError
+		body = []byte(`{"error":{"code":"internal_error","message":"unable to encode response"}}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// WriteError responds with an error, identified by code and described by
+// err.
+func WriteError(w http.ResponseWriter, status int, code string, err error) {
+	Write(w, status, &Response{Error: &ErrorResponse{Code: code, Message: err.Error()}})
+}
@@ -0,0 +1,29 @@
+package envelope
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, http.StatusTeapot, "teapot", errors.New("short and stout"))
+
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if want := `{"error":{"code":"teapot","message":"short and stout"}}` + "\n"; rec.Body.String() != want {
+		t.Fatalf("body = %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestWriteUnencodable(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, http.StatusOK, &Response{Data: func() {}})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
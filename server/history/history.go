@@ -0,0 +1,203 @@
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrGameNotFound = errors.New("game not found")
+
+// Game is a completed game, kept after its room resets the players' win
+// counts.
+type Game struct {
+	Id           string        `json:"id"`
+	RoomId       string        `json:"roomId"`
+	StartedAt    time.Time     `json:"startedAt"`
+	EndedAt      time.Time     `json:"endedAt"`
+	Participants []Participant `json:"participants"`
+	Rounds       []Round       `json:"rounds"`
+	WinnerId     string        `json:"winnerId"`
+}
+
+type Participant struct {
+	PlayerId string `json:"playerId"`
+	// AccountId is the account the player signed in with, empty for
+	// guests.
+	AccountId string `json:"accountId"`
+	Name      string `json:"name"`
+	RoundsWon int    `json:"roundsWon"`
+}
+
+type Round struct {
+	Number    int       `json:"number"`
+	Category  string    `json:"category"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+	// PlayerIds are the players in the round when it started.
+	PlayerIds []string       `json:"playerIds"`
+	Letters   []LetterChoice `json:"letters"`
+	// Eliminations are in the order the players were eliminated.
+	Eliminations []Elimination `json:"eliminations"`
+	WinnerId     string        `json:"winnerId"`
+}
+
+type LetterChoice struct {
+	PlayerId string `json:"playerId"`
+	Letter   string `json:"letter"`
+}
+
+type Elimination struct {
+	PlayerId string `json:"playerId"`
+	// SurvivedSeconds is how long the player lasted in the round.
+	SurvivedSeconds float64 `json:"survivedSeconds"`
+}
+
+func NewGameId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Participant returns the game's participant with the id, adding them if
+// they are not in the game yet.
+func (g *Game) Participant(playerId, name string) *Participant {
+	for i := range g.Participants {
+		if g.Participants[i].PlayerId == playerId {
+			return &g.Participants[i]
+		}
+	}
+	g.Participants = append(g.Participants, Participant{PlayerId: playerId, Name: name})
+	return &g.Participants[len(g.Participants)-1]
+}
+
+// playerId finds the id a player had in the game from either that id or
+// their account id.
+func (g *Game) playerId(identity string) (string, bool) {
+	i := slices.IndexFunc(g.Participants, func(p Participant) bool {
+		return p.PlayerId == identity || (p.AccountId != "" && p.AccountId == identity)
+	})
+	if i == -1 {
+		return "", false
+	}
+	return g.Participants[i].PlayerId, true
+}
+
+// Store keeps completed games.
+type Store interface {
+	Save(game *Game) error
+	Game(gameId string) (*Game, error)
+	// PlayerGames lists the games a player took part in, newest first,
+	// by their player id or account id.
+	PlayerGames(playerId string) ([]*Game, error)
+	// Games lists every game in the order they were saved.
+	Games() ([]*Game, error)
+}
+
+// MemoryStore keeps games for as long as the server runs.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	games []*Game
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Save(game *Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games = append(s.games, game)
+	return nil
+}
+
+func (s *MemoryStore) Game(gameId string) (*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, game := range s.games {
+		if game.Id == gameId {
+			return game, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: id=%s", ErrGameNotFound, gameId)
+}
+
+func (s *MemoryStore) PlayerGames(playerId string) ([]*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	games := []*Game{}
+	for i := len(s.games) - 1; i >= 0; i-- {
+		if _, ok := s.games[i].playerId(playerId); ok {
+			games = append(games, s.games[i])
+		}
+	}
+	return games, nil
+}
+
+func (s *MemoryStore) Games() ([]*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.games), nil
+}
+
+// FileStore appends games to a JSON Lines file and keeps them in memory
+// for lookups, loading the file's games when it is opened.
+type FileStore struct {
+	*MemoryStore
+	path string
+	mu   sync.Mutex
+}
+
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<22)
+	for line := 1; scanner.Scan(); line++ {
+		var game Game
+		if err := json.Unmarshal(scanner.Bytes(), &game); err != nil {
+			return nil, fmt.Errorf("%s is corrupt at line %d: %w", path, line, err)
+		}
+		s.MemoryStore.Save(&game)
+	}
+	return s, scanner.Err()
+}
+
+func (s *FileStore) Save(game *Game) error {
+	line, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Save(game)
+}
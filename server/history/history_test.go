@@ -0,0 +1,114 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func game(id, winnerId string, start time.Time) *Game {
+	return &Game{
+		Id:        id,
+		RoomId:    "room-" + id,
+		StartedAt: start,
+		EndedAt:   start.Add(time.Minute),
+		Participants: []Participant{
+			{PlayerId: "p1", Name: "Kiri", RoundsWon: 1},
+			{PlayerId: "p2", Name: "Mere"},
+		},
+		Rounds: []Round{{
+			Number:    1,
+			Category:  "Animals",
+			StartedAt: start,
+			EndedAt:   start.Add(30 * time.Second),
+			PlayerIds: []string{"p1", "p2"},
+			Letters: []LetterChoice{
+				{PlayerId: "p1", Letter: "A"},
+				{PlayerId: "p2", Letter: "B"},
+				{PlayerId: "p1", Letter: "C"},
+			},
+			Eliminations: []Elimination{{PlayerId: "p2", SurvivedSeconds: 10}},
+			WinnerId:     winnerId,
+		}},
+		WinnerId: winnerId,
+	}
+}
+
+func TestStats(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	games := []*Game{game("g1", "p1", start), game("g2", "p2", start)}
+	games[1].Rounds[0].Letters = append(games[1].Rounds[0].Letters, LetterChoice{PlayerId: "p1", Letter: "C"})
+
+	stats := Stats("p1", games)
+	want := &PlayerStats{
+		PlayerId:               "p1",
+		Name:                   "Kiri",
+		GamesPlayed:            2,
+		GamesWon:               1,
+		WinRate:                0.5,
+		RoundsPlayed:           2,
+		RoundsWon:              1,
+		AverageSurvivalSeconds: 30,
+		FavouriteLetters:       []LetterCount{{Letter: "C", Count: 3}, {Letter: "A", Count: 2}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+
+	stats = Stats("p2", games)
+	if stats.AverageSurvivalSeconds != 10 {
+		t.Fatalf("average survival = %v, want the time to elimination", stats.AverageSurvivalSeconds)
+	}
+
+	games[0].Participants[0].AccountId = "account-1"
+	games[1].Participants[0].AccountId = "account-1"
+	if stats := Stats("account-1", games); stats.GamesPlayed != 2 || stats.GamesWon != 1 {
+		t.Fatalf("stats = %+v, want both games played under the account", stats)
+	}
+
+	stats = Stats("nobody", games)
+	if stats.GamesPlayed != 0 || stats.WinRate != 0 || len(stats.FavouriteLetters) != 0 {
+		t.Fatalf("stats = %+v, want none", stats)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "games.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, g := range []*Game{game("g1", "p1", start), game("g2", "p2", start.Add(time.Hour))} {
+		if err := store.Save(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	games, err := reopened.PlayerGames("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].Id != "g2" || games[1].Id != "g1" {
+		t.Fatalf("games = %v, want g2 then g1", games)
+	}
+	got, err := reopened.Game("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, game("g1", "p1", start)) {
+		t.Fatalf("game = %+v, want it as saved", got)
+	}
+	if all, err := reopened.Games(); err != nil || len(all) != 2 || all[0].Id != "g1" {
+		t.Fatalf("games = %v, %v, want g1 then g2", all, err)
+	}
+	if _, err := reopened.Game("missing"); !errors.Is(err, ErrGameNotFound) {
+		t.Fatalf("err = %v, want ErrGameNotFound", err)
+	}
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+)
+
+// favouriteLetterCount is how many of a player's most chosen letters are
+// reported.
+const favouriteLetterCount = 5
+
+type PlayerStats struct {
+	PlayerId               string        `json:"playerId"`
+	Name                   string        `json:"name"`
+	GamesPlayed            int           `json:"gamesPlayed"`
+	GamesWon               int           `json:"gamesWon"`
+	WinRate                float64       `json:"winRate"`
+	RoundsPlayed           int           `json:"roundsPlayed"`
+	RoundsWon              int           `json:"roundsWon"`
+	AverageSurvivalSeconds float64       `json:"averageSurvivalSeconds"`
+	FavouriteLetters       []LetterCount `json:"favouriteLetters"`
+}
+
+type LetterCount struct {
+	Letter string `json:"letter"`
+	Count  int    `json:"count"`
+}
+
+// Stats sums up a player's games, identifying them by player id or, to
+// combine the games they played under an account, account id. A player who
+// survives a round is counted as surviving until it ended.
+func Stats(identity string, games []*Game) *PlayerStats {
+	stats := &PlayerStats{PlayerId: identity, FavouriteLetters: []LetterCount{}}
+	letters := map[string]int{}
+	survived := 0.0
+	for _, game := range games {
+		playerId, ok := game.playerId(identity)
+		if !ok {
+			continue
+		}
+		stats.GamesPlayed++
+		if game.WinnerId == playerId {
+			stats.GamesWon++
+		}
+		if stats.Name == "" {
+			stats.Name = game.Participant(playerId, "").Name
+		}
+		for _, round := range game.Rounds {
+			if !slices.Contains(round.PlayerIds, playerId) {
+				continue
+			}
+			stats.RoundsPlayed++
+			if round.WinnerId == playerId {
+				stats.RoundsWon++
+			}
+			survived += round.survivedSeconds(playerId)
+			for _, choice := range round.Letters {
+				if choice.PlayerId == playerId {
+					letters[choice.Letter]++
+				}
+			}
+		}
+	}
+	if stats.GamesPlayed > 0 {
+		stats.WinRate = float64(stats.GamesWon) / float64(stats.GamesPlayed)
+	}
+	if stats.RoundsPlayed > 0 {
+		stats.AverageSurvivalSeconds = survived / float64(stats.RoundsPlayed)
+	}
+	for letter, count := range letters {
+		stats.FavouriteLetters = append(stats.FavouriteLetters, LetterCount{Letter: letter, Count: count})
+	}
+	slices.SortFunc(stats.FavouriteLetters, func(a, b LetterCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Letter, b.Letter))
+	})
+	if len(stats.FavouriteLetters) > favouriteLetterCount {
+		stats.FavouriteLetters = stats.FavouriteLetters[:favouriteLetterCount]
+	}
+	return stats
+}
+
+func (r *Round) survivedSeconds(playerId string) float64 {
+	for _, elimination := range r.Eliminations {
+		if elimination.PlayerId == playerId {
+			return elimination.SurvivedSeconds
+		}
+	}
+	if r.EndedAt.IsZero() {
+		return 0
+	}
+	return r.EndedAt.Sub(r.StartedAt).Seconds()
+}
@@ -0,0 +1,191 @@
+package leaderboard
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/history"
+)
+
+const (
+	// InitialRating is an account's Elo rating before its first game.
+	InitialRating = 1500
+	// kFactor caps how far one game moves a rating.
+	kFactor = 32
+)
+
+const (
+	WindowAllTime = "all-time"
+	WindowWeekly  = "weekly"
+)
+
+const (
+	SortRating  = "rating"
+	SortWins    = "wins"
+	SortWinRate = "winRate"
+)
+
+var (
+	Windows = []string{WindowAllTime, WindowWeekly}
+	Sorts   = []string{SortRating, SortWins, SortWinRate}
+)
+
+var ErrInvalidQuery = errors.New("invalid leaderboard query")
+
+// Entry is an account's standing on a leaderboard.
+type Entry struct {
+	Rank        int     `json:"rank"`
+	AccountId   string  `json:"accountId"`
+	Name        string  `json:"name"`
+	GamesPlayed int     `json:"gamesPlayed"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"winRate"`
+	Rating      float64 `json:"rating"`
+}
+
+// table is the standings for one window.
+type table struct {
+	startsAt time.Time
+	entries  map[string]*Entry
+}
+
+// Board ranks accounts by the games they finish. Each game updates the
+// all-time standings and those of the week it ended in, and ratings start
+// over every week. Guests are left out since their ids only last as long
+// as their connection.
+type Board struct {
+	mu      sync.RWMutex
+	allTime *table
+	weekly  *table
+	now     func() time.Time
+}
+
+func New() *Board {
+	return &Board{allTime: newTable(time.Time{}), now: time.Now}
+}
+
+func newTable(startsAt time.Time) *table {
+	return &table{startsAt: startsAt, entries: make(map[string]*Entry)}
+}
+
+// weekStart is the start of the UTC week, from Monday, that t falls in.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// Record adds a finished game to the standings. Games must be recorded in
+// the order they ended.
+func (b *Board) Record(game *history.Game) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.allTime.record(game)
+	week := weekStart(game.EndedAt)
+	if b.weekly == nil || week.After(b.weekly.startsAt) {
+		b.weekly = newTable(week)
+	}
+	if week.Equal(b.weekly.startsAt) {
+		b.weekly.record(game)
+	}
+}
+
+// record updates the standings of a game's account holders. The winner
+// is rated as having beaten each of the others.
+func (t *table) record(game *history.Game) {
+	var winner *Entry
+	losers := []*Entry{}
+	for _, p := range game.Participants {
+		if p.AccountId == "" {
+			continue
+		}
+		entry, ok := t.entries[p.AccountId]
+		if !ok {
+			entry = &Entry{AccountId: p.AccountId, Rating: InitialRating}
+			t.entries[p.AccountId] = entry
+		}
+		entry.Name = p.Name
+		entry.GamesPlayed++
+		if p.PlayerId == game.WinnerId {
+			entry.Wins++
+			winner = entry
+		} else {
+			losers = append(losers, entry)
+		}
+		entry.WinRate = float64(entry.Wins) / float64(entry.GamesPlayed)
+	}
+	if winner == nil {
+		return
+	}
+	gained := 0.0
+	for _, loser := range losers {
+		change := kFactor * (1 - expectedScore(winner.Rating, loser.Rating))
+		loser.Rating -= change
+		gained += change
+	}
+	winner.Rating += gained
+}
+
+// expectedScore is the chance Elo gives a player rated a of beating one
+// rated b.
+func expectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Standings ranks the accounts in a window, best first, returning up to
+// limit entries along with when the window started. The all-time window
+// starts at the zero time.
+func (b *Board) Standings(window, sortBy string, limit int) ([]Entry, time.Time, error) {
+	if !slices.Contains(Windows, window) {
+		return nil, time.Time{}, fmt.Errorf("%w: window must be one of %v", ErrInvalidQuery, Windows)
+	}
+	if !slices.Contains(Sorts, sortBy) {
+		return nil, time.Time{}, fmt.Errorf("%w: sort must be one of %v", ErrInvalidQuery, Sorts)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	t := b.allTime
+	if window == WindowWeekly {
+		week := weekStart(b.now())
+		if b.weekly == nil || !b.weekly.startsAt.Equal(week) {
+			return []Entry{}, week, nil
+		}
+		t = b.weekly
+	}
+	entries := make([]Entry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, *entry)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Or(
+			compareBy(sortBy, b, a),
+			cmp.Compare(b.GamesPlayed, a.GamesPlayed),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.AccountId, b.AccountId),
+		)
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+		entries[i].Rating = math.Round(entries[i].Rating)
+	}
+	return entries, t.startsAt, nil
+}
+
+func compareBy(sortBy string, a, b Entry) int {
+	switch sortBy {
+	case SortWins:
+		return cmp.Compare(a.Wins, b.Wins)
+	case SortWinRate:
+		return cmp.Compare(a.WinRate, b.WinRate)
+	default:
+		return cmp.Compare(a.Rating, b.Rating)
+	}
+}
@@ -0,0 +1,107 @@
+package leaderboard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/history"
+)
+
+// monday is the start of a week.
+var monday = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func game(endedAt time.Time, winnerId string, accountIds ...string) *history.Game {
+	g := &history.Game{EndedAt: endedAt, WinnerId: winnerId}
+	for _, accountId := range accountIds {
+		g.Participants = append(g.Participants, history.Participant{
+			PlayerId:  "player-" + accountId,
+			AccountId: accountId,
+			Name:      "Name " + accountId,
+		})
+	}
+	return g
+}
+
+func TestRecord(t *testing.T) {
+	board := New()
+	board.now = func() time.Time { return monday.Add(time.Hour) }
+	board.Record(game(monday, "player-a", "a", "b", "c"))
+	guest := game(monday, "guest")
+	guest.Participants = append(guest.Participants, history.Participant{PlayerId: "guest", Name: "Guest"})
+	board.Record(guest)
+
+	entries, startsAt, err := board.Standings(WindowAllTime, SortRating, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !startsAt.IsZero() {
+		t.Fatalf("all-time starts at %v, want the zero time", startsAt)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v, want the three account holders", entries)
+	}
+	// The winner takes 16 points from each evenly rated loser.
+	if entries[0].AccountId != "a" || entries[0].Rank != 1 || entries[0].Rating != InitialRating+32 || entries[0].WinRate != 1 {
+		t.Fatalf("first = %+v, want a rated %d", entries[0], InitialRating+32)
+	}
+	for _, entry := range entries[1:] {
+		if entry.Rating != InitialRating-16 || entry.Wins != 0 || entry.GamesPlayed != 1 {
+			t.Fatalf("loser = %+v, want rated %d", entry, InitialRating-16)
+		}
+	}
+
+	// An upset moves ratings further than an expected win.
+	board.Record(game(monday.Add(time.Hour), "player-b", "a", "b"))
+	entries, _, _ = board.Standings(WindowAllTime, SortRating, 2)
+	if len(entries) != 2 || entries[1].AccountId != "b" || entries[1].Rating-(InitialRating-16) <= 16 {
+		t.Fatalf("entries = %+v, want b to gain more than 16 for beating a higher rated player", entries)
+	}
+
+	entries, _, _ = board.Standings(WindowAllTime, SortWinRate, 0)
+	if entries[0].WinRate != 0.5 || entries[2].AccountId != "c" || entries[2].WinRate != 0 {
+		t.Fatalf("entries = %+v, want a and b ahead of c by win rate", entries)
+	}
+	entries, _, _ = board.Standings(WindowAllTime, SortWins, 0)
+	if entries[0].AccountId != "a" || entries[0].Wins != 1 || entries[0].GamesPlayed != 2 {
+		t.Fatalf("entries = %+v, want a first by wins then games played", entries)
+	}
+}
+
+func TestWeekly(t *testing.T) {
+	board := New()
+	now := monday.AddDate(0, 0, 3)
+	board.now = func() time.Time { return now }
+	board.Record(game(monday.Add(-time.Hour), "player-a", "a", "b"))
+	board.Record(game(monday.Add(time.Hour), "player-b", "b", "c"))
+
+	entries, startsAt, err := board.Standings(WindowWeekly, SortRating, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !startsAt.Equal(monday) {
+		t.Fatalf("week starts at %v, want %v", startsAt, monday)
+	}
+	if len(entries) != 2 || entries[0].AccountId != "b" || entries[0].Rating != InitialRating+16 {
+		t.Fatalf("entries = %+v, want only this week's game, rated afresh", entries)
+	}
+	if all, _, _ := board.Standings(WindowAllTime, SortRating, 0); len(all) != 3 {
+		t.Fatalf("all-time entries = %+v, want every account", all)
+	}
+
+	now = monday.AddDate(0, 0, 7)
+	entries, startsAt, _ = board.Standings(WindowWeekly, SortRating, 0)
+	if len(entries) != 0 || !startsAt.Equal(monday.AddDate(0, 0, 7)) {
+		t.Fatalf("entries = %+v from %v, want an empty new week", entries, startsAt)
+	}
+}
+
+func TestStandingsInvalid(t *testing.T) {
+	board := New()
+	if _, _, err := board.Standings("monthly", SortRating, 0); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("err = %v, want ErrInvalidQuery", err)
+	}
+	if _, _, err := board.Standings(WindowAllTime, "losses", 0); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("err = %v, want ErrInvalidQuery", err)
+	}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RequestIdHeader carries the correlation id of a request. An id sent by
+// the client, or by another node forwarding the request, is kept.
+const RequestIdHeader = "X-Request-Id"
+
+// Middleware gives each request a correlation id, adds it to the request's
+// context for logging and logs the request once it has been handled.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIdHeader)
+		if requestId == "" {
+			requestId = newRequestId()
+			r.Header.Set(RequestIdHeader, requestId)
+		}
+		w.Header().Set(RequestIdHeader, requestId)
+		ctx := WithAttrs(r.Context(), KeyRequestId, requestId)
+
+		start := time.Now()
+		rec := &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		slog.InfoContext(ctx, "handled request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.Status,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// StatusRecorder remembers the status code written to a response. It can
+// still be hijacked for WebSocket upgrades.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int
+}
+
+func (r *StatusRecorder) WriteHeader(status int) {
+	r.Status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *StatusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	r.Status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (r *StatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func newRequestId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
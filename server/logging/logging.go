@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Attribute keys shared by every log line, so that logs can be filtered by
+// room, player or event.
+const (
+	KeyRoomId     = "room_id"
+	KeyPlayerId   = "player_id"
+	KeyPlayerName = "player_name"
+	KeyEvent      = "event"
+	KeyRequestId  = "request_id"
+	KeyNodeId     = "node_id"
+	KeyGameId     = "game_id"
+	KeyAccountId  = "account_id"
+	KeyError      = "error"
+)
+
+const redacted = "[redacted]"
+
+type Options struct {
+	// Level is one of debug, info, warn or error.
+	Level string
+	// Format is text or json.
+	Format string
+	// RedactNames replaces player names with a placeholder.
+	RedactNames bool
+}
+
+// New creates a logger writing to w that adds the attributes stored in the
+// context of each log call.
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, err
+	}
+	handlerOpts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if opts.RedactNames && a.Key == KeyPlayerName {
+				return slog.String(KeyPlayerName, redacted)
+			}
+			return a
+		},
+	}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	return slog.New(&contextHandler{handler}), nil
+}
+
+// Setup makes a logger created with New the default for log/slog.
+func Setup(w io.Writer, opts Options) error {
+	logger, err := New(w, opts)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// Err is the attribute used to log an error.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+type contextKey struct{}
+
+// WithAttrs returns a context whose log calls, e.g. slog.InfoContext, carry
+// the given key-value pairs.
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	attrs := append(attrsFrom(ctx), argsToAttrs(args)...)
+	return context.WithValue(ctx, contextKey{}, attrs)
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs[:len(attrs):len(attrs)]
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// contextHandler adds the attributes stored by WithAttrs to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFrom(ctx); len(attrs) > 0 {
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	lines := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid JSON log line %q: %s", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestContextAttrsAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Options{Level: "info", Format: "json", RedactNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithAttrs(context.Background(), KeyRequestId, "req-1")
+	logger.InfoContext(ctx, "player added", KeyRoomId, "room-1", KeyPlayerName, "Kiri")
+	logger.DebugContext(ctx, "hidden below the level")
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("got %d log lines, want 1", len(lines))
+	}
+	entry := lines[0]
+	if entry[KeyRequestId] != "req-1" || entry[KeyRoomId] != "room-1" {
+		t.Fatalf("entry = %v, want the context and call attributes", entry)
+	}
+	if entry[KeyPlayerName] != redacted {
+		t.Fatalf("player name = %v, want it redacted", entry[KeyPlayerName])
+	}
+}
+
+func TestNewRejectsUnknownOptions(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Options{Level: "loud", Format: "text"}); err == nil {
+		t.Fatal("expected an unknown level to be rejected")
+	}
+	if _, err := New(&bytes.Buffer{}, Options{Level: "info", Format: "xml"}); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+}
+
+func TestMiddlewareCorrelatesRequests(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Options{Level: "info", Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "handling")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/room/abc", nil)
+	req.Header.Set(RequestIdHeader, "from-client")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIdHeader); got != "from-client" {
+		t.Fatalf("response request id = %q, want the client's", got)
+	}
+	lines := decodeLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	for _, entry := range lines {
+		if entry[KeyRequestId] != "from-client" {
+			t.Fatalf("entry = %v, want the request id", entry)
+		}
+	}
+	if lines[1]["status"] != float64(http.StatusTeapot) {
+		t.Fatalf("status = %v, want %d", lines[1]["status"], http.StatusTeapot)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get(RequestIdHeader) == "" {
+		t.Fatal("expected a request id to be generated")
+	}
+}
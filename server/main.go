@@ -1,42 +1,248 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/campbell-rehu/quik-be/api"
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/cluster"
+	"github.com/campbell-rehu/quik-be/config"
+	"github.com/campbell-rehu/quik-be/history"
+	"github.com/campbell-rehu/quik-be/leaderboard"
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/matchmaking"
+	"github.com/campbell-rehu/quik-be/metrics"
+	"github.com/campbell-rehu/quik-be/names"
+	"github.com/campbell-rehu/quik-be/origins"
+	"github.com/campbell-rehu/quik-be/ratelimit"
+	"github.com/campbell-rehu/quik-be/room"
 	"github.com/campbell-rehu/quik-be/socket"
+	"github.com/campbell-rehu/quik-be/tlsserver"
+	"github.com/campbell-rehu/quik-be/tracing"
+	"github.com/campbell-rehu/quik-be/words"
 	"github.com/rs/cors"
 )
 
-const PORT = "9191"
+// shutdownGracePeriod bounds how long in-flight HTTP requests get to finish
+// once the rooms have been drained.
+const shutdownGracePeriod = 5 * time.Second
 
 func main() {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.PrintConfig {
+		b, err := cfg.YAML()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(b)
+		os.Exit(0)
+	}
+	err = logging.Setup(os.Stdout, logging.Options{
+		Level:       cfg.Log.Level,
+		Format:      cfg.Log.Format,
+		RedactNames: cfg.Log.RedactNames,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names.SetFilter(newNameFilter(cfg.Names))
+	wordList := newDictionaryList(cfg.Dictionary.Dir)
+	room.SetDictionary(words.NewDictionary(wordList, words.Strictness(cfg.Dictionary.Strictness)))
+	room.Configure(room.Settings{
+		TimerDuration:   cfg.Game.TimerDuration,
+		WinCount:        cfg.Game.WinCount,
+		ChallengeWindow: cfg.Game.ChallengeWindow,
+		VoteDuration:    cfg.Game.VoteDuration,
+		MaxRooms:        cfg.Rooms.MaxRooms,
+		MaxPlayers:      cfg.Rooms.MaxPlayers,
+		EventLogDir:     cfg.Rooms.EventLogDir,
+	})
+
 	router := http.NewServeMux()
 	cors := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
+		AllowedOrigins: cfg.AllowedOrigins,
 		AllowedMethods: []string{
 			http.MethodPost,
 			http.MethodGet,
+			http.MethodDelete,
 			http.MethodOptions,
 		},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: false,
 	})
 
-	roomHandler := &api.RoomHandler{}
-	io := socket.NewSocket()
+	node := cluster.NewNode(nodeInfo(cfg), newClusterAdapter(cfg.Cluster.RedisAddr))
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		NodeId:      node.Id,
+	})
+	if err != nil {
+		fatal("unable to set up tracing", err)
+	}
+	var roomQuota *ratelimit.RoomQuota
+	if cfg.RateLimit.Enabled {
+		roomQuota = ratelimit.NewRoomQuota(cfg.RateLimit.RoomsPerAddr)
+	}
+	games := newHistoryStore(cfg.Rooms.HistoryFile)
+	board := newLeaderboard(games)
+	node.SetRoomState(room.Snapshots{})
+	room.SetHooks(room.Hooks{
+		Accepts: node.Prefers,
+		OnAdd:   func(r *room.Room) { node.Own(r.Id) },
+		OnRemove: func(roomId string) {
+			node.Disown(roomId)
+			if roomQuota != nil {
+				roomQuota.Release(roomId)
+			}
+		},
+		OnGameEnded: func(game *history.Game) {
+			if err := games.Save(game); err != nil {
+				slog.Error("unable to save game", logging.KeyRoomId, game.RoomId, logging.KeyGameId, game.Id, logging.Err(err))
+			}
+			board.Record(game)
+		},
+	})
+	go node.Run()
+	go func() {
+		for range time.Tick(room.EmptyRoomTimeout) {
+			room.RemoveEmptyRooms(room.EmptyRoomTimeout)
+		}
+	}()
+
+	if cfg.Auth.SessionSecret == "" {
+		slog.Warn("no session secret is set, players will be signed out when the server restarts")
+	}
+	sessions := auth.NewSessions(cfg.Auth.SessionSecret, cfg.Auth.SessionTTL)
+	accounts := &auth.Accounts{Store: newAccountStore(cfg.Auth.AccountsFile)}
+
+	roomHandler := &api.RoomHandler{
+		Cluster:    node,
+		Sessions:   sessions,
+		RoomQuota:  roomQuota,
+		TrustProxy: cfg.RateLimit.TrustProxy,
+	}
+	io, err := socket.NewSocket(node)
+	if err != nil {
+		fatal("unable to subscribe to room broadcasts", err)
+	}
+	node.SetLeaseLostHandler(func(roomId string) {
+		io.EvictRoom(roomId)
+		if roomQuota != nil {
+			roomQuota.Release(roomId)
+		}
+	})
+	queue := matchmaking.NewQueue(io)
+	io.SetMatchmakingQueue(queue)
+	io.SetSessions(sessions)
+	allowedOrigins := origins.NewChecker(cfg.AllowedOrigins)
+	if allowedOrigins.AllowsAny() {
+		slog.Warn("every origin is allowed, so any website can connect players to the server")
+	}
+	io.SetAllowedOrigins(allowedOrigins)
+	if cfg.Bots.Enabled {
+		io.SetBots(cfg.Bots.Levels, cfg.Bots.Difficulty, newWordList(wordList, cfg.Bots.WordList))
+		roomHandler.Bots = io
+		if cfg.Bots.FillQueue {
+			queue.SetFiller(io)
+		}
+	}
+	var handler http.Handler = router
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.NewLimiter()
+		io.SetEventLimits(&ratelimit.Events{
+			Limiter:       limiter,
+			Rate:          cfg.RateLimit.Events,
+			Rates:         cfg.RateLimit.EventRates,
+			MaxViolations: cfg.RateLimit.MaxViolations,
+		})
+		httpLimits := &ratelimit.HTTP{
+			Limiter:    limiter,
+			Rate:       cfg.RateLimit.HTTP,
+			TrustProxy: cfg.RateLimit.TrustProxy,
+			// Probes and scrapes come often from a few addresses, and
+			// socket.io polls; its events are limited instead.
+			Exempt: []string{"GET /healthz", "GET /readyz", "GET /metrics", "/socket.io/"},
+		}
+		handler = httpLimits.Middleware(router, router)
+	}
+	if cfg.CSRF {
+		handler = allowedOrigins.CSRF(handler)
+	}
+	matchmakingHandler := &api.MatchmakingHandler{Queue: queue, Sessions: sessions}
+	accountHandler := &api.AccountHandler{Accounts: accounts, Sessions: sessions}
+	docsHandler := &api.DocsHandler{}
+	healthHandler := &api.HealthHandler{Checks: map[string]func() error{
+		"draining": func() error {
+			if io.Draining() {
+				return errors.New("server is shutting down")
+			}
+			return nil
+		},
+		"store": node.Adapter().Ping,
+	}}
+	historyHandler := &api.HistoryHandler{Games: games}
+	leaderboardHandler := &api.LeaderboardHandler{Board: board}
+	adminHandler := &api.AdminHandler{Token: cfg.Admin.Token, Notifier: io, Cluster: node}
 	router.HandleFunc("POST /room", roomHandler.CreateRoom)
 	router.HandleFunc("GET /room/{roomId}", roomHandler.JoinRoom)
 	router.HandleFunc("POST /room/{roomId}/addPlayer", roomHandler.AddPlayerToRoom)
+	router.HandleFunc("POST /room/{roomId}/bots", roomHandler.AddBot)
+	router.HandleFunc("GET /room/{roomId}/replay", roomHandler.Replay)
+	router.HandleFunc("POST /matchmaking", matchmakingHandler.JoinQueue)
+	router.HandleFunc("POST /accounts", accountHandler.CreateAccount)
+	router.HandleFunc("GET /accounts/me", accountHandler.Me)
+	router.HandleFunc("POST /sessions", accountHandler.CreateSession)
+	router.HandleFunc("GET /players/{playerId}/stats", historyHandler.PlayerStats)
+	router.HandleFunc("GET /players/{playerId}/games", historyHandler.PlayerGames)
+	router.HandleFunc("GET /games/{gameId}", historyHandler.Game)
+	router.HandleFunc("GET /leaderboard", leaderboardHandler.Leaderboard)
+	router.HandleFunc("GET /healthz", healthHandler.Healthz)
+	router.HandleFunc("GET /readyz", healthHandler.Readyz)
+	router.HandleFunc("GET /admin/rooms", adminHandler.ListRooms)
+	router.HandleFunc("DELETE /admin/rooms/{roomId}", adminHandler.EndRoom)
+	router.HandleFunc("DELETE /admin/rooms/{roomId}/players/{playerId}", adminHandler.KickPlayer)
+	router.HandleFunc("POST /admin/broadcast", adminHandler.Broadcast)
+	router.HandleFunc("GET /openapi.json", docsHandler.OpenAPI)
+	router.HandleFunc("GET /asyncapi.json", docsHandler.AsyncAPI)
 	router.HandleFunc("/socket.io/", io.HandleHTTP)
+	router.HandleFunc("GET /ws", io.HandleNativeWS)
+	router.Handle("GET /metrics", metrics.Handler())
 
-	fmt.Printf("Listening on port %s\n", PORT)
+	slog.Info("listening", logging.KeyNodeId, node.Id, "addr", cfg.Addr)
 
-	go http.ListenAndServe(fmt.Sprintf(":%s", PORT), cors.Handler(router))
+	server := &http.Server{Addr: cfg.Addr, Handler: logging.Middleware(metrics.Middleware(router, tracing.Middleware(router, cors.Handler(handler))))}
+	if cfg.TLS.Enabled() {
+		serveTLS(server, cfg.TLS, cfg.Port())
+	}
+	go func() {
+		var err error
+		if cfg.TLS.Enabled() {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("unable to listen", err, "addr", cfg.Addr)
+		}
+	}()
 
 	io.RegisterWSHandlers()
 
@@ -64,5 +270,163 @@ func main() {
 	}()
 
 	<-exit
+	shutdown(server, io, cfg.DrainTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("unable to flush traces", logging.Err(err))
+	}
+	cancel()
 	os.Exit(0)
 }
+
+// shutdown warns every room that the server is going away, gives players
+// until the drain deadline, then hands the rooms over to the rest of the
+// cluster and stops serving.
+func shutdown(server *http.Server, io *socket.Socket, drainTimeout time.Duration) {
+	slog.Info("shutting down, draining rooms", "drain_timeout", drainTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	io.Shutdown(ctx)
+
+	ctx, cancel = context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("unable to shut down the server cleanly", logging.Err(err))
+	}
+}
+
+// serveTLS sets the server up to serve the configured certificate, reloading
+// it when it is renewed, and starts the listener redirecting plain HTTP to
+// it. Both stop when the server shuts down.
+func serveTLS(server *http.Server, cfg config.TLSConfig, port string) {
+	cert, err := tlsserver.LoadCertificate(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		fatal("unable to load the TLS certificate", err)
+	}
+	tlsserver.Configure(server, cert, cfg.HTTP2)
+	if cfg.ReloadInterval > 0 {
+		server.RegisterOnShutdown(cert.Watch(cfg.ReloadInterval))
+	}
+	if cfg.RedirectAddr == "" {
+		return
+	}
+	redirect := &http.Server{Addr: cfg.RedirectAddr, Handler: tlsserver.Redirect(port), ReadHeaderTimeout: 10 * time.Second}
+	server.RegisterOnShutdown(func() { redirect.Close() })
+	go func() {
+		slog.Info("redirecting to HTTPS", "addr", cfg.RedirectAddr)
+		if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("unable to listen", err, "addr", cfg.RedirectAddr)
+		}
+	}()
+}
+
+// nodeInfo identifies this replica to the rest of the cluster.
+func nodeInfo(cfg *config.Config) cluster.NodeInfo {
+	info := cluster.NodeInfo{Id: cfg.Cluster.NodeId, Addr: cfg.Cluster.NodeAddr}
+	if info.Id == "" {
+		info.Id = cluster.NewNodeId()
+	}
+	if info.Addr == "" {
+		scheme := "http"
+		if cfg.TLS.Enabled() {
+			scheme = "https"
+		}
+		info.Addr = fmt.Sprintf("%s://localhost:%s", scheme, cfg.Port())
+	}
+	return info
+}
+
+// newClusterAdapter shares broadcasts through Redis when a redis address is
+// configured, so that several replicas can run behind a load balancer.
+func newClusterAdapter(addr string) cluster.Adapter {
+	if addr == "" {
+		return cluster.NewMemoryAdapter()
+	}
+	adapter, err := cluster.NewRedisAdapter(addr)
+	if err != nil {
+		fatal("unable to connect to redis", err, "addr", addr)
+	}
+	return adapter
+}
+
+func newNameFilter(cfg config.NamesConfig) names.Filter {
+	if !cfg.Filter {
+		return nil
+	}
+	filters := names.Filters{names.DefaultBlocklist()}
+	if cfg.BlocklistFile != "" {
+		blocklist, err := names.LoadBlocklist(cfg.BlocklistFile)
+		if err != nil {
+			fatal("unable to load name blocklist", err, "path", cfg.BlocklistFile)
+		}
+		filters = append(filters, blocklist)
+	}
+	return filters
+}
+
+// newDictionaryList is the built-in word list with the lists in dir added.
+func newDictionaryList(dir string) *words.List {
+	list := words.Default()
+	if dir != "" {
+		extra, err := words.LoadDir(dir)
+		if err != nil {
+			fatal("unable to load dictionary", err, "dir", dir)
+		}
+		list.Merge(extra)
+	}
+	return list
+}
+
+// newWordList is the dictionary's words with the list in path added.
+func newWordList(dictionary *words.List, path string) *words.List {
+	list := words.New()
+	list.Merge(dictionary)
+	if path != "" {
+		extra, err := words.Load(path)
+		if err != nil {
+			fatal("unable to load word list", err, "path", path)
+		}
+		list.Merge(extra)
+	}
+	return list
+}
+
+func newAccountStore(path string) auth.Store {
+	if path == "" {
+		return auth.NewMemoryStore()
+	}
+	store, err := auth.OpenFileStore(path)
+	if err != nil {
+		fatal("unable to open accounts", err, "path", path)
+	}
+	return store
+}
+
+func newHistoryStore(path string) history.Store {
+	if path == "" {
+		return history.NewMemoryStore()
+	}
+	store, err := history.OpenFileStore(path)
+	if err != nil {
+		fatal("unable to open game history", err, "path", path)
+	}
+	return store
+}
+
+// newLeaderboard ranks the games already in the history.
+func newLeaderboard(games history.Store) *leaderboard.Board {
+	board := leaderboard.New()
+	saved, err := games.Games()
+	if err != nil {
+		fatal("unable to load game history", err)
+	}
+	for _, game := range saved {
+		board.Record(game)
+	}
+	return board
+}
+
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append(args, logging.Err(err))...)
+	os.Exit(1)
+}
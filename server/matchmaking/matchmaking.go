@@ -0,0 +1,270 @@
+package matchmaking
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/names"
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+const (
+	DefaultMatchSize    = 2
+	DefaultQueueTimeout = 60 * time.Second
+)
+
+// Notifier tells queued players what happened to their ticket.
+type Notifier interface {
+	NotifyQueuePosition(playerId string, position int, waiting int)
+	NotifyMatchFound(playerId string, room *roomPkg.Room)
+	NotifyQueueTimeout(playerId string)
+}
+
+// Filler seats bots in a room.
+type Filler interface {
+	FillRoom(room *roomPkg.Room, seats int)
+}
+
+var (
+	ErrInvalidTicket = errors.New("invalid matchmaking ticket")
+	ErrAlreadyQueued = errors.New("player is already queued")
+)
+
+type Ticket struct {
+	PlayerId    string            `json:"playerId"`
+	PlayerName  string            `json:"playerName"`
+	AccountId   string            `json:"accountId"`
+	Preferences types.Preferences `json:"preferences"`
+	JoinedAt    time.Time         `json:"joinedAt"`
+	timer       *time.Timer
+}
+
+type Queue struct {
+	mu        sync.Mutex
+	waiting   []*Ticket
+	notifier  Notifier
+	filler    Filler
+	matchSize int
+	timeout   time.Duration
+}
+
+func NewQueue(notifier Notifier) *Queue {
+	return &Queue{
+		waiting:   []*Ticket{},
+		notifier:  notifier,
+		matchSize: DefaultMatchSize,
+		timeout:   DefaultQueueTimeout,
+	}
+}
+
+// SetFiller has players who wait out their ticket seated in a room with
+// bots instead of timing out of the queue.
+func (q *Queue) SetFiller(filler Filler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.filler = filler
+}
+
+// Join puts the player into the queue and returns their position in it. If
+// enough compatible players are waiting a room is created straight away.
+// accountId is empty for guests.
+func (q *Queue) Join(playerId, playerName, accountId string, preferences types.Preferences) (int, error) {
+	if playerId == "" {
+		return 0, fmt.Errorf("%w: player id is required", ErrInvalidTicket)
+	}
+	playerName, err := names.Clean(playerName)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidTicket, err)
+	}
+	if err := validatePreferences(preferences); err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.indexOf(playerId) != -1 {
+		return 0, fmt.Errorf("%w: id=%s", ErrAlreadyQueued, playerId)
+	}
+
+	ticket := &Ticket{
+		PlayerId:    playerId,
+		PlayerName:  playerName,
+		AccountId:   accountId,
+		Preferences: preferences,
+		JoinedAt:    time.Now(),
+	}
+	ticket.timer = time.AfterFunc(q.timeout, func() { q.expire(ticket) })
+	q.waiting = append(q.waiting, ticket)
+	slog.Info("player joined the matchmaking queue", logging.KeyPlayerId, playerId, logging.KeyPlayerName, playerName)
+
+	q.match()
+	q.notifyPositions()
+
+	return q.indexOf(playerId) + 1, nil
+}
+
+// Leave removes the player from the queue, reporting whether they were queued.
+func (q *Queue) Leave(playerId string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.indexOf(playerId)
+	if i == -1 {
+		return false
+	}
+	q.waiting[i].timer.Stop()
+	q.remove(i)
+	slog.Info("player left the matchmaking queue", logging.KeyPlayerId, playerId)
+	q.notifyPositions()
+	return true
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+func (q *Queue) expire(ticket *Ticket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := slices.Index(q.waiting, ticket)
+	if i == -1 {
+		return
+	}
+	q.remove(i)
+	if q.filler != nil {
+		slog.Info("no match found, seating player with bots", logging.KeyPlayerId, ticket.PlayerId)
+		q.seat([]*Ticket{ticket}, ticket.Preferences)
+	} else {
+		slog.Info("player timed out of the matchmaking queue", logging.KeyPlayerId, ticket.PlayerId)
+		q.notifier.NotifyQueueTimeout(ticket.PlayerId)
+	}
+	q.notifyPositions()
+}
+
+// match seats groups of compatible players, oldest tickets first, until no
+// full group can be formed.
+func (q *Queue) match() {
+	for {
+		group, preferences := q.findGroup()
+		if group == nil {
+			return
+		}
+		for _, ticket := range group {
+			ticket.timer.Stop()
+			q.remove(slices.Index(q.waiting, ticket))
+		}
+		q.seat(group, preferences)
+	}
+}
+
+func (q *Queue) findGroup() ([]*Ticket, types.Preferences) {
+	for i, first := range q.waiting {
+		group := []*Ticket{first}
+		preferences := first.Preferences
+		for _, candidate := range q.waiting[i+1:] {
+			merged, ok := mergePreferences(preferences, candidate.Preferences)
+			if !ok {
+				continue
+			}
+			group = append(group, candidate)
+			preferences = merged
+			if len(group) == q.matchSize {
+				return group, preferences
+			}
+		}
+	}
+	return nil, types.Preferences{}
+}
+
+// seat puts a group in a new room, filling any seats left over with bots.
+// Players who cannot be seated are told their ticket ended, so they can
+// queue again.
+func (q *Queue) seat(group []*Ticket, preferences types.Preferences) {
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		slog.Error("matchmaking could not create a room", logging.Err(err))
+		for _, ticket := range group {
+			q.notifier.NotifyQueueTimeout(ticket.PlayerId)
+		}
+		return
+	}
+	room.SetPreferences(preferences)
+	seated := []*Ticket{}
+	for _, ticket := range group {
+		if err := room.AddAccountPlayerToRoom(ticket.PlayerId, ticket.PlayerName, ticket.AccountId); err != nil {
+			slog.Error("matchmaking could not seat player", logging.KeyRoomId, room.Id, logging.KeyPlayerId, ticket.PlayerId, logging.Err(err))
+			q.notifier.NotifyQueueTimeout(ticket.PlayerId)
+			continue
+		}
+		seated = append(seated, ticket)
+	}
+	if len(seated) == 0 {
+		roomPkg.EndRoom(room.Id)
+		return
+	}
+	if q.filler != nil && len(seated) < q.matchSize {
+		q.filler.FillRoom(room, q.matchSize-len(seated))
+	}
+	slog.Info("matchmaking created room", logging.KeyRoomId, room.Id, "players", len(seated))
+	for _, ticket := range seated {
+		q.notifier.NotifyMatchFound(ticket.PlayerId, room)
+	}
+}
+
+func (q *Queue) notifyPositions() {
+	for i, ticket := range q.waiting {
+		q.notifier.NotifyQueuePosition(ticket.PlayerId, i+1, len(q.waiting))
+	}
+}
+
+func (q *Queue) indexOf(playerId string) int {
+	return slices.IndexFunc(q.waiting, func(t *Ticket) bool {
+		return t.PlayerId == playerId
+	})
+}
+
+func (q *Queue) remove(i int) {
+	q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+}
+
+func validatePreferences(preferences types.Preferences) error {
+	if preferences.Difficulty != "" && !slices.Contains(types.Difficulties, preferences.Difficulty) {
+		return fmt.Errorf("%w: unknown difficulty=%s", ErrInvalidTicket, preferences.Difficulty)
+	}
+	if preferences.Language != "" && !slices.Contains(types.Languages, preferences.Language) {
+		return fmt.Errorf("%w: unknown language=%s", ErrInvalidTicket, preferences.Language)
+	}
+	if preferences.LetterSet != "" && !slices.Contains(types.LetterSets, preferences.LetterSet) {
+		return fmt.Errorf("%w: unknown letter set=%s", ErrInvalidTicket, preferences.LetterSet)
+	}
+	return nil
+}
+
+// mergePreferences combines two sets of preferences, treating empty fields as
+// wildcards. ok is false when the preferences conflict.
+func mergePreferences(a, b types.Preferences) (merged types.Preferences, ok bool) {
+	merge := func(x, y string) (string, bool) {
+		if x == "" {
+			return y, true
+		}
+		if y == "" || x == y {
+			return x, true
+		}
+		return "", false
+	}
+	var okDifficulty, okLanguage, okLetterSet bool
+	merged.Difficulty, okDifficulty = merge(a.Difficulty, b.Difficulty)
+	merged.Language, okLanguage = merge(a.Language, b.Language)
+	merged.LetterSet, okLetterSet = merge(a.LetterSet, b.LetterSet)
+	return merged, okDifficulty && okLanguage && okLetterSet
+}
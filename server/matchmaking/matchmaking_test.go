@@ -0,0 +1,258 @@
+package matchmaking
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+type fakeNotifier struct {
+	mu        sync.Mutex
+	positions map[string][2]int
+	matches   map[string]*roomPkg.Room
+	timeouts  []string
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{positions: make(map[string][2]int), matches: make(map[string]*roomPkg.Room)}
+}
+
+func (n *fakeNotifier) NotifyQueuePosition(playerId string, position int, waiting int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.positions[playerId] = [2]int{position, waiting}
+}
+
+func (n *fakeNotifier) NotifyMatchFound(playerId string, room *roomPkg.Room) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.matches[playerId] = room
+}
+
+func (n *fakeNotifier) NotifyQueueTimeout(playerId string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.timeouts = append(n.timeouts, playerId)
+}
+
+func (n *fakeNotifier) match(playerId string) *roomPkg.Room {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.matches[playerId]
+}
+
+type fakeFiller struct {
+	mu    sync.Mutex
+	seats map[string]int
+}
+
+func (f *fakeFiller) FillRoom(room *roomPkg.Room, seats int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seats[room.Id] = seats
+}
+
+func TestJoin(t *testing.T) {
+	notifier := newFakeNotifier()
+	q := NewQueue(notifier)
+
+	if position, err := q.Join("p1", "Aroha", "", types.Preferences{Difficulty: types.Easy}); err != nil || position != 1 {
+		t.Fatalf("Join = %d, %v, want position 1", position, err)
+	}
+	if position, err := q.Join("p2", "Mere", "", types.Preferences{Difficulty: types.Hard}); err != nil || position != 2 {
+		t.Fatalf("Join = %d, %v, want position 2", position, err)
+	}
+	if got, want := notifier.positions["p1"], [2]int{1, 2}; got != want {
+		t.Fatalf("p1 position = %v, want %v", got, want)
+	}
+	if _, err := q.Join("p1", "Aroha", "", types.Preferences{}); !errors.Is(err, ErrAlreadyQueued) {
+		t.Fatalf("err = %v, want %v", err, ErrAlreadyQueued)
+	}
+
+	if !q.Leave("p1") || q.Leave("p1") {
+		t.Fatal("expected p1 to leave the queue once")
+	}
+	if got, want := notifier.positions["p2"], [2]int{1, 1}; got != want {
+		t.Fatalf("p2 position = %v after p1 left, want %v", got, want)
+	}
+	q.Leave("p2")
+}
+
+func TestJoinInvalid(t *testing.T) {
+	q := NewQueue(newFakeNotifier())
+	tests := map[string]struct {
+		playerId    string
+		preferences types.Preferences
+	}{
+		"no player id":       {"", types.Preferences{}},
+		"unknown difficulty": {"p1", types.Preferences{Difficulty: "Impossible"}},
+		"unknown language":   {"p1", types.Preferences{Language: "xx"}},
+		"unknown letter set": {"p1", types.Preferences{LetterSet: "greek"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := q.Join(tt.playerId, "Aroha", "", tt.preferences); !errors.Is(err, ErrInvalidTicket) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidTicket)
+			}
+		})
+	}
+	if q.Len() != 0 {
+		t.Fatalf("queue length = %d, want 0", q.Len())
+	}
+}
+
+func TestMatchSeatsCompatiblePlayers(t *testing.T) {
+	notifier := newFakeNotifier()
+	q := NewQueue(notifier)
+
+	q.Join("m1", "Aroha", "account-1", types.Preferences{Difficulty: types.Easy})
+	q.Join("m2", "Mere", "", types.Preferences{Difficulty: types.Hard})
+	if _, err := q.Join("m3", "Tama", "", types.Preferences{LetterSet: types.LetterSetHard}); err != nil {
+		t.Fatal(err)
+	}
+
+	room := notifier.match("m1")
+	if room == nil || notifier.match("m3") != room {
+		t.Fatalf("m1 and m3 were not seated together")
+	}
+	defer roomPkg.EndRoom(room.Id)
+	if notifier.match("m2") != nil {
+		t.Fatal("m2 was seated with players wanting another difficulty")
+	}
+	if want := (types.Preferences{Difficulty: types.Easy, LetterSet: types.LetterSetHard}); room.Preferences != want {
+		t.Fatalf("room preferences = %+v, want %+v", room.Preferences, want)
+	}
+	if room.GetPlayerCount() != 2 || room.Players["m1"].AccountId != "account-1" {
+		t.Fatalf("room players = %v, want m1 signed in and m3", room.Players)
+	}
+	if !room.HasLetter("Q") || room.HasLetter("1") {
+		t.Fatal("room does not use the hard letter set it was matched with")
+	}
+	if q.Len() != 1 || notifier.positions["m2"] != [2]int{1, 1} {
+		t.Fatalf("queue length = %d, m2 position = %v, want m2 alone at the front", q.Len(), notifier.positions["m2"])
+	}
+	q.Leave("m2")
+}
+
+func TestMatchKeepsLanguagesApart(t *testing.T) {
+	notifier := newFakeNotifier()
+	q := NewQueue(notifier)
+
+	q.Join("l1", "Aroha", "", types.Preferences{Language: types.LanguageMaori})
+	q.Join("l2", "Mere", "", types.Preferences{Language: types.LanguageEnglish})
+	if notifier.match("l1") != nil || notifier.match("l2") != nil || q.Len() != 2 {
+		t.Fatal("players wanting different languages were seated together")
+	}
+
+	q.Join("l3", "Tama", "", types.Preferences{})
+	room := notifier.match("l1")
+	if room == nil || notifier.match("l3") != room {
+		t.Fatal("l3 was not seated with the first player waiting")
+	}
+	defer roomPkg.EndRoom(room.Id)
+	if room.Preferences.Language != types.LanguageMaori {
+		t.Fatalf("room language = %q, want %q", room.Preferences.Language, types.LanguageMaori)
+	}
+	q.Leave("l2")
+}
+
+func TestMatchOnlyNotifiesSeatedPlayers(t *testing.T) {
+	roomPkg.Configure(roomPkg.Settings{MaxPlayers: 1})
+	defer roomPkg.Configure(roomPkg.Settings{
+		TimerDuration:   roomPkg.DefaultTimerDuration,
+		WinCount:        roomPkg.DefaultWinCount,
+		ChallengeWindow: roomPkg.DefaultChallengeWindow,
+		VoteDuration:    roomPkg.DefaultVoteDuration,
+	})
+	notifier := newFakeNotifier()
+	q := NewQueue(notifier)
+
+	q.Join("s1", "Aroha", "", types.Preferences{})
+	q.Join("s2", "Mere", "", types.Preferences{})
+
+	room := notifier.match("s1")
+	if room == nil {
+		t.Fatal("s1 was not seated")
+	}
+	defer roomPkg.EndRoom(room.Id)
+	if notifier.match("s2") != nil || room.Players["s2"] != nil {
+		t.Fatal("s2 was told they were seated in a full room")
+	}
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if want := []string{"s2"}; !reflect.DeepEqual(notifier.timeouts, want) {
+		t.Fatalf("timeouts = %v, want %v", notifier.timeouts, want)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	notifier := newFakeNotifier()
+	q := NewQueue(notifier)
+	q.timeout = 10 * time.Millisecond
+
+	q.Join("t1", "Aroha", "", types.Preferences{})
+	time.Sleep(50 * time.Millisecond)
+
+	if q.Len() != 0 {
+		t.Fatalf("queue length = %d, want 0", q.Len())
+	}
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if want := []string{"t1"}; !reflect.DeepEqual(notifier.timeouts, want) {
+		t.Fatalf("timeouts = %v, want %v", notifier.timeouts, want)
+	}
+}
+
+func TestQueueTimeoutFillsWithBots(t *testing.T) {
+	notifier := newFakeNotifier()
+	filler := &fakeFiller{seats: make(map[string]int)}
+	q := NewQueue(notifier)
+	q.SetFiller(filler)
+	q.timeout = 10 * time.Millisecond
+
+	q.Join("f1", "Aroha", "", types.Preferences{Difficulty: types.Moderate})
+	time.Sleep(50 * time.Millisecond)
+
+	room := notifier.match("f1")
+	if room == nil {
+		t.Fatal("player was not seated when their ticket ran out")
+	}
+	defer roomPkg.EndRoom(room.Id)
+	filler.mu.Lock()
+	defer filler.mu.Unlock()
+	if filler.seats[room.Id] != DefaultMatchSize-1 {
+		t.Fatalf("bot seats = %d, want %d", filler.seats[room.Id], DefaultMatchSize-1)
+	}
+	if room.Preferences.Difficulty != types.Moderate {
+		t.Fatalf("room difficulty = %q, want %q", room.Preferences.Difficulty, types.Moderate)
+	}
+}
+
+func TestMergePreferences(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b types.Preferences
+		want types.Preferences
+		ok   bool
+	}{
+		{"both empty", types.Preferences{}, types.Preferences{}, types.Preferences{}, true},
+		{"wildcard", types.Preferences{Difficulty: types.Easy}, types.Preferences{LetterSet: types.LetterSetHard}, types.Preferences{Difficulty: types.Easy, LetterSet: types.LetterSetHard}, true},
+		{"same", types.Preferences{Difficulty: types.Hard}, types.Preferences{Difficulty: types.Hard}, types.Preferences{Difficulty: types.Hard}, true},
+		{"conflicting difficulty", types.Preferences{Difficulty: types.Easy}, types.Preferences{Difficulty: types.Hard}, types.Preferences{}, false},
+		{"conflicting language", types.Preferences{Language: types.LanguageEnglish}, types.Preferences{Language: types.LanguageMaori}, types.Preferences{}, false},
+		{"conflicting letter set", types.Preferences{LetterSet: types.LetterSetEasy}, types.Preferences{LetterSet: types.LetterSetHard}, types.Preferences{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mergePreferences(tt.a, tt.b)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Fatalf("mergePreferences = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "quik"
+
+// Registry holds every metric served on /metrics, along with the Go runtime
+// and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	SocketsActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sockets_active",
+		Help:      "Clients connected to this server, by transport.",
+	}, []string{"transport"})
+
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "socket_events_received_total",
+		Help:      "Socket events received from clients, by event type.",
+	}, []string{"event"})
+
+	EventsEmitted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "socket_events_emitted_total",
+		Help:      "Socket events emitted to rooms and clients, by event type.",
+	}, []string{"event"})
+
+	HandlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "socket_handler_duration_seconds",
+		Help:      "Time spent handling socket events, by event type. Handlers that run a turn timer last until it stops.",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30, 60},
+	}, []string{"event"})
+
+	TimerExpiries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "timer_expiries_total",
+		Help:      "Turn timers that ran out.",
+	})
+
+	PlayersEliminated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "players_eliminated_total",
+		Help:      "Players eliminated from a round.",
+	})
+
+	RoundsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rounds_completed_total",
+		Help:      "Rounds played to the end.",
+	})
+
+	GamesCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_completed_total",
+		Help:      "Games won by a player.",
+	})
+
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route and status code.",
+	}, []string{"route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests, socket events and room creations refused for exceeding a limit, by kind and route or event type.",
+	}, []string{"kind", "name"})
+
+	RateLimitDisconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_disconnects_total",
+		Help:      "Clients disconnected for repeatedly exceeding the socket event limits.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		SocketsActive,
+		EventsReceived,
+		EventsEmitted,
+		HandlerDuration,
+		TimerExpiries,
+		PlayersEliminated,
+		RoundsCompleted,
+		GamesCompleted,
+		HTTPRequests,
+		HTTPDuration,
+		RateLimited,
+		RateLimitDisconnects,
+	)
+}
+
+// RegisterGaugeFunc adds a gauge whose value is read from f when metrics
+// are collected, for values a package already tracks such as its rooms.
+func RegisterGaugeFunc(name, help string, f func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, f))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware counts and times the requests served by router, labelled by
+// the route pattern that matched them rather than the raw path.
+func Middleware(router *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := router.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		start := time.Now()
+		rec := &logging.StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		HTTPRequests.WithLabelValues(route, strconv.Itoa(rec.Status)).Inc()
+		HTTPDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,152 @@
+// Package names cleans up the names players choose before they are shown
+// to anyone else.
+package names
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the most characters a name may have.
+const MaxLength = 32
+
+var (
+	ErrInvalidName    = errors.New("invalid name")
+	ErrNameNotAllowed = errors.New("name is not allowed")
+)
+
+// Filter decides whether a normalized name may be used.
+type Filter interface {
+	Allowed(name string) bool
+}
+
+// Filters allows the names every one of its filters allows.
+type Filters []Filter
+
+func (f Filters) Allowed(name string) bool {
+	for _, filter := range f {
+		if !filter.Allowed(name) {
+			return false
+		}
+	}
+	return true
+}
+
+var filter Filter
+
+// SetFilter sets the filter Clean checks names against, nil for none.
+func SetFilter(f Filter) {
+	filter = f
+}
+
+// Normalize puts a name in Unicode NFC form, trims it and collapses runs
+// of whitespace, then checks its length.
+func Normalize(name string) (string, error) {
+	name = strings.Join(strings.Fields(norm.NFC.String(name)), " ")
+	for _, r := range name {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return "", fmt.Errorf("%w: name must not contain control characters", ErrInvalidName)
+		}
+	}
+	switch n := utf8.RuneCountInString(name); {
+	case n == 0:
+		return "", fmt.Errorf("%w: name is required", ErrInvalidName)
+	case n > MaxLength:
+		return "", fmt.Errorf("%w: name must not be longer than %d characters", ErrInvalidName, MaxLength)
+	}
+	return name, nil
+}
+
+// Clean normalizes a name and checks it against the filter.
+func Clean(name string) (string, error) {
+	name, err := Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	if filter != nil && !filter.Allowed(name) {
+		return "", fmt.Errorf("%w: %q", ErrNameNotAllowed, name)
+	}
+	return name, nil
+}
+
+// Unique returns name, or name with the lowest numbered suffix from 2 up
+// that is not taken, shortening it to fit MaxLength.
+func Unique(name string, taken func(name string) bool) string {
+	if !taken(name) {
+		return name
+	}
+	base := []rune(name)
+	for n := 2; ; n++ {
+		suffix := " " + strconv.Itoa(n)
+		candidate := string(base[:min(len(base), MaxLength-len(suffix))]) + suffix
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+//go:embed blocklist.txt
+var defaultBlocklist string
+
+// Blocklist blocks names containing any of its words.
+type Blocklist struct {
+	words map[string]bool
+}
+
+func NewBlocklist(words []string) *Blocklist {
+	b := &Blocklist{words: make(map[string]bool)}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" && !strings.HasPrefix(word, "#") {
+			b.words[word] = true
+		}
+	}
+	return b
+}
+
+// DefaultBlocklist is the built-in list of profanity.
+func DefaultBlocklist() *Blocklist {
+	return NewBlocklist(strings.Split(defaultBlocklist, "\n"))
+}
+
+// LoadBlocklist reads a word list with one word per line. Lines starting
+// with # are ignored.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	words := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		words = append(words, scanner.Text())
+	}
+	return NewBlocklist(words), scanner.Err()
+}
+
+// substitutions undoes the look-alike characters used to slip words past
+// filters.
+var substitutions = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// Allowed checks each word of the name, and the name with its separators
+// removed so that spaced out words are caught too. Words are not searched
+// for inside longer words, which would block innocent names.
+func (b *Blocklist) Allowed(name string) bool {
+	name = substitutions.Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, word := range append(words, strings.Join(words, "")) {
+		if b.words[word] {
+			return false
+		}
+	}
+	return true
+}
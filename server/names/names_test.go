@@ -0,0 +1,91 @@
+package names
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{"trims", "  Kiri  ", "Kiri", nil},
+		{"collapses whitespace", "Te \t Ao  Mārama", "Te Ao Mārama", nil},
+		{"composes to NFC", "Ma\u0304ui", "M\u0101ui", nil},
+		{"counts characters not bytes", strings.Repeat("ā", MaxLength), strings.Repeat("ā", MaxLength), nil},
+		{"empty", "   ", "", ErrInvalidName},
+		{"too long", strings.Repeat("a", MaxLength+1), "", ErrInvalidName},
+		{"huge", strings.Repeat("a", 10_000), "", ErrInvalidName},
+		{"control character", "Ki\x00ri", "", ErrInvalidName},
+		{"format character", "Ki\u200bri", "", ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	taken := map[string]bool{"Kiri": true, "Kiri 2": true}
+	isTaken := func(name string) bool { return taken[name] }
+	if got := Unique("Mere", isTaken); got != "Mere" {
+		t.Fatalf("name = %q, want it unchanged", got)
+	}
+	if got := Unique("Kiri", isTaken); got != "Kiri 3" {
+		t.Fatalf("name = %q, want Kiri 3", got)
+	}
+	long := strings.Repeat("ā", MaxLength)
+	taken[long] = true
+	if got := Unique(long, isTaken); got != strings.Repeat("ā", MaxLength-2)+" 2" {
+		t.Fatalf("name = %q, want it shortened to fit the suffix", got)
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	blocklist := DefaultBlocklist()
+	for _, name := range []string{"shit", "Big Shit", "sh1t", "$h!t", "s h i t", "the-wanker"} {
+		if blocklist.Allowed(name) {
+			t.Errorf("%q was allowed", name)
+		}
+	}
+	for _, name := range []string{"Kiri", "Scunthorpe", "Dickens", "Cockburn", "Assassin"} {
+		if !blocklist.Allowed(name) {
+			t.Errorf("%q was blocked", name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	os.WriteFile(path, []byte("# local words\nGrinch\n\n"), 0o644)
+	local, err := LoadBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := Filters{blocklist, local}
+	if filters.Allowed("The Grinch") || !filters.Allowed("Kiri") {
+		t.Fatal("want the local word list blocked alongside the default one")
+	}
+}
+
+func TestClean(t *testing.T) {
+	SetFilter(DefaultBlocklist())
+	defer SetFilter(nil)
+	if _, err := Clean("  fuck  "); !errors.Is(err, ErrNameNotAllowed) {
+		t.Fatalf("err = %v, want ErrNameNotAllowed", err)
+	}
+	if got, err := Clean("  Kiri "); err != nil || got != "Kiri" {
+		t.Fatalf("name = %q, %v, want Kiri", got, err)
+	}
+}
@@ -0,0 +1,124 @@
+package origins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/campbell-rehu/quik-be/envelope"
+)
+
+// ErrorCode is the API error code for requests refused for their origin.
+const ErrorCode = "origin_not_allowed"
+
+var ErrOriginNotAllowed = errors.New("origin not allowed")
+
+// Checker decides which web origins may use the server. An allowed origin
+// is either an exact origin such as https://quik.example, * for every
+// origin, or an origin with one * standing in for part of it, such as
+// https://*.quik.example.
+type Checker struct {
+	any       bool
+	exact     map[string]bool
+	wildcards [][2]string
+}
+
+func NewChecker(allowed []string) *Checker {
+	c := &Checker{exact: make(map[string]bool)}
+	for _, origin := range allowed {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch prefix, suffix, wildcard := strings.Cut(origin, "*"); {
+		case origin == "*":
+			c.any = true
+		case wildcard:
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		case origin != "":
+			c.exact[origin] = true
+		}
+	}
+	return c
+}
+
+// AllowsAny reports whether every origin is allowed.
+func (c *Checker) AllowsAny() bool {
+	return c.any
+}
+
+// Allowed reports whether an origin is allowed.
+func (c *Checker) Allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if c.any || c.exact[origin] {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(origin) < len(w[0])+len(w[1]) || !strings.HasPrefix(origin, w[0]) || !strings.HasSuffix(origin, w[1]) {
+			continue
+		}
+		if !strings.Contains(origin[len(w[0]):len(origin)-len(w[1])], "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check refuses a request from a web page on an origin that is not
+// allowed. Requests without an Origin header do not come from a browser
+// running another site's scripts, and pages may always reach the server
+// they were served from.
+func (c *Checker) Check(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || c.Allowed(origin) || sameHost(origin, r) {
+		return nil
+	}
+	return fmt.Errorf("%w: origin=%s", ErrOriginNotAllowed, origin)
+}
+
+// CSRF refuses state changing requests made by pages on origins that are
+// not allowed, so other sites cannot submit forms or scripts to the API on
+// a player's behalf. The origin is taken from the Origin header, or the
+// Referer header for browsers that leave Origin out.
+func (c *Checker) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if safeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.Header.Get("Sec-Fetch-Site") {
+		case "same-origin", "none":
+			next.ServeHTTP(w, r)
+			return
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			origin = refererOrigin(r.Header.Get("Referer"))
+		}
+		if origin == "" || c.Allowed(origin) || sameHost(origin, r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		envelope.WriteError(w, http.StatusForbidden, ErrorCode, fmt.Errorf("%w: origin=%s", ErrOriginNotAllowed, origin))
+	})
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func sameHost(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+func refererOrigin(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
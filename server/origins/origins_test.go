@@ -0,0 +1,86 @@
+package origins
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	c := NewChecker([]string{"https://quik.example", "https://*.preview.example"})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://quik.example", true},
+		{"HTTPS://Quik.Example", true},
+		{"http://quik.example", false},
+		{"https://evil.example", false},
+		{"https://pr-1.preview.example", true},
+		{"https://preview.example", false},
+		{"https://evil.example/.preview.example", false},
+	}
+	for _, tt := range tests {
+		if got := c.Allowed(tt.origin); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+	if c.AllowsAny() {
+		t.Fatal("want only the listed origins allowed")
+	}
+	if any := NewChecker([]string{"*"}); !any.AllowsAny() || !any.Allowed("https://evil.example") {
+		t.Fatal("want * to allow every origin")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := NewChecker([]string{"https://quik.example"})
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://api.quik.example/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+	for _, origin := range []string{"", "https://quik.example", "http://api.quik.example"} {
+		if err := c.Check(request(origin)); err != nil {
+			t.Errorf("Check(%q) = %v, want allowed", origin, err)
+		}
+	}
+	if err := c.Check(request("https://evil.example")); !errors.Is(err, ErrOriginNotAllowed) {
+		t.Fatalf("err = %v, want ErrOriginNotAllowed", err)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	handler := NewChecker([]string{"https://quik.example"}).CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{"allowed origin", http.MethodPost, map[string]string{"Origin": "https://quik.example"}, http.StatusOK},
+		{"other origin", http.MethodPost, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"other origin deleting", http.MethodDelete, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"other referer", http.MethodPost, map[string]string{"Referer": "https://evil.example/game"}, http.StatusForbidden},
+		{"allowed referer", http.MethodPost, map[string]string{"Referer": "https://quik.example/room/1"}, http.StatusOK},
+		{"same site fetch", http.MethodPost, map[string]string{"Origin": "https://evil.example", "Sec-Fetch-Site": "same-origin"}, http.StatusOK},
+		{"not a browser", http.MethodPost, nil, http.StatusOK},
+		{"reading", http.MethodGet, map[string]string{"Origin": "https://evil.example"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://api.quik.example/room", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body=%s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// violationWindow is how long refused events count towards disconnecting
+// a client.
+const violationWindow = time.Minute
+
+// Events limits the socket events each client sends, separately for each
+// event type, and picks out clients that keep sending after being refused.
+type Events struct {
+	Limiter *Limiter
+	// Rate applies to every event type without its own in Rates.
+	Rate  Rate
+	Rates map[string]Rate
+	// MaxViolations is how many refused events a client may send within a
+	// minute before it is disconnected, 0 to never disconnect.
+	MaxViolations int
+
+	mu         sync.Mutex
+	violations map[string]*violations
+}
+
+type violations struct {
+	count int
+	since time.Time
+}
+
+// Allow reports whether a client may send an event, and whether it has
+// been refused so often that it should be disconnected.
+func (e *Events) Allow(clientId, eventType string) (allowed, disconnect bool) {
+	rate, ok := e.Rates[eventType]
+	if !ok {
+		rate = e.Rate
+	}
+	if ok, _ := e.Limiter.Allow(clientId+" "+eventType, rate); ok {
+		return true, false
+	}
+	if e.MaxViolations <= 0 {
+		return false, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.violations == nil {
+		e.violations = make(map[string]*violations)
+	}
+	now := e.Limiter.now()
+	v, ok := e.violations[clientId]
+	if !ok || now.Sub(v.since) > violationWindow {
+		v = &violations{since: now}
+		e.violations[clientId] = v
+	}
+	v.count++
+	return false, v.count > e.MaxViolations
+}
+
+// Forget drops what is known about a client once it disconnects.
+func (e *Events) Forget(clientId string) {
+	e.Limiter.Forget(clientId + " ")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.violations, clientId)
+}
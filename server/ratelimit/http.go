@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/envelope"
+	"github.com/campbell-rehu/quik-be/metrics"
+)
+
+// ErrorCode is the API error code of a refused request.
+const ErrorCode = "rate_limited"
+
+// HTTP limits the requests each client address makes to each route.
+type HTTP struct {
+	Limiter *Limiter
+	Rate    Rate
+	// TrustProxy takes the client address from X-Forwarded-For, which is
+	// only safe behind a proxy that sets it.
+	TrustProxy bool
+	// Exempt lists route patterns that are never limited, such as health
+	// checks.
+	Exempt []string
+}
+
+// Middleware refuses requests over the limit with 429 Too Many Requests,
+// keying buckets by the route pattern that matched rather than the raw
+// path.
+func (h *HTTP) Middleware(router *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := router.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		if slices.Contains(h.Exempt, route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		addr := ClientAddr(r, h.TrustProxy)
+		if ok, wait := h.Limiter.Allow(addr+" "+route, h.Rate); !ok {
+			metrics.RateLimited.WithLabelValues("http", route).Inc()
+			slog.WarnContext(r.Context(), "rate limited request", "route", route, "remote_address", addr)
+			writeTooManyRequests(w, fmt.Errorf("too many requests, retry in %s", wait.Round(100*time.Millisecond)), wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientAddr is the IP address a request came from.
+func ClientAddr(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeTooManyRequests writes a 429 response in the API's error envelope,
+// telling the client when to retry.
+func writeTooManyRequests(w http.ResponseWriter, err error, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds())))))
+	envelope.WriteError(w, http.StatusTooManyRequests, ErrorCode, err)
+}
@@ -0,0 +1,163 @@
+// Package ratelimit keeps clients from flooding the server, with token
+// buckets per client and caps on the rooms one address can hold.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/metrics"
+)
+
+var ErrRoomQuota = errors.New("too many rooms for this address")
+
+// Rate is a token bucket's refill rate and size. A zero PerSecond means no
+// limit.
+type Rate struct {
+	PerSecond float64 `yaml:"perSecond" toml:"perSecond"`
+	Burst     int     `yaml:"burst" toml:"burst"`
+}
+
+func (r Rate) unlimited() bool {
+	return r.PerSecond <= 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// idleAfter is how long a bucket goes untouched before it is dropped. A
+// bucket refills long before then, so dropping it changes nothing.
+const idleAfter = 10 * time.Minute
+
+// Limiter holds a token bucket per key.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow takes a token from key's bucket, reporting whether there was one
+// and, if not, how long until there will be.
+func (l *Limiter) Allow(key string, rate Rate) (bool, time.Duration) {
+	if rate.unlimited() {
+		return true, 0
+	}
+	burst := float64(max(rate.Burst, 1))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate.PerSecond)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate.PerSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Forget drops the buckets for keys with the prefix, e.g. once a client
+// disconnects.
+func (l *Limiter) Forget(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range l.buckets {
+		if strings.HasPrefix(key, prefix) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleAfter {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleAfter {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// addrKind labels refused room creations, which are counted without the
+// address to keep the metric's cardinality down.
+const addrKind = "per-address"
+
+// RoomQuota caps the rooms created from one address that are open at
+// once. A zero max means no cap.
+type RoomQuota struct {
+	mu     sync.Mutex
+	max    int
+	counts map[string]int
+	owners map[string]string
+}
+
+func NewRoomQuota(max int) *RoomQuota {
+	return &RoomQuota{max: max, counts: make(map[string]int), owners: make(map[string]string)}
+}
+
+// Reserve claims a room for addr, failing once it holds the most it may.
+// The claim must be bound to the room once it is created, or cancelled if
+// it is not.
+func (q *RoomQuota) Reserve(addr string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.max > 0 && q.counts[addr] >= q.max {
+		metrics.RateLimited.WithLabelValues("rooms", addrKind).Inc()
+		return fmt.Errorf("%w: at most %d open at once", ErrRoomQuota, q.max)
+	}
+	q.counts[addr]++
+	return nil
+}
+
+func (q *RoomQuota) Cancel(addr string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.release(addr)
+}
+
+// Bind ties a reserved claim to the room created with it, so that it is
+// given back when the room is released.
+func (q *RoomQuota) Bind(addr, roomId string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.owners[roomId] = addr
+}
+
+// Release gives back the claim on a room that has been removed.
+func (q *RoomQuota) Release(roomId string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	addr, ok := q.owners[roomId]
+	if !ok {
+		return
+	}
+	delete(q.owners, roomId)
+	q.release(addr)
+}
+
+func (q *RoomQuota) release(addr string) {
+	if q.counts[addr] <= 1 {
+		delete(q.counts, addr)
+		return
+	}
+	q.counts[addr]--
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// clock is a fake time source for a limiter.
+type clock struct{ now time.Time }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter() (*Limiter, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter()
+	l.now = func() time.Time { return c.now }
+	return l, c
+}
+
+func TestLimiter(t *testing.T) {
+	l, c := newTestLimiter()
+	rate := Rate{PerSecond: 2, Burst: 3}
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a", rate); !ok {
+			t.Fatalf("request %d refused within the burst", i+1)
+		}
+	}
+	ok, wait := l.Allow("a", rate)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("allow = %v, wait = %v, want refused for 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("b", rate); !ok {
+		t.Fatal("want keys limited separately")
+	}
+	c.advance(500 * time.Millisecond)
+	if ok, _ := l.Allow("a", rate); !ok {
+		t.Fatal("want a token after refilling")
+	}
+
+	if ok, _ := l.Allow("a", Rate{}); !ok {
+		t.Fatal("want no limit without a rate")
+	}
+
+	l.Forget("a")
+	if _, ok := l.buckets["a"]; ok {
+		t.Fatal("want the bucket forgotten")
+	}
+	c.advance(2 * idleAfter)
+	l.Allow("c", rate)
+	if _, ok := l.buckets["b"]; ok {
+		t.Fatal("want idle buckets swept")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	l, c := newTestLimiter()
+	events := &Events{
+		Limiter:       l,
+		Rate:          Rate{PerSecond: 1, Burst: 1},
+		Rates:         map[string]Rate{"select-letter": {PerSecond: 1, Burst: 2}},
+		MaxViolations: 2,
+	}
+	if ok, _ := events.Allow("c1", "end-turn"); !ok {
+		t.Fatal("want the first event allowed")
+	}
+	if ok, _ := events.Allow("c1", "select-letter"); !ok {
+		t.Fatal("want event types limited separately")
+	}
+	if ok, _ := events.Allow("c1", "select-letter"); !ok {
+		t.Fatal("want the event type's own burst")
+	}
+	for i, want := range []bool{false, false, true} {
+		ok, disconnect := events.Allow("c1", "end-turn")
+		if ok || disconnect != want {
+			t.Fatalf("violation %d: allow = %v, disconnect = %v, want disconnect %v", i+1, ok, disconnect, want)
+		}
+	}
+
+	c.advance(violationWindow + time.Second)
+	if ok, disconnect := events.Allow("c2", "end-turn"); !ok || disconnect {
+		t.Fatal("want other clients unaffected")
+	}
+	events.Allow("c1", "end-turn")
+	if _, disconnect := events.Allow("c1", "end-turn"); disconnect {
+		t.Fatal("want violations to be forgotten after a minute")
+	}
+
+	events.Forget("c1")
+	if _, ok := events.violations["c1"]; ok {
+		t.Fatal("want the client's violations forgotten")
+	}
+}
+
+func TestRoomQuota(t *testing.T) {
+	q := NewRoomQuota(2)
+	for _, roomId := range []string{"r1", "r2"} {
+		if err := q.Reserve("1.2.3.4"); err != nil {
+			t.Fatal(err)
+		}
+		q.Bind("1.2.3.4", roomId)
+	}
+	if err := q.Reserve("1.2.3.4"); !errors.Is(err, ErrRoomQuota) {
+		t.Fatalf("err = %v, want ErrRoomQuota", err)
+	}
+	if err := q.Reserve("5.6.7.8"); err != nil {
+		t.Fatalf("want addresses capped separately, got %v", err)
+	}
+	q.Cancel("5.6.7.8")
+
+	q.Release("r1")
+	if err := q.Reserve("1.2.3.4"); err != nil {
+		t.Fatalf("want a room freed by its release, got %v", err)
+	}
+	if len(q.counts) != 1 || q.counts["1.2.3.4"] != 2 {
+		t.Fatalf("counts = %v, want only 1.2.3.4 holding two rooms", q.counts)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	router := http.NewServeMux()
+	router.HandleFunc("POST /room", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	limits := &HTTP{Limiter: NewLimiter(), Rate: Rate{PerSecond: 1, Burst: 1}, Exempt: []string{"GET /healthz"}}
+	handler := limits.Middleware(router, router)
+
+	do := func(method, target, addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.RemoteAddr = addr + ":1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+	if rec := do(http.MethodPost, "/room", "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want the first request allowed", rec.Code)
+	}
+	rec := do(http.MethodPost, "/room", "192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("status = %d, Retry-After = %q, want 429 retrying in a second", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var envelope struct {
+		Error struct{ Code string } `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil || envelope.Error.Code != ErrorCode {
+		t.Fatalf("body = %s, want the %s error code", rec.Body.String(), ErrorCode)
+	}
+	if rec := do(http.MethodPost, "/room", "192.0.2.2"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want addresses limited separately", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := do(http.MethodGet, "/healthz", "192.0.2.1"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want exempt routes unlimited", rec.Code)
+		}
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	if got := ClientAddr(r, false); got != "192.0.2.1" {
+		t.Fatalf("addr = %q, want the remote address when proxies are not trusted", got)
+	}
+	if got := ClientAddr(r, true); got != "203.0.113.9" {
+		t.Fatalf("addr = %q, want the first forwarded address", got)
+	}
+}
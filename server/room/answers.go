@@ -0,0 +1,117 @@
+package room
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/campbell-rehu/quik-be/words"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxAnswerLength caps the characters in a typed answer.
+const MaxAnswerLength = 64
+
+var (
+	ErrInvalidAnswer = errors.New("invalid answer")
+	ErrAnswerUsed    = errors.New("answer already used this round")
+)
+
+var dictionary *words.Dictionary
+
+// SetDictionary sets the dictionary typed answers are looked up in, nil
+// for none.
+func SetDictionary(d *words.Dictionary) {
+	dictionary = d
+}
+
+// SetTextMode turns typed answers on or off. It can only be changed while
+// the room is waiting for players.
+func (r *Room) SetTextMode(on bool) error {
+	if r.locked {
+		return fmt.Errorf("%w: id=%s", ErrRoomLocked, r.Id)
+	}
+	r.apply(EventTextModeSet, "", &EventData{TextMode: on})
+	return nil
+}
+
+func (r *Room) IsInTextMode() bool {
+	return r.TextMode
+}
+
+// SubmitAnswer checks the answer a player gave with the letter they chose
+// and keeps it for the round, returning it tidied up. The answer must start
+// with the letter, and neither may have been used already this round. With
+// a dictionary set, an answer is spelled as the word it matches, and one
+// that matches no word in the round's category is rejected or marked
+// unknown as the dictionary's strictness says. A rejected answer leaves the
+// player's turn running; see AnswerRejected.
+func (r *Room) SubmitAnswer(playerId, letter, answer string) (string, error) {
+	answer, unknown, err := r.checkAnswer(letter, answer)
+	if err != nil {
+		r.answerRejected = true
+		r.logger().Info("answer rejected", logging.KeyPlayerId, playerId, logging.Err(err))
+		return "", err
+	}
+	if unknown {
+		r.logger().Info("answer is not in the dictionary", logging.KeyPlayerId, playerId, "answer", answer)
+	}
+	r.answerRejected = false
+	r.apply(EventAnswerGiven, playerId, &EventData{Letter: letter, Answer: answer, Unknown: unknown})
+	r.answeredAt = time.Now()
+	return answer, nil
+}
+
+// AnswerRejected reports whether the current player's last answer was
+// rejected. Their turn carries on until they answer again or run out of
+// time, so requests to restart the timer are ignored meanwhile.
+func (r *Room) AnswerRejected() bool {
+	return r.answerRejected
+}
+
+// checkAnswer tidies up an answer and checks it, reporting whether the
+// dictionary did not know it.
+func (r *Room) checkAnswer(letter, answer string) (string, bool, error) {
+	answer = strings.Join(strings.Fields(norm.NFC.String(answer)), " ")
+	switch {
+	case answer == "":
+		return "", false, fmt.Errorf("%w: an answer is required", ErrInvalidAnswer)
+	case utf8.RuneCountInString(answer) > MaxAnswerLength:
+		return "", false, fmt.Errorf("%w: answers are at most %d characters", ErrInvalidAnswer, MaxAnswerLength)
+	case letter == "":
+		return "", false, fmt.Errorf("%w: a letter is required", ErrInvalidAnswer)
+	case !r.HasLetter(letter):
+		return "", false, fmt.Errorf("%w: %s is not in the room's letter set", ErrInvalidAnswer, letter)
+	case !strings.EqualFold(words.Initial(answer), letter):
+		return "", false, fmt.Errorf("%w: %q does not start with %s", ErrInvalidAnswer, answer, letter)
+	}
+	if selectable, ok := r.UsedLetters[letter]; ok && !selectable {
+		return "", false, fmt.Errorf("%w: letter=%s", ErrAnswerUsed, letter)
+	}
+	unknown := false
+	if d := dictionary; d != nil && d.Strictness() != words.StrictnessOff {
+		match, err := d.Check(r.category, letter, answer)
+		switch {
+		case err == nil:
+			answer = match
+		case d.Strictness() == words.StrictnessReject:
+			return "", false, fmt.Errorf("%w: %w", ErrInvalidAnswer, err)
+		default:
+			unknown = true
+		}
+	}
+	for _, given := range r.Answers {
+		if strings.EqualFold(given.Answer, answer) {
+			return "", false, fmt.Errorf("%w: answer=%s", ErrAnswerUsed, answer)
+		}
+	}
+	return answer, unknown, nil
+}
+
+func (r *Room) resetAnswers() {
+	r.Answers = []types.Answer{}
+}
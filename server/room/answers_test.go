@@ -0,0 +1,135 @@
+package room
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/campbell-rehu/quik-be/words"
+)
+
+func TestSubmitAnswer(t *testing.T) {
+	tests := []struct {
+		name        string
+		letterSet   string
+		usedLetters map[string]bool
+		answers     []string
+		letter      string
+		answer      string
+		want        string
+		err         error
+	}{
+		{name: "accepted", letter: "A", answer: "  aardvark ", want: "aardvark"},
+		{name: "spaces tidied", letter: "S", answer: "sea   lion", want: "sea lion"},
+		{name: "accented initial", letter: "O", answer: "Ōtaki", want: "Ōtaki"},
+		{name: "wrong initial letter", letter: "A", answer: "Zebra", err: ErrInvalidAnswer},
+		{name: "empty answer", letter: "A", answer: "   ", err: ErrInvalidAnswer},
+		{name: "no letter", letter: "", answer: "Aardvark", err: ErrInvalidAnswer},
+		{name: "too long", letter: "A", answer: "A" + strings.Repeat("a", MaxAnswerLength), err: ErrInvalidAnswer},
+		{name: "longest allowed", letter: "A", answer: "A" + strings.Repeat("a", MaxAnswerLength-1), want: "A" + strings.Repeat("a", MaxAnswerLength-1)},
+		{name: "letter not in letter set", letterSet: types.LetterSetEasy, letter: "Q", answer: "Quail", err: ErrInvalidAnswer},
+		{name: "used letter", usedLetters: map[string]bool{"A": false}, letter: "A", answer: "Aardvark", err: ErrAnswerUsed},
+		{name: "selected letter", usedLetters: map[string]bool{"A": true}, letter: "A", answer: "Aardvark", want: "Aardvark"},
+		{name: "duplicate answer", answers: []string{"Bear"}, letter: "B", answer: "Bear", err: ErrAnswerUsed},
+		{name: "duplicate answer in another case", answers: []string{"Bear"}, letter: "B", answer: "bEAR", err: ErrAnswerUsed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRoom()
+			r.Preferences.LetterSet = tt.letterSet
+			for letter, selectable := range tt.usedLetters {
+				r.UsedLetters[letter] = selectable
+			}
+			for _, answer := range tt.answers {
+				r.Answers = append(r.Answers, types.Answer{PlayerId: "p0", Answer: answer})
+			}
+
+			got, err := r.SubmitAnswer("p1", tt.letter, tt.answer)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				if !r.AnswerRejected() {
+					t.Fatal("the rejected answer was not recorded")
+				}
+				if len(r.Answers) != len(tt.answers) {
+					t.Fatalf("answers = %v, want the rejected answer left out", r.Answers)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("SubmitAnswer = %q, %v, want %q", got, err, tt.want)
+			}
+			if r.AnswerRejected() {
+				t.Fatal("an accepted answer was recorded as rejected")
+			}
+			if last := r.Answers[len(r.Answers)-1]; last.PlayerId != "p1" || last.Answer != tt.want || last.Letter != tt.letter {
+				t.Fatalf("last answer = %+v", last)
+			}
+		})
+	}
+}
+
+func TestAnswerRejectedUntilNextTurn(t *testing.T) {
+	r := NewRoom()
+	r.AddPlayerToRoom("p1", "Aroha")
+	r.AddPlayerToRoom("p2", "Mere")
+	defer func() {
+		for _, playerId := range []string{"p1", "p2"} {
+			RemovePlayerIdToRoomIdMapping(playerId)
+		}
+	}()
+
+	if _, err := r.SubmitAnswer("p1", "A", "Zebra"); err == nil {
+		t.Fatal("accepted an answer with the wrong initial")
+	}
+	if !r.AnswerRejected() {
+		t.Fatal("the rejected answer was not recorded")
+	}
+	if _, err := r.SubmitAnswer("p1", "Z", "Zebra"); err != nil || r.AnswerRejected() {
+		t.Fatalf("answering again = %v, rejected = %v, want the turn to carry on", err, r.AnswerRejected())
+	}
+
+	r.SubmitAnswer("p1", "A", "Zebra")
+	r.SetNextPlayerIndex()
+	if r.AnswerRejected() {
+		t.Fatal("the rejection carried over to the next player's turn")
+	}
+}
+
+func TestSubmitAnswerWithDictionary(t *testing.T) {
+	list := words.New()
+	list.Add("Fish", "Snapper")
+	defer SetDictionary(nil)
+	tests := []struct {
+		strictness words.Strictness
+		answer     string
+		want       string
+		unknown    bool
+		err        error
+	}{
+		{words.StrictnessOff, "Salmon", "Salmon", false, nil},
+		{words.StrictnessWarn, "snaper", "Snapper", false, nil},
+		{words.StrictnessWarn, "Salmon", "Salmon", true, nil},
+		{words.StrictnessReject, "snaper", "Snapper", false, nil},
+		{words.StrictnessReject, "Salmon", "", false, words.ErrNotInDictionary},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strictness)+" "+tt.answer, func(t *testing.T) {
+			SetDictionary(words.NewDictionary(list, tt.strictness))
+			r := NewRoom()
+			r.category = "Fish"
+			got, err := r.SubmitAnswer("p1", "S", tt.answer)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) || !errors.Is(err, ErrInvalidAnswer) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want || r.Answers[0].Unknown != tt.unknown {
+				t.Fatalf("SubmitAnswer = %q, %v, unknown = %v, want %q, unknown = %v", got, err, r.Answers[0].Unknown, tt.want, tt.unknown)
+			}
+		})
+	}
+}
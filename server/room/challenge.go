@@ -0,0 +1,201 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/metrics"
+	"github.com/campbell-rehu/quik-be/tracing"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+var (
+	ErrNothingToChallenge  = errors.New("no answer can be challenged")
+	ErrChallengeInProgress = errors.New("an answer is already being voted on")
+	ErrNoChallenge         = errors.New("no answer is being voted on")
+	ErrCannotChallenge     = errors.New("player cannot challenge the answer")
+	ErrCannotVote          = errors.New("player cannot vote on the answer")
+)
+
+// challenge is a vote on whether to keep the last answer given. The players
+// still in the round vote, apart from the player who gave the answer, and
+// the challenger is counted as voting to strike it. The answer is struck
+// only when more voters strike it than keep it, so a tie keeps it; in a
+// round of two the challenger decides alone.
+type challenge struct {
+	answer    types.Answer
+	voters    []string
+	votes     map[string]bool
+	timer     *time.Timer
+	emitEvent func(types.EventType, any)
+}
+
+func (c *challenge) count() (keep, strike int) {
+	for _, v := range c.votes {
+		if v {
+			keep++
+		} else {
+			strike++
+		}
+	}
+	return keep, strike
+}
+
+// decided reports whether the outcome can no longer change: everyone has
+// voted or one side has a majority of the voters.
+func (c *challenge) decided() bool {
+	keep, strike := c.count()
+	return len(c.votes) == len(c.voters) || keep*2 > len(c.voters) || strike*2 > len(c.voters)
+}
+
+func (c *challenge) canVote(playerId string) bool {
+	for _, voter := range c.voters {
+		if voter == playerId {
+			return true
+		}
+	}
+	return false
+}
+
+// ChallengeAnswer disputes the last answer given, which players may do for
+// settings.ChallengeWindow seconds after it is given. The turn timer is
+// paused while the remaining players vote, for at most
+// settings.VoteDuration seconds. emitEvent tells the room the vote has
+// started and its outcome, which is settled at once when the challenger is
+// the only voter.
+func (r *Room) ChallengeAnswer(challengerId string, emitEvent func(types.EventType, any)) (*types.ChallengeStartedPayload, error) {
+	c, err := r.newChallenge(challengerId, emitEvent)
+	if err != nil {
+		return nil, err
+	}
+	started := &types.ChallengeStartedPayload{
+		ChallengerId: challengerId,
+		Answer:       c.answer,
+		Voters:       c.voters,
+		VoteSeconds:  settings.VoteDuration,
+	}
+	emitEvent(types.EventTypeChallengeStarted, started)
+
+	r.challengeMu.Lock()
+	decided := c.decided()
+	r.challengeMu.Unlock()
+	if decided {
+		r.endChallenge(c)
+	}
+	return started, nil
+}
+
+// newChallenge checks the challenge can be made and opens the vote.
+func (r *Room) newChallenge(challengerId string, emitEvent func(types.EventType, any)) (*challenge, error) {
+	r.challengeMu.Lock()
+	defer r.challengeMu.Unlock()
+	if r.challenge != nil {
+		return nil, fmt.Errorf("%w: id=%s", ErrChallengeInProgress, r.Id)
+	}
+	window := time.Duration(settings.ChallengeWindow) * time.Second
+	// Answers are challenged during the next player's turn, whose timer is
+	// paused for the vote.
+	if !r.TextMode || len(r.Answers) == 0 || r.answeredAt.IsZero() || time.Since(r.answeredAt) > window || !r.timer.started {
+		return nil, fmt.Errorf("%w: id=%s", ErrNothingToChallenge, r.Id)
+	}
+	answer := r.Answers[len(r.Answers)-1]
+	challenger, ok := r.Players[challengerId]
+	if !ok || challenger.Eliminated || challengerId == answer.PlayerId {
+		return nil, fmt.Errorf("%w: id=%s", ErrCannotChallenge, challengerId)
+	}
+
+	c := &challenge{answer: answer, voters: []string{}, votes: make(map[string]bool), emitEvent: emitEvent}
+	for _, playerId := range r.playerOrder {
+		if player := r.Players[playerId]; player != nil && !player.Eliminated && playerId != answer.PlayerId {
+			c.voters = append(c.voters, playerId)
+		}
+	}
+	c.votes[challengerId] = false
+	// An answer is only challenged once.
+	r.answeredAt = time.Time{}
+	r.challenge = c
+	r.apply(EventAnswerChallenged, challengerId, &EventData{Letter: answer.Letter, Answer: answer.Answer})
+	r.PauseTimer()
+	r.traceTransition(context.Background(), spanAnswerChallenged, tracing.KeyPlayerId.String(challengerId))
+	r.logger().Info("answer challenged", "challenger_id", challengerId, "answer", answer.Answer)
+
+	voteDuration := time.Duration(settings.VoteDuration) * time.Second
+	c.timer = time.AfterFunc(voteDuration, func() { r.endChallenge(c) })
+	return c, nil
+}
+
+// ChallengeInProgress reports whether an answer is being voted on. Turns
+// wait until the vote ends.
+func (r *Room) ChallengeInProgress() bool {
+	r.challengeMu.Lock()
+	defer r.challengeMu.Unlock()
+	return r.challenge != nil
+}
+
+// Vote records a player's vote on the challenged answer. The vote ends as
+// soon as its outcome is decided.
+func (r *Room) Vote(playerId string, keep bool) error {
+	r.challengeMu.Lock()
+	c := r.challenge
+	if c == nil {
+		r.challengeMu.Unlock()
+		return fmt.Errorf("%w: id=%s", ErrNoChallenge, r.Id)
+	}
+	if _, voted := c.votes[playerId]; voted || !c.canVote(playerId) {
+		r.challengeMu.Unlock()
+		return fmt.Errorf("%w: id=%s", ErrCannotVote, playerId)
+	}
+	c.votes[playerId] = keep
+	r.apply(EventVoteCast, playerId, &EventData{Keep: keep})
+	decided := c.decided()
+	r.challengeMu.Unlock()
+
+	if decided {
+		r.endChallenge(c)
+	}
+	return nil
+}
+
+// endChallenge counts the votes. A struck answer eliminates the player who
+// gave it, which may end the round; otherwise the turn timer carries on.
+func (r *Room) endChallenge(c *challenge) {
+	r.challengeMu.Lock()
+	if r.challenge != c {
+		r.challengeMu.Unlock()
+		return
+	}
+	r.challenge = nil
+	c.timer.Stop()
+	r.challengeMu.Unlock()
+	if !HasRoom(r.Id) {
+		return
+	}
+
+	keep, strike := c.count()
+	struck := strike > keep
+	r.apply(EventChallengeEnded, c.answer.PlayerId, &EventData{Letter: c.answer.Letter, Answer: c.answer.Answer, Struck: struck})
+	r.logger().Info("challenge ended", "answer", c.answer.Answer, "keep", keep, "strike", strike)
+	answer := c.answer
+	answer.Struck = struck
+	c.emitEvent(types.EventTypeChallengeResolved, &types.ChallengeResolvedPayload{
+		Answer: answer,
+		Keep:   keep,
+		Strike: strike,
+		Struck: struck,
+	})
+
+	if player := r.Players[c.answer.PlayerId]; struck && player != nil && !player.Eliminated {
+		ctx := context.Background()
+		r.apply(EventPlayerEliminated, player.Id, nil)
+		metrics.PlayersEliminated.Inc()
+		r.traceTransition(ctx, spanPlayerEliminated, tracing.KeyPlayerId.String(player.Id))
+		c.emitEvent(types.EventTypePlayerEliminated, &types.PlayerEliminatedPayload{EliminatedPlayer: player})
+		// Ending the round stops the paused turn's timer.
+		if r.settleRound(ctx, c.emitEvent) {
+			return
+		}
+	}
+	r.ResumeTimer()
+}
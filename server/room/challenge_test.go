@@ -0,0 +1,207 @@
+package room
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+// roomEvents records the events a room emits.
+type roomEvents struct {
+	mu       sync.Mutex
+	events   map[types.EventType][]any
+	resolved chan *types.ChallengeResolvedPayload
+}
+
+func newRoomEvents() *roomEvents {
+	return &roomEvents{events: make(map[types.EventType][]any), resolved: make(chan *types.ChallengeResolvedPayload, 1)}
+}
+
+func (e *roomEvents) emit(eventType types.EventType, data any) {
+	e.mu.Lock()
+	e.events[eventType] = append(e.events[eventType], data)
+	e.mu.Unlock()
+	if resolved, ok := data.(*types.ChallengeResolvedPayload); ok {
+		e.resolved <- resolved
+	}
+}
+
+func (e *roomEvents) get(eventType types.EventType) []any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.events[eventType]
+}
+
+// startChallengeRound seats n typing players in a new room and starts a
+// turn timer in which the first player answers, so the answer can be
+// challenged during the second player's turn.
+func startChallengeRound(t *testing.T, n int) (*Room, []string) {
+	t.Helper()
+	r, err := AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { EndRoom(r.Id) })
+	r.TextMode = true
+	playerIds := []string{}
+	for i := 1; i <= n; i++ {
+		playerId := fmt.Sprintf("%s-p%d", r.Id, i)
+		if err := r.AddPlayerToRoom(playerId, "Player"); err != nil {
+			t.Fatal(err)
+		}
+		playerIds = append(playerIds, playerId)
+	}
+
+	ticking := make(chan struct{})
+	emitTick := func(tickChan chan int, doneCh chan bool) {
+		for {
+			select {
+			case <-doneCh:
+				return
+			case <-tickChan:
+				select {
+				case ticking <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+	go r.StartTimer(emitTick, func(types.EventType, any) {})
+	<-ticking
+
+	if _, err := r.SubmitAnswer(playerIds[0], "A", "Aardvark"); err != nil {
+		t.Fatal(err)
+	}
+	r.SetNextPlayerIndex()
+	return r, playerIds
+}
+
+func TestChallengeWindow(t *testing.T) {
+	r, playerIds := startChallengeRound(t, 3)
+	events := newRoomEvents()
+
+	if _, err := r.ChallengeAnswer(playerIds[0], events.emit); !errors.Is(err, ErrCannotChallenge) {
+		t.Fatalf("err = %v, want %v for challenging your own answer", err, ErrCannotChallenge)
+	}
+	r.answeredAt = time.Now().Add(-time.Duration(settings.ChallengeWindow+1) * time.Second)
+	if _, err := r.ChallengeAnswer(playerIds[1], events.emit); !errors.Is(err, ErrNothingToChallenge) {
+		t.Fatalf("err = %v, want %v once the window has passed", err, ErrNothingToChallenge)
+	}
+	if r.ChallengeInProgress() || r.TimerState().Paused {
+		t.Fatal("a challenge started after the window had passed")
+	}
+}
+
+func TestChallengeVote(t *testing.T) {
+	r, playerIds := startChallengeRound(t, 4)
+	answerer, challenger := playerIds[0], playerIds[2]
+	events := newRoomEvents()
+
+	started, err := r.ChallengeAnswer(challenger, events.emit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{playerIds[1], playerIds[2], playerIds[3]}; fmt.Sprint(started.Voters) != fmt.Sprint(want) {
+		t.Fatalf("voters = %v, want %v without the answerer", started.Voters, want)
+	}
+	if len(events.get(types.EventTypeChallengeStarted)) != 1 {
+		t.Fatal("the room was not told the vote started")
+	}
+	if !r.ChallengeInProgress() || !r.TimerState().Paused {
+		t.Fatal("the timer is not paused for the vote")
+	}
+	if _, err := r.ChallengeAnswer(playerIds[1], events.emit); !errors.Is(err, ErrChallengeInProgress) {
+		t.Fatalf("err = %v, want %v", err, ErrChallengeInProgress)
+	}
+	if err := r.Vote(challenger, true); !errors.Is(err, ErrCannotVote) {
+		t.Fatalf("err = %v, want the challenger unable to vote again", err)
+	}
+	if err := r.Vote(answerer, true); !errors.Is(err, ErrCannotVote) {
+		t.Fatalf("err = %v, want the answerer unable to vote", err)
+	}
+
+	// Two of the three voters striking the answer is a majority.
+	if err := r.Vote(playerIds[1], false); err != nil {
+		t.Fatal(err)
+	}
+	resolved := <-events.resolved
+	if !resolved.Struck || resolved.Strike != 2 || resolved.Keep != 0 {
+		t.Fatalf("resolved = %+v, want the answer struck 2-0", resolved)
+	}
+	if !r.Players[answerer].Eliminated || len(events.get(types.EventTypePlayerEliminated)) != 1 {
+		t.Fatal("the player who gave the struck answer is still in the round")
+	}
+	if r.ChallengeInProgress() || r.TimerState().Paused || !r.TimerState().Running {
+		t.Fatal("the turn timer did not resume after the vote")
+	}
+	if err := r.Vote(playerIds[3], true); !errors.Is(err, ErrNoChallenge) {
+		t.Fatalf("err = %v, want %v after the vote ended", err, ErrNoChallenge)
+	}
+	if _, err := r.ChallengeAnswer(playerIds[1], events.emit); !errors.Is(err, ErrNothingToChallenge) {
+		t.Fatalf("err = %v, want an answer to be challenged only once", err)
+	}
+}
+
+func TestChallengeVoteTimesOut(t *testing.T) {
+	defer func(voteDuration int) { settings.VoteDuration = voteDuration }(settings.VoteDuration)
+	settings.VoteDuration = 1
+	r, playerIds := startChallengeRound(t, 4)
+	events := newRoomEvents()
+
+	if _, err := r.ChallengeAnswer(playerIds[2], events.emit); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Vote(playerIds[3], true); err != nil {
+		t.Fatal(err)
+	}
+	if !r.ChallengeInProgress() {
+		t.Fatal("the vote ended before everyone voted")
+	}
+
+	select {
+	case resolved := <-events.resolved:
+		// The player who did not vote is not counted, and a tie keeps the
+		// answer.
+		if resolved.Struck || resolved.Keep != 1 || resolved.Strike != 1 {
+			t.Fatalf("resolved = %+v, want the answer kept on a 1-1 tie", resolved)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the vote did not time out")
+	}
+	if r.Players[playerIds[0]].Eliminated || r.ChallengeInProgress() || r.TimerState().Paused {
+		t.Fatal("the kept answer's turn did not carry on")
+	}
+}
+
+func TestChallengeStrikeEndsRound(t *testing.T) {
+	r, playerIds := startChallengeRound(t, 2)
+	events := newRoomEvents()
+
+	// With two players in the round the challenger is the only voter, so
+	// the vote is settled at once.
+	started, err := r.ChallengeAnswer(playerIds[1], events.emit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{playerIds[1]}; fmt.Sprint(started.Voters) != fmt.Sprint(want) {
+		t.Fatalf("voters = %v, want %v", started.Voters, want)
+	}
+	if r.ChallengeInProgress() {
+		t.Fatal("the vote is still open")
+	}
+	resolved := <-events.resolved
+	if !resolved.Struck || !resolved.Answer.Struck {
+		t.Fatalf("resolved = %+v, want the answer struck", resolved)
+	}
+	roundEnded := events.get(types.EventTypeRoundEnded)
+	if len(roundEnded) != 1 || roundEnded[0].(*types.RoundEndedPayload).WinningPlayer.Id != playerIds[1] {
+		t.Fatalf("round-ended = %v, want the challenger to win the round", roundEnded)
+	}
+	if r.TimerState().Running {
+		t.Fatal("the turn timer is still running after the round ended")
+	}
+}
@@ -0,0 +1,174 @@
+package room
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+var ErrNoEventLog = errors.New("no event log for room")
+
+// Kinds of log entries. Commands are what clients and operators asked a
+// room to do, events are the state changes that followed. Only events are
+// replayed.
+const (
+	EntryKindCommand = "command"
+	EntryKindEvent   = "event"
+)
+
+// LogEntry is one line of a room's event log.
+type LogEntry struct {
+	Seq      int             `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Kind     string          `json:"kind"`
+	Type     string          `json:"type"`
+	PlayerId string          `json:"playerId"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// eventLog keeps the entries of every room held by this server in memory
+// and, when dir is set, appends them to a JSON Lines file per room that
+// outlives the room.
+type eventLog struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string][]LogEntry
+}
+
+func newEventLog(dir string) *eventLog {
+	return &eventLog{dir: dir, entries: make(map[string][]LogEntry)}
+}
+
+var events = newEventLog("")
+
+// append numbers and timestamps an entry and adds it to the room's log,
+// returning the entry as logged.
+func (l *eventLog) append(roomId string, entry LogEntry) LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.Seq = len(l.entries[roomId]) + 1
+	entry.Time = time.Now().UTC()
+	l.entries[roomId] = append(l.entries[roomId], entry)
+	if l.dir == "" {
+		return entry
+	}
+	if err := l.write(roomId, entry); err != nil {
+		slog.Error("unable to write to event log", logging.KeyRoomId, roomId, logging.Err(err))
+	}
+	return entry
+}
+
+func (l *eventLog) write(roomId string, entry LogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path(roomId), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exists reports whether a room has a log on disk.
+func (l *eventLog) exists(roomId string) bool {
+	l.mu.Lock()
+	dir := l.dir
+	l.mu.Unlock()
+	if dir == "" || !validRoomId(roomId) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, roomId+".jsonl"))
+	return err == nil
+}
+
+// forget drops a room's entries from memory. Its file is kept for review.
+func (l *eventLog) forget(roomId string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, roomId)
+}
+
+func (l *eventLog) read(roomId string) ([]LogEntry, error) {
+	l.mu.Lock()
+	entries, ok := l.entries[roomId]
+	l.mu.Unlock()
+	if ok {
+		return append([]LogEntry{}, entries...), nil
+	}
+	if l.dir == "" || !validRoomId(roomId) {
+		return nil, fmt.Errorf("%w: id=%s", ErrNoEventLog, roomId)
+	}
+
+	f, err := os.Open(l.path(roomId))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: id=%s", ErrNoEventLog, roomId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries = []LogEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var corrupt error
+	for scanner.Scan() {
+		if corrupt != nil {
+			return nil, corrupt
+		}
+		var entry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			corrupt = fmt.Errorf("event log for room %s is corrupt at line %d: %w", roomId, len(entries)+1, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if corrupt != nil {
+		// The server stopped while writing the last line.
+		slog.Warn("ignoring the unreadable last line of an event log", logging.KeyRoomId, roomId, logging.Err(corrupt))
+	}
+	return entries, scanner.Err()
+}
+
+func (l *eventLog) path(roomId string) string {
+	return filepath.Join(l.dir, roomId+".jsonl")
+}
+
+// validRoomId rejects ids that would name a file outside the log
+// directory.
+func validRoomId(roomId string) bool {
+	return roomId != "" && !strings.ContainsAny(roomId, `/\`) && !strings.HasPrefix(roomId, ".")
+}
+
+// EventLog returns a room's log, from memory while the room is held by
+// this server and from disk once it has ended.
+func EventLog(roomId string) ([]LogEntry, error) {
+	return events.read(roomId)
+}
+
+// RecordCommand logs a command sent to the room before it is handled.
+// data is the command's payload as the client sent it.
+func (r *Room) RecordCommand(command, playerId string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		r.logger().Warn("unable to encode command for event log", "command", command, logging.Err(err))
+		raw = nil
+	}
+	events.append(r.Id, LogEntry{Kind: EntryKindCommand, Type: command, PlayerId: playerId, Data: raw})
+}
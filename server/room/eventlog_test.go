@@ -0,0 +1,92 @@
+package room
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+func TestReplayFromLogOnDisk(t *testing.T) {
+	defer func(l *eventLog) { events = l }(events)
+	events = newEventLog(t.TempDir())
+
+	r, err := AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPreferences(types.Preferences{Difficulty: types.Easy, LetterSet: types.LetterSetHard})
+	r.AddPlayerToRoom("e1", "Aroha")
+	r.AddPlayerToRoom("e2", "Mere")
+	r.AddPlayerToRoom("e3", "Tama")
+	r.LockRoom()
+	r.ToggleUsedLetter("A")
+	r.SetLetterUnselectable("A")
+	r.SetNextPlayerIndex()
+	r.ToggleUsedLetter("B")
+	r.apply(EventPlayerEliminated, "e2", nil)
+	live := r.Snapshot()
+	EndRoom(r.Id)
+
+	entries, err := EventLog(r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Replay(r.Id, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.Snapshot(); !reflect.DeepEqual(got, live) {
+		t.Fatalf("replayed room = %+v, want %+v", got, live)
+	}
+	if !events.exists(r.Id) || events.exists("../"+r.Id) {
+		t.Fatal("exists did not find the log by the room's id alone")
+	}
+}
+
+func TestReadLogWithBadLine(t *testing.T) {
+	defer func(l *eventLog) { events = l }(events)
+	dir := t.TempDir()
+	events = newEventLog(dir)
+	lines := `{"seq":1,"kind":"event","type":"room-created"}
+{"seq":2,"kind":"event","type":"player-added","playerId":"e1","data":{"playerName":"Aroha"}}
+`
+	tests := []struct {
+		name    string
+		log     string
+		entries int
+		corrupt bool
+	}{
+		{"complete", lines, 2, false},
+		{"truncated last line", lines + `{"seq":3,"kind":"ev`, 2, false},
+		{"corrupt last line", lines + "not json\n", 2, false},
+		{"corrupt line", "not json\n" + lines, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(dir, "log-room.jsonl"), []byte(tt.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := EventLog("log-room")
+			if tt.corrupt {
+				if err == nil {
+					t.Fatal("read a log that is corrupt before its last line")
+				}
+				return
+			}
+			if err != nil || len(entries) != tt.entries {
+				t.Fatalf("EventLog = %d entries, %v, want %d", len(entries), err, tt.entries)
+			}
+			replayed, err := Replay("log-room", entries)
+			if err != nil || replayed.Players["e1"] == nil {
+				t.Fatalf("Replay = %+v, %v, want e1 seated", replayed, err)
+			}
+		})
+	}
+	if _, err := EventLog("../log-room"); !errors.Is(err, ErrNoEventLog) {
+		t.Fatalf("err = %v, want %v for an id outside the log directory", err, ErrNoEventLog)
+	}
+}
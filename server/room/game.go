@@ -0,0 +1,70 @@
+package room
+
+import (
+	"github.com/campbell-rehu/quik-be/history"
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+// trackGame builds the history of the game being played in the room from
+// the events applied to it, handing the game to Hooks.OnGameEnded once it
+// has a winner. It sees each event before the room changes, so a game's
+// win counts are read before they are reset. Games in progress are not
+// part of a room's snapshot and are lost when another server adopts it.
+func (r *Room) trackGame(entry LogEntry, data *EventData) {
+	if entry.Type == EventRoundStarted {
+		if r.game == nil {
+			r.game = &history.Game{
+				Id:           history.NewGameId(),
+				RoomId:       r.Id,
+				StartedAt:    entry.Time,
+				Participants: []history.Participant{},
+				Rounds:       []history.Round{},
+			}
+		}
+		playerIds := []string{}
+		for _, playerId := range r.playerOrder {
+			if player, ok := r.Players[playerId]; ok {
+				r.game.Participant(playerId, player.Name).AccountId = player.AccountId
+				playerIds = append(playerIds, playerId)
+			}
+		}
+		r.game.Rounds = append(r.game.Rounds, history.Round{
+			Number:       len(r.game.Rounds) + 1,
+			Category:     data.Category,
+			StartedAt:    entry.Time,
+			PlayerIds:    playerIds,
+			Letters:      []history.LetterChoice{},
+			Eliminations: []history.Elimination{},
+		})
+		return
+	}
+	if r.game == nil || len(r.game.Rounds) == 0 {
+		return
+	}
+	round := &r.game.Rounds[len(r.game.Rounds)-1]
+	switch entry.Type {
+	case EventLetterUsed:
+		round.Letters = append(round.Letters, history.LetterChoice{PlayerId: entry.PlayerId, Letter: data.Letter})
+	case EventPlayerEliminated:
+		round.Eliminations = append(round.Eliminations, history.Elimination{
+			PlayerId:        entry.PlayerId,
+			SurvivedSeconds: entry.Time.Sub(round.StartedAt).Seconds(),
+		})
+	case EventRoundWon:
+		round.WinnerId = entry.PlayerId
+		round.EndedAt = entry.Time
+		r.game.Participant(entry.PlayerId, "").RoundsWon++
+	case EventGameEnded:
+		r.game.WinnerId = entry.PlayerId
+		r.game.EndedAt = entry.Time
+		game := r.game
+		r.game = nil
+		r.logger().Info("game ended", logging.KeyGameId, game.Id, "rounds", len(game.Rounds))
+		if hooks.OnGameEnded != nil {
+			hooks.OnGameEnded(game)
+		}
+	case EventRoomEnded:
+		// The game was abandoned without a winner.
+		r.game = nil
+	}
+}
@@ -0,0 +1,20 @@
+package room
+
+import "github.com/campbell-rehu/quik-be/metrics"
+
+func init() {
+	metrics.RegisterGaugeFunc("rooms_active", "Rooms held by this server.", func() float64 {
+		allRooms.mu.RLock()
+		defer allRooms.mu.RUnlock()
+		return float64(len(allRooms.rooms))
+	})
+	metrics.RegisterGaugeFunc("players_active", "Players seated in rooms held by this server.", func() float64 {
+		allRooms.mu.RLock()
+		defer allRooms.mu.RUnlock()
+		players := 0
+		for _, room := range allRooms.rooms {
+			players += room.GetPlayerCount()
+		}
+		return float64(players)
+	})
+}
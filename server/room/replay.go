@@ -0,0 +1,180 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+// Events recorded in a room's log. Every change to a room's state is made
+// by applying one of them, so replaying a log rebuilds the room. The timer,
+// challenge and vote events change nothing that is replayed but show when
+// each turn started, stopped and ran out, and how answers were disputed.
+const (
+	EventRoomCreated      = "room-created"
+	EventRoomRestored     = "room-restored"
+	EventRoomEnded        = "room-ended"
+	EventPreferencesSet   = "preferences-set"
+	EventRoomLocked       = "room-locked"
+	EventRoomUnlocked     = "room-unlocked"
+	EventPlayerAdded      = "player-added"
+	EventPlayerLeft       = "player-left"
+	EventRoundStarted     = "round-started"
+	EventTurnAdvanced     = "turn-advanced"
+	EventLetterSelected   = "letter-selected"
+	EventLetterRemoved    = "letter-removed"
+	EventLetterUsed       = "letter-used"
+	EventTimerStarted     = "timer-started"
+	EventTimerReset       = "timer-reset"
+	EventTimerPaused      = "timer-paused"
+	EventTimerResumed     = "timer-resumed"
+	EventTimerExpired     = "timer-expired"
+	EventPlayerEliminated = "player-eliminated"
+	EventRoundWon         = "round-won"
+	EventRoundEnded       = "round-ended"
+	EventGameEnded        = "game-ended"
+	EventTextModeSet      = "text-mode-set"
+	EventAnswerGiven      = "answer-given"
+	EventAnswerChallenged = "answer-challenged"
+	EventVoteCast         = "vote-cast"
+	EventChallengeEnded   = "challenge-ended"
+)
+
+// EventData is the payload of an event, with only the fields the event
+// needs set.
+type EventData struct {
+	PlayerName  string             `json:"playerName,omitempty"`
+	AccountId   string             `json:"accountId,omitempty"`
+	Bot         bool               `json:"bot,omitempty"`
+	Letter      string             `json:"letter,omitempty"`
+	Category    string             `json:"category,omitempty"`
+	TextMode    bool               `json:"textMode,omitempty"`
+	Answer      string             `json:"answer,omitempty"`
+	Unknown     bool               `json:"unknown,omitempty"`
+	Keep        bool               `json:"keep,omitempty"`
+	Struck      bool               `json:"struck,omitempty"`
+	Preferences *types.Preferences `json:"preferences,omitempty"`
+	Snapshot    *Snapshot          `json:"snapshot,omitempty"`
+}
+
+// apply records an event in the room's log and then makes the change it
+// describes.
+func (r *Room) apply(eventType, playerId string, data *EventData) {
+	var raw json.RawMessage
+	if data != nil {
+		raw, _ = json.Marshal(data)
+	}
+	entry := events.append(r.Id, LogEntry{Kind: EntryKindEvent, Type: eventType, PlayerId: playerId, Data: raw})
+	r.trackGame(entry, data)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mutate(entry, data)
+}
+
+// mutate changes the room's state as an event describes. It must not touch
+// anything outside the room, since it is also used to replay logs.
+func (r *Room) mutate(entry LogEntry, data *EventData) {
+	switch entry.Type {
+	case EventRoomRestored:
+		r.restore(data.Snapshot)
+	case EventPreferencesSet:
+		r.Preferences = *data.Preferences
+	case EventRoomLocked:
+		r.locked = true
+	case EventRoomUnlocked:
+		r.locked = false
+	case EventPlayerAdded:
+		r.playerOrder = append(r.playerOrder, entry.PlayerId)
+		r.Players[entry.PlayerId] = &types.Player{
+			Id:         entry.PlayerId,
+			Name:       data.PlayerName,
+			AccountId:  data.AccountId,
+			Bot:        data.Bot,
+			IsTurn:     false,
+			Eliminated: false,
+			WinCount:   0,
+		}
+	case EventPlayerLeft:
+		r.removePlayerFromPlayersMap(entry.PlayerId)
+		r.removePlayerFromPlayerOrder(entry.PlayerId)
+	case EventRoundStarted:
+		r.category = data.Category
+	case EventTurnAdvanced:
+		r.advanceTurn()
+	case EventLetterSelected:
+		r.UsedLetters[data.Letter] = true
+	case EventLetterRemoved:
+		delete(r.UsedLetters, data.Letter)
+	case EventLetterUsed:
+		if _, ok := r.UsedLetters[data.Letter]; ok {
+			r.UsedLetters[data.Letter] = false
+		}
+	case EventPlayerEliminated:
+		if player, ok := r.Players[entry.PlayerId]; ok {
+			player.Eliminated = true
+		}
+	case EventRoundWon:
+		if player, ok := r.Players[entry.PlayerId]; ok {
+			player.WinCount++
+		}
+	case EventRoundEnded:
+		r.resetUsedLetters()
+		r.resetAnswers()
+		r.resetPlayersState(false)
+	case EventGameEnded:
+		r.resetUsedLetters()
+		r.resetAnswers()
+		r.resetPlayersState(true)
+	case EventTextModeSet:
+		r.TextMode = data.TextMode
+	case EventAnswerGiven:
+		r.Answers = append(r.Answers, types.Answer{PlayerId: entry.PlayerId, Letter: data.Letter, Answer: data.Answer, Unknown: data.Unknown})
+	case EventChallengeEnded:
+		for i, answer := range r.Answers {
+			if data.Struck && answer.PlayerId == entry.PlayerId && answer.Answer == data.Answer {
+				r.Answers[i].Struck = true
+			}
+		}
+	}
+}
+
+// Replay rebuilds a room from its log. The room is not added to this
+// server's rooms and its timer is not running.
+func Replay(roomId string, entries []LogEntry) (*Room, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: id=%s", ErrNoEventLog, roomId)
+	}
+	room := NewRoom()
+	room.Id = roomId
+	for _, entry := range entries {
+		if entry.Kind != EntryKindEvent {
+			continue
+		}
+		data := &EventData{}
+		if len(entry.Data) > 0 {
+			if err := json.Unmarshal(entry.Data, data); err != nil {
+				return nil, fmt.Errorf("unable to replay event %d %s: %w", entry.Seq, entry.Type, err)
+			}
+		}
+		if err := data.validate(entry.Type); err != nil {
+			return nil, fmt.Errorf("unable to replay event %d %s: %w", entry.Seq, entry.Type, err)
+		}
+		room.mutate(entry, data)
+	}
+	if room.currentPlayerIndex < len(room.playerOrder) {
+		room.CurrentPlayer = room.GetCurrentPlayer()
+	}
+	return room, nil
+}
+
+func (d *EventData) validate(eventType string) error {
+	switch {
+	case eventType == EventRoomRestored && d.Snapshot == nil:
+		return errors.New("missing snapshot")
+	case eventType == EventPreferencesSet && d.Preferences == nil:
+		return errors.New("missing preferences")
+	}
+	return nil
+}
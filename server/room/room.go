@@ -1,50 +1,120 @@
 package room
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/campbell-rehu/quik-be/helpers"
+	"github.com/campbell-rehu/quik-be/history"
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/metrics"
+	"github.com/campbell-rehu/quik-be/names"
+	"github.com/campbell-rehu/quik-be/tracing"
 	"github.com/campbell-rehu/quik-be/types"
 	"github.com/jaswdr/faker/v2"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const DefaultTimerDuration = 10
+var (
+	ErrRoomLocked     = errors.New("room is locked, new players cannot join")
+	ErrPlayerNotFound = errors.New("player not found in room")
+)
+
+// Phases a room can be in. A room starts waiting for players and is
+// playing once its first round has started and it is locked.
+const (
+	PhaseWaiting = "waiting"
+	PhasePlaying = "playing"
+)
+
+// fake is shared so that rooms created within the same second do not get
+// the same id; faker.New seeds from the current unix second.
+var fake = faker.New()
 
 type Room struct {
-	Id                 string                   `json:"id"`
-	UsedLetters        map[string]bool          `json:"usedLetters"`
-	Players            map[string]*types.Player `json:"players"`
-	CurrentPlayer      *types.Player            `json:"currentPlayer"`
+	Id            string                   `json:"id"`
+	UsedLetters   map[string]bool          `json:"usedLetters"`
+	Players       map[string]*types.Player `json:"players"`
+	CurrentPlayer *types.Player            `json:"currentPlayer"`
+	Preferences   types.Preferences        `json:"preferences"`
+	// TextMode has players type their answers, which are checked and kept
+	// in Answers for the round.
+	TextMode           bool           `json:"textMode"`
+	Answers            []types.Answer `json:"answers"`
 	locked             bool
 	timer              *Timer
 	playerOrder        []string
 	currentPlayerIndex int
+	category           string
+	answerRejected     bool
+	answeredAt         time.Time
+	createdAt          time.Time
+	// mu is held while the room's state changes, so that it can be
+	// snapshotted while players are playing.
+	mu          sync.Mutex
+	challengeMu sync.Mutex
+	challenge   *challenge
+	game        *history.Game
 }
 
 func NewRoom() *Room {
-	fake := faker.New()
 	return &Room{
 		Id:                 fmt.Sprintf("%s-%s", fake.Lorem().Word(), fake.Lorem().Word()),
 		UsedLetters:        make(map[string]bool),
 		Players:            make(map[string]*types.Player),
+		Answers:            []types.Answer{},
 		CurrentPlayer:      nil,
 		locked:             false,
 		timer:              NewTimer(),
 		playerOrder:        []string{},
 		currentPlayerIndex: 0,
+		createdAt:          time.Now(),
 	}
 }
 
+func (r *Room) logger() *slog.Logger {
+	return slog.With(logging.KeyRoomId, r.Id)
+}
+
+func (r *Room) SetPreferences(preferences types.Preferences) {
+	r.apply(EventPreferencesSet, "", &EventData{Preferences: &preferences})
+}
+
 func (r *Room) GetCategory() string {
-	difficultyIndex := rand.Intn(len(types.Difficulties))
-	difficultyValue := types.Difficulties[difficultyIndex]
+	difficultyValue := r.Preferences.Difficulty
+	if difficultyValue == "" {
+		difficultyIndex := rand.Intn(len(types.Difficulties))
+		difficultyValue = types.Difficulties[difficultyIndex]
+	}
 	categoriesForDifficulty := types.C[difficultyValue]
 	return categoriesForDifficulty[rand.Intn(len(categoriesForDifficulty))]
 }
 
+// StartRound picks the category for a new round and records its start.
+func (r *Room) StartRound() string {
+	category := r.GetCategory()
+	r.apply(EventRoundStarted, "", &EventData{Category: category})
+	return category
+}
+
+// Category is the current round's category, or the last round's between
+// rounds.
+func (r *Room) Category() string {
+	return r.category
+}
+
 func (r *Room) SetNextPlayerIndex() {
+	r.answerRejected = false
+	r.apply(EventTurnAdvanced, "", nil)
+}
+
+func (r *Room) advanceTurn() {
 	next := r.currentPlayerIndex + 1
 	if next >= len(r.Players) {
 		next = 0
@@ -57,32 +127,73 @@ func (r *Room) IsLocked() bool {
 }
 
 func (r *Room) LockRoom() {
-	r.locked = true
+	r.apply(EventRoomLocked, "", nil)
+	r.traceTransition(context.Background(), spanRoomLocked)
 }
 
 func (r *Room) UnlockRoom() {
 	if r.timer.started {
 		return
 	}
-	r.locked = false
+	r.apply(EventRoomUnlocked, "", nil)
+	r.traceTransition(context.Background(), spanRoomUnlocked)
 }
 
 func (r *Room) AddPlayerToRoom(playerId, playerName string) error {
+	return r.AddAccountPlayerToRoom(playerId, playerName, "")
+}
+
+// AddAccountPlayerToRoom adds a player signed in to an account, so that
+// their games are kept against it. Guests have an empty accountId. The
+// player's name is cleaned up and given a suffix if another player in the
+// room already has it.
+func (r *Room) AddAccountPlayerToRoom(playerId, playerName, accountId string) error {
+	return r.addPlayer(playerId, playerName, &EventData{AccountId: accountId})
+}
+
+// AddBotToRoom adds a player that the server plays itself.
+func (r *Room) AddBotToRoom(botId, botName string) error {
+	return r.addPlayer(botId, botName, &EventData{Bot: true})
+}
+
+// addPlayer seats a player described by data, which is given the
+// player's name.
+func (r *Room) addPlayer(playerId, playerName string, data *EventData) error {
 	if r.locked {
-		err := errors.New("room is locked, new players cannot join")
-		helpers.PrintError(err)
+		r.logger().Warn("room is locked, player cannot join", logging.KeyPlayerId, playerId)
+		return ErrRoomLocked
+	}
+	if _, ok := r.Players[playerId]; !ok && settings.MaxPlayers > 0 && r.GetPlayerCount() >= settings.MaxPlayers {
+		return fmt.Errorf("%w: id=%s", ErrRoomFull, r.Id)
+	}
+	playerName, err := names.Clean(playerName)
+	if err != nil {
 		return err
 	}
+	playerName = names.Unique(playerName, func(name string) bool {
+		for id, player := range r.Players {
+			if id != playerId && strings.EqualFold(player.Name, name) {
+				return true
+			}
+		}
+		return false
+	})
 	AddPlayerIdToRoomIdMapping(playerId, r.Id)
-	r.playerOrder = append(r.playerOrder, playerId)
-	r.Players[playerId] = &types.Player{
-		Id:         playerId,
-		Name:       playerName,
-		IsTurn:     false,
-		Eliminated: false,
-		WinCount:   0,
+	data.PlayerName = playerName
+	r.apply(EventPlayerAdded, playerId, data)
+	r.logger().Info("player added to room", logging.KeyPlayerId, playerId, logging.KeyPlayerName, playerName)
+	r.traceTransition(context.Background(), spanPlayerJoined, tracing.KeyPlayerId.String(playerId))
+	return nil
+}
+
+// KickPlayer removes a player from the room as if they had left it.
+func (r *Room) KickPlayer(playerId string) error {
+	if _, ok := r.Players[playerId]; !ok {
+		return fmt.Errorf("%w: id=%s", ErrPlayerNotFound, playerId)
 	}
-	helpers.Print("player id=%s added to room id=%s", playerId, r.Id)
+	r.logger().Info("kicking player from room", logging.KeyPlayerId, playerId)
+	r.traceTransition(context.Background(), spanPlayerKicked, tracing.KeyPlayerId.String(playerId))
+	r.LeaveRoom(playerId)
 	return nil
 }
 
@@ -90,15 +201,24 @@ func (r *Room) GetPlayerCount() int {
 	return len(r.Players)
 }
 
+// IsTurnOf reports whether it is the player's turn.
+func (r *Room) IsTurnOf(playerId string) bool {
+	if r.GetPlayerCount() == 0 {
+		return false
+	}
+	player := r.GetCurrentPlayer()
+	return player != nil && player.Id == playerId
+}
+
 func (r *Room) GetCurrentPlayer() *types.Player {
 	currentPlayerId := r.playerOrder[r.currentPlayerIndex]
 	return r.Players[currentPlayerId]
 }
 
 func (r *Room) LeaveRoom(playerId string) {
-	helpers.Print("player id=%s leaving room", playerId)
-	r.removePlayerFromPlayersMap(playerId)
-	r.removePlayerFromPlayerOrder(playerId)
+	r.logger().Info("player leaving room", logging.KeyPlayerId, playerId)
+	r.traceTransition(context.Background(), spanPlayerLeft, tracing.KeyPlayerId.String(playerId))
+	r.apply(EventPlayerLeft, playerId, nil)
 	RemovePlayerIdToRoomIdMapping(playerId)
 	if r.GetPlayerCount() == 1 {
 		r.UnlockRoom()
@@ -123,7 +243,7 @@ func (r *Room) removePlayerFromPlayerOrder(playerId string) {
 	if found == true {
 		r.playerOrder = append(r.playerOrder[:playerIndex], r.playerOrder[playerIndex+1:]...)
 		if playerIndex == r.currentPlayerIndex {
-			r.SetNextPlayerIndex()
+			r.advanceTurn()
 		}
 	}
 }
@@ -132,44 +252,55 @@ func (r *Room) StartTimer(
 	emitTick func(chan int, chan bool),
 	emitEvent func(types.EventType, any),
 ) {
+	r.startTurnSpan()
+	r.apply(EventTimerStarted, r.currentPlayerId(), nil)
 	r.timer.start(emitTick, r.handleTimerExpiry(emitEvent))
 }
 
 func (r *Room) handleTimerExpiry(emitEvent func(types.EventType, any)) func() {
 	return func() {
+		ctx, span := tracing.Tracer().Start(r.endTurnSpan("expired"), spanTimerExpired,
+			trace.WithAttributes(tracing.KeyRoomId.String(r.Id)),
+		)
+		defer span.End()
+		metrics.TimerExpiries.Inc()
+		r.apply(EventTimerExpired, r.currentPlayerId(), nil)
 		player := r.eliminateCurrentPlayer()
+		metrics.PlayersEliminated.Inc()
+		r.traceTransition(ctx, spanPlayerEliminated, tracing.KeyPlayerId.String(player.Id))
 		r.SetNextPlayerIndex()
-		type x struct {
-			EliminatedPlayer *types.Player `json:"eliminatedPlayer"`
-		}
-		emitEvent(types.EventTypePlayerEliminated, &x{EliminatedPlayer: player})
-		if r.getRemainingPlayerCount() == 1 {
-			remainingPlayer := r.getRemainingPlayer()
-			r.increasePlayerWinCount(remainingPlayer.Id)
-			gameWinner := r.getGameWinner()
-			if gameWinner == nil {
-				r.endRound()
-				type t struct {
-					WinningPlayer *types.Player `json:"winningPlayer"`
-				}
-				emitEvent(types.EventTypeRoundEnded, &t{WinningPlayer: remainingPlayer})
-			} else {
-				r.endGame()
-				type t struct {
-					GameWinner    *types.Player   `json:"gameWinner"`
-					UsedLetters   map[string]bool `json:"usedLetters"`
-					CurrentPlayer *types.Player   `json:"currentPlayer"`
-					PlayerCount   int             `json:"playerCount"`
-				}
-				emitEvent(types.EventTypeGameEnded, &t{
-					GameWinner:    gameWinner,
-					UsedLetters:   r.UsedLetters,
-					CurrentPlayer: r.GetCurrentPlayer(),
-					PlayerCount:   r.GetPlayerCount(),
-				})
-			}
-		}
+		emitEvent(types.EventTypePlayerEliminated, &types.PlayerEliminatedPayload{EliminatedPlayer: player})
+		r.settleRound(ctx, emitEvent)
+	}
+}
+
+// settleRound ends the round once one player is left in it, and the game
+// once that player has won enough rounds. It reports whether the round
+// ended.
+func (r *Room) settleRound(ctx context.Context, emitEvent func(types.EventType, any)) bool {
+	if r.getRemainingPlayerCount() != 1 {
+		return false
+	}
+	remainingPlayer := r.getRemainingPlayer()
+	r.increasePlayerWinCount(remainingPlayer.Id)
+	gameWinner := r.getGameWinner()
+	metrics.RoundsCompleted.Inc()
+	if gameWinner == nil {
+		r.endRound()
+		r.traceTransition(ctx, spanRoundEnded, tracing.KeyPlayerId.String(remainingPlayer.Id))
+		emitEvent(types.EventTypeRoundEnded, &types.RoundEndedPayload{WinningPlayer: remainingPlayer})
+	} else {
+		r.endGame(gameWinner.Id)
+		metrics.GamesCompleted.Inc()
+		r.traceTransition(ctx, spanGameEnded, tracing.KeyPlayerId.String(gameWinner.Id))
+		emitEvent(types.EventTypeGameEnded, &types.GameEndedPayload{
+			GameWinner:    gameWinner,
+			UsedLetters:   r.UsedLetters,
+			CurrentPlayer: r.GetCurrentPlayer(),
+			PlayerCount:   r.GetPlayerCount(),
+		})
 	}
+	return true
 }
 
 func (r *Room) getRemainingPlayerCount() int {
@@ -183,15 +314,23 @@ func (r *Room) getRemainingPlayerCount() int {
 }
 
 func (r *Room) eliminateCurrentPlayer() *types.Player {
-	player := r.Players[r.playerOrder[r.currentPlayerIndex]]
-	r.Players[r.playerOrder[r.currentPlayerIndex]].Eliminated = true
-	return player
+	playerId := r.playerOrder[r.currentPlayerIndex]
+	r.apply(EventPlayerEliminated, playerId, nil)
+	return r.Players[playerId]
+}
+
+// currentPlayerId is the id of the player whose turn it is, or "" when the
+// room is empty.
+func (r *Room) currentPlayerId() string {
+	if r.currentPlayerIndex >= len(r.playerOrder) {
+		return ""
+	}
+	return r.playerOrder[r.currentPlayerIndex]
 }
 
 func (r *Room) endRound() {
 	r.ResetTimer()
-	r.resetUsedLetters()
-	r.resetPlayersState(false)
+	r.apply(EventRoundEnded, "", nil)
 }
 
 func (r *Room) resetUsedLetters() {
@@ -207,15 +346,14 @@ func (r *Room) resetPlayersState(endGame bool) {
 	}
 }
 
-func (r *Room) endGame() {
+func (r *Room) endGame(winnerId string) {
 	r.ResetTimer()
-	r.resetUsedLetters()
-	r.resetPlayersState(true)
+	r.apply(EventGameEnded, winnerId, nil)
 }
 
 func (r *Room) getGameWinner() *types.Player {
 	for _, player := range r.Players {
-		if player.WinCount >= 3 {
+		if player.WinCount >= settings.WinCount {
 			return player
 		}
 	}
@@ -223,7 +361,7 @@ func (r *Room) getGameWinner() *types.Player {
 }
 
 func (r *Room) increasePlayerWinCount(playerId string) {
-	r.Players[playerId].WinCount++
+	r.apply(EventRoundWon, playerId, nil)
 }
 
 func (r *Room) getRemainingPlayer() *types.Player {
@@ -236,24 +374,87 @@ func (r *Room) getRemainingPlayer() *types.Player {
 }
 
 func (r *Room) ResetTimer() {
+	r.endTurnSpan("reset")
+	r.apply(EventTimerReset, "", nil)
 	r.timer.reset()
 }
 
+func (r *Room) PauseTimer() {
+	r.timer.pause()
+	r.apply(EventTimerPaused, "", nil)
+	r.addTurnEvent("paused")
+}
+
+func (r *Room) ResumeTimer() {
+	r.timer.resume()
+	r.apply(EventTimerResumed, "", nil)
+	r.addTurnEvent("resumed")
+}
+
+// StopTimer resets the turn timer if it is running.
+func (r *Room) StopTimer() {
+	if r.timer.started {
+		r.ResetTimer()
+	}
+}
+
+func (r *Room) Phase() string {
+	if r.locked {
+		return PhasePlaying
+	}
+	return PhaseWaiting
+}
+
+type TimerState struct {
+	Running          bool `json:"running"`
+	Paused           bool `json:"paused"`
+	TimeLimit        int  `json:"timeLimit"`
+	SecondsRemaining int  `json:"secondsRemaining"`
+}
+
+func (r *Room) TimerState() TimerState {
+	state := TimerState{
+		Running:   r.timer.started,
+		Paused:    r.timer.isPaused(),
+		TimeLimit: r.timer.timeLimit,
+	}
+	if state.Running {
+		state.SecondsRemaining = r.timer.getRemaining()
+	}
+	return state
+}
+
+// Letters are the letters players choose from: the room's letter set, or
+// the easy letters when it has none.
+func (r *Room) Letters() []string {
+	letterSet := r.Preferences.LetterSet
+	if letterSet == "" {
+		letterSet = types.LetterSetEasy
+	}
+	return types.Letters[letterSet]
+}
+
+// HasLetter reports whether players may choose the letter. Rooms without a
+// letter set leave the choice of letters to their players.
+func (r *Room) HasLetter(letter string) bool {
+	return r.Preferences.LetterSet == "" || slices.Contains(r.Letters(), letter)
+}
+
 func (r *Room) ToggleUsedLetter(letter string) {
 	if val, ok := r.UsedLetters[letter]; val && ok {
 		r.RemoveUsedLetter(letter)
 	}
-	r.UsedLetters[letter] = true
+	r.apply(EventLetterSelected, "", &EventData{Letter: letter})
 }
 
 func (r *Room) RemoveUsedLetter(letter string) {
 	if _, ok := r.UsedLetters[letter]; ok {
-		delete(r.UsedLetters, letter)
+		r.apply(EventLetterRemoved, "", &EventData{Letter: letter})
 	}
 }
 
 func (r *Room) SetLetterUnselectable(letter string) {
 	if _, ok := r.UsedLetters[letter]; ok {
-		r.UsedLetters[letter] = false
+		r.apply(EventLetterUsed, r.currentPlayerId(), &EventData{Letter: letter})
 	}
 }
@@ -1,18 +1,51 @@
 package room
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/history"
 )
 
+// maxRoomIdAttempts bounds how many ids AddRoom generates looking for one
+// that Hooks.Accepts.
+const maxRoomIdAttempts = 32
+
+// EmptyRoomTimeout is how long a room is kept without anyone joining it.
+const EmptyRoomTimeout = 5 * time.Minute
+
 var allRooms = newRooms()
 
+var ErrRoomNotFound = errors.New("room not found")
+
+// Hooks are called as rooms are added and removed, e.g. to claim ownership
+// of them across replicas. Accepts, if set, picks which generated room ids
+// this server should use. OnGameEnded is given each completed game.
+type Hooks struct {
+	Accepts     func(roomId string) bool
+	OnAdd       func(room *Room)
+	OnRemove    func(roomId string)
+	OnGameEnded func(game *history.Game)
+}
+
+var hooks Hooks
+
+func SetHooks(h Hooks) {
+	hooks = h
+}
+
 type RoomIdAndPlayerId struct {
 	RoomId   string
 	PlayerId string
 }
 
 type Rooms struct {
+	mu               sync.RWMutex
 	rooms            map[string]*Room
 	playerIdToRoomId map[string]string
 }
@@ -24,16 +57,39 @@ func newRooms() *Rooms {
 	}
 }
 
-func AddRoom() *Room {
+func AddRoom() (*Room, error) {
+	allRooms.mu.Lock()
+	if settings.MaxRooms > 0 && len(allRooms.rooms) >= settings.MaxRooms {
+		allRooms.mu.Unlock()
+		return nil, fmt.Errorf("%w: limit=%d", ErrTooManyRooms, settings.MaxRooms)
+	}
 	room := NewRoom()
+	for attempt := 1; ; attempt++ {
+		_, taken := allRooms.rooms[room.Id]
+		// Ids with a log on disk belong to rooms that have ended.
+		taken = taken || events.exists(room.Id)
+		accepted := hooks.Accepts == nil || attempt >= maxRoomIdAttempts || hooks.Accepts(room.Id)
+		if !taken && accepted {
+			break
+		}
+		room = NewRoom()
+	}
 	allRooms.rooms[room.Id] = room
-	return room
+	allRooms.mu.Unlock()
+	room.apply(EventRoomCreated, "", nil)
+	room.traceTransition(context.Background(), spanRoomCreated)
+	if hooks.OnAdd != nil {
+		hooks.OnAdd(room)
+	}
+	return room, nil
 }
 
 func GetRoom(roomId string) (*Room, error) {
+	allRooms.mu.RLock()
 	room, ok := allRooms.rooms[roomId]
+	allRooms.mu.RUnlock()
 	if !ok {
-		return nil, errors.New(fmt.Sprintf("room with id=%s not found\n", roomId))
+		return nil, fmt.Errorf("%w: id=%s", ErrRoomNotFound, roomId)
 	}
 	if room.GetPlayerCount() > 0 {
 		room.CurrentPlayer = room.GetCurrentPlayer()
@@ -41,11 +97,73 @@ func GetRoom(roomId string) (*Room, error) {
 	return room, nil
 }
 
+func HasRoom(roomId string) bool {
+	allRooms.mu.RLock()
+	defer allRooms.mu.RUnlock()
+	_, ok := allRooms.rooms[roomId]
+	return ok
+}
+
+// RoomIds lists the rooms held by this server.
+func RoomIds() []string {
+	allRooms.mu.RLock()
+	defer allRooms.mu.RUnlock()
+	roomIds := make([]string, 0, len(allRooms.rooms))
+	for roomId := range allRooms.rooms {
+		roomIds = append(roomIds, roomId)
+	}
+	return roomIds
+}
+
+// List returns the rooms held by this server, ordered by id.
+func List() []*Room {
+	allRooms.mu.RLock()
+	rooms := make([]*Room, 0, len(allRooms.rooms))
+	for _, room := range allRooms.rooms {
+		rooms = append(rooms, room)
+	}
+	allRooms.mu.RUnlock()
+	slices.SortFunc(rooms, func(a, b *Room) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return rooms
+}
+
+// EndRoom stops a room's timer and removes it along with its players.
+func EndRoom(roomId string) error {
+	room, err := GetRoom(roomId)
+	if err != nil {
+		return err
+	}
+	room.StopTimer()
+	for playerId := range room.Players {
+		RemovePlayerIdToRoomIdMapping(playerId)
+	}
+	room.apply(EventRoomEnded, "", nil)
+	RemoveRoom(roomId)
+	room.logger().Info("room ended")
+	room.traceTransition(context.Background(), spanRoomEnded)
+	return nil
+}
+
+// PauseTimers pauses the turn timer of every room on this server.
+func PauseTimers() {
+	allRooms.mu.RLock()
+	defer allRooms.mu.RUnlock()
+	for _, room := range allRooms.rooms {
+		room.PauseTimer()
+	}
+}
+
 func AddPlayerIdToRoomIdMapping(playerId, roomId string) {
+	allRooms.mu.Lock()
+	defer allRooms.mu.Unlock()
 	allRooms.playerIdToRoomId[playerId] = roomId
 }
 
 func GetRoomId(playerId string) string {
+	allRooms.mu.RLock()
+	defer allRooms.mu.RUnlock()
 	roomId, ok := allRooms.playerIdToRoomId[playerId]
 	if !ok {
 		return ""
@@ -54,9 +172,60 @@ func GetRoomId(playerId string) string {
 }
 
 func RemovePlayerIdToRoomIdMapping(playerId string) {
+	allRooms.mu.Lock()
+	defer allRooms.mu.Unlock()
 	delete(allRooms.playerIdToRoomId, playerId)
 }
 
+// EvictRoom stops a room's timer and drops it from this server without
+// calling Hooks.OnRemove, for a room another server has taken over and
+// whose lease and snapshot are no longer this server's to release.
+func EvictRoom(roomId string) {
+	room, err := GetRoom(roomId)
+	if err != nil {
+		return
+	}
+	room.StopTimer()
+	allRooms.mu.Lock()
+	delete(allRooms.rooms, roomId)
+	for playerId, id := range allRooms.playerIdToRoomId {
+		if id == roomId {
+			delete(allRooms.playerIdToRoomId, playerId)
+		}
+	}
+	allRooms.mu.Unlock()
+	events.forget(roomId)
+	room.logger().Warn("room evicted, another server runs it now")
+}
+
+// RemoveEmptyRooms removes the rooms nobody joined within maxAge of them
+// being created, so that whatever was held for them is released.
+func RemoveEmptyRooms(maxAge time.Duration) {
+	allRooms.mu.RLock()
+	empty := []*Room{}
+	for _, room := range allRooms.rooms {
+		if room.GetPlayerCount() == 0 && time.Since(room.createdAt) > maxAge {
+			empty = append(empty, room)
+		}
+	}
+	allRooms.mu.RUnlock()
+	for _, room := range empty {
+		room.logger().Info("removing room nobody joined")
+		room.StopTimer()
+		RemoveRoom(room.Id)
+	}
+}
+
 func RemoveRoom(roomId string) {
+	allRooms.mu.Lock()
+	room, ok := allRooms.rooms[roomId]
 	delete(allRooms.rooms, roomId)
+	allRooms.mu.Unlock()
+	if ok {
+		events.forget(roomId)
+		room.traceTransition(context.Background(), spanRoomRemoved)
+	}
+	if hooks.OnRemove != nil {
+		hooks.OnRemove(roomId)
+	}
 }
@@ -0,0 +1,39 @@
+package room
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemoveEmptyRooms(t *testing.T) {
+	removed := make(chan string, 2)
+	SetHooks(Hooks{OnRemove: func(roomId string) { removed <- roomId }})
+	defer SetHooks(Hooks{})
+
+	fresh, err := AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer EndRoom(fresh.Id)
+	joined, err := AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer EndRoom(joined.Id)
+	joined.AddPlayerToRoom("r1", "Aroha")
+	joined.createdAt = joined.createdAt.Add(-time.Hour)
+	abandoned, err := AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	abandoned.createdAt = abandoned.createdAt.Add(-time.Hour)
+
+	RemoveEmptyRooms(time.Minute)
+
+	if HasRoom(abandoned.Id) || len(removed) != 1 || <-removed != abandoned.Id {
+		t.Fatal("the room nobody joined was not removed")
+	}
+	if !HasRoom(fresh.Id) || !HasRoom(joined.Id) {
+		t.Fatal("a new room or one with players in it was removed")
+	}
+}
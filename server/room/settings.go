@@ -0,0 +1,49 @@
+package room
+
+import "errors"
+
+const (
+	DefaultTimerDuration   = 10
+	DefaultWinCount        = 3
+	DefaultChallengeWindow = 5
+	DefaultVoteDuration    = 10
+)
+
+var (
+	ErrRoomFull     = errors.New("room is full, new players cannot join")
+	ErrTooManyRooms = errors.New("too many rooms, no new rooms can be created")
+)
+
+// Settings are the game rules and limits applied to every room.
+type Settings struct {
+	// TimerDuration is the length of a turn in seconds.
+	TimerDuration int
+	// WinCount is the number of rounds a player must win to win the game.
+	WinCount int
+	// ChallengeWindow is how many seconds players have to challenge an
+	// answer once it is given, and VoteDuration how many seconds they
+	// then have to vote on it.
+	ChallengeWindow int
+	VoteDuration    int
+	// MaxRooms caps the rooms held by this server, 0 for no limit.
+	MaxRooms int
+	// MaxPlayers caps the players in a room, 0 for no limit.
+	MaxPlayers int
+	// EventLogDir is where each room's event log is written as JSON Lines.
+	// Logs are only kept in memory while it is empty.
+	EventLogDir string
+}
+
+var settings = Settings{
+	TimerDuration:   DefaultTimerDuration,
+	WinCount:        DefaultWinCount,
+	ChallengeWindow: DefaultChallengeWindow,
+	VoteDuration:    DefaultVoteDuration,
+}
+
+func Configure(s Settings) {
+	settings = s
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	events.dir = s.EventLogDir
+}
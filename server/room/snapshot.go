@@ -0,0 +1,112 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+// Snapshot is the persisted state of a room, enough for another server to
+// adopt the room. Timers are not persisted; an adopted room's turn restarts
+// when a client resets the timer.
+type Snapshot struct {
+	Id                 string                   `json:"id"`
+	UsedLetters        map[string]bool          `json:"usedLetters"`
+	Players            map[string]*types.Player `json:"players"`
+	Preferences        types.Preferences        `json:"preferences"`
+	Locked             bool                     `json:"locked"`
+	PlayerOrder        []string                 `json:"playerOrder"`
+	CurrentPlayerIndex int                      `json:"currentPlayerIndex"`
+	Category           string                   `json:"category"`
+	TextMode           bool                     `json:"textMode"`
+	Answers            []types.Answer           `json:"answers"`
+}
+
+// Snapshot copies the room's state, so that it can be encoded while the
+// room carries on changing.
+func (r *Room) Snapshot() *Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	players := make(map[string]*types.Player, len(r.Players))
+	for playerId, player := range r.Players {
+		p := *player
+		players[playerId] = &p
+	}
+	return &Snapshot{
+		Id:                 r.Id,
+		UsedLetters:        maps.Clone(r.UsedLetters),
+		Players:            players,
+		Preferences:        r.Preferences,
+		Locked:             r.locked,
+		PlayerOrder:        slices.Clone(r.playerOrder),
+		CurrentPlayerIndex: r.currentPlayerIndex,
+		Category:           r.category,
+		TextMode:           r.TextMode,
+		Answers:            slices.Clone(r.Answers),
+	}
+}
+
+// RestoreRoom recreates a room from a snapshot and adds it to this server's
+// rooms, replacing any local copy.
+func RestoreRoom(snapshot *Snapshot) *Room {
+	room := NewRoom()
+	room.Id = snapshot.Id
+	room.apply(EventRoomRestored, "", &EventData{Snapshot: snapshot})
+	allRooms.mu.Lock()
+	allRooms.rooms[room.Id] = room
+	allRooms.mu.Unlock()
+	for _, playerId := range room.playerOrder {
+		AddPlayerIdToRoomIdMapping(playerId, room.Id)
+	}
+	return room
+}
+
+func (r *Room) restore(snapshot *Snapshot) {
+	r.UsedLetters = snapshot.UsedLetters
+	r.Players = snapshot.Players
+	r.Preferences = snapshot.Preferences
+	r.locked = snapshot.Locked
+	r.playerOrder = snapshot.PlayerOrder
+	r.currentPlayerIndex = snapshot.CurrentPlayerIndex
+	r.category = snapshot.Category
+	r.TextMode = snapshot.TextMode
+	r.Answers = snapshot.Answers
+	if r.UsedLetters == nil {
+		r.UsedLetters = make(map[string]bool)
+	}
+	if r.Players == nil {
+		r.Players = make(map[string]*types.Player)
+	}
+	if r.playerOrder == nil {
+		r.playerOrder = []string{}
+	}
+	if r.Answers == nil {
+		r.Answers = []types.Answer{}
+	}
+}
+
+// Snapshots encodes and decodes the rooms on this server for the cluster
+// store.
+type Snapshots struct{}
+
+func (Snapshots) Snapshot(roomId string) ([]byte, error) {
+	allRooms.mu.RLock()
+	room, ok := allRooms.rooms[roomId]
+	allRooms.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: id=%s", ErrRoomNotFound, roomId)
+	}
+	return json.Marshal(room.Snapshot())
+}
+
+func (Snapshots) Restore(snapshot []byte) (string, error) {
+	var s Snapshot
+	if err := json.Unmarshal(snapshot, &s); err != nil {
+		return "", err
+	}
+	RestoreRoom(&s)
+	return s.Id, nil
+}
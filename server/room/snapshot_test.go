@@ -0,0 +1,46 @@
+package room
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotIsACopy(t *testing.T) {
+	r := NewRoom()
+	r.AddPlayerToRoom("s1", "Aroha")
+	defer RemovePlayerIdToRoomIdMapping("s1")
+	r.ToggleUsedLetter("A")
+
+	snapshot := r.Snapshot()
+	r.AddPlayerToRoom("s2", "Mere")
+	defer RemovePlayerIdToRoomIdMapping("s2")
+	r.ToggleUsedLetter("B")
+	r.apply(EventPlayerEliminated, "s1", nil)
+
+	if len(snapshot.Players) != 1 || len(snapshot.PlayerOrder) != 1 || len(snapshot.UsedLetters) != 1 {
+		t.Fatalf("snapshot = %+v, want the room as it was when it was taken", snapshot)
+	}
+	if snapshot.Players["s1"].Eliminated {
+		t.Fatal("the snapshot shares its players with the room")
+	}
+}
+
+func TestSnapshotWhilePlaying(t *testing.T) {
+	r := NewRoom()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, letter := range strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ", "") {
+			r.ToggleUsedLetter(letter)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := json.Marshal(r.Snapshot()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
@@ -1,8 +1,11 @@
 package room
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -16,6 +19,11 @@ type Timer struct {
 	tickCh    TickChannel
 	doneCh    DoneChannel
 	wg        sync.WaitGroup
+	mu        sync.Mutex
+	paused    bool
+	remaining int
+	turnCtx   context.Context
+	turnSpan  trace.Span
 }
 
 func NewTimer() *Timer {
@@ -23,41 +31,66 @@ func NewTimer() *Timer {
 	doneCh := make(chan bool)
 	return &Timer{
 		started:   false,
-		timeLimit: DefaultTimerDuration,
+		timeLimit: settings.TimerDuration,
 		tickCh:    tickCh,
 		doneCh:    doneCh,
 		wg:        sync.WaitGroup{},
 	}
 }
 
+// reset stops the countdown and the goroutine emitting its ticks, by
+// closing the channels they share. It may be called more than once.
 func (t *Timer) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.started = false
-	t.doneCh <- true
+	t.paused = false
+	if t.doneCh != nil {
+		close(t.doneCh)
+		t.doneCh = nil
+	}
 }
 
 func (t *Timer) start(emitTick func(chan int, chan bool), onTimerExpiry func()) {
+	// Each countdown gets its own channels, so that one that was reset
+	// cannot tick into the next.
+	tickCh, doneCh := make(TickChannel), make(DoneChannel)
+	t.mu.Lock()
+	t.tickCh, t.doneCh = tickCh, doneCh
+	t.mu.Unlock()
 	t.wg.Add(1)
 
 	// go routine to start the timer
-	go t.doStart(onTimerExpiry)
+	go t.doStart(tickCh, doneCh, onTimerExpiry)
 
 	// go routine to receive the countdown ticks from the tickCh
 	// and emit it back to the room
-	go emitTick(t.tickCh, t.doneCh)
+	go emitTick(tickCh, doneCh)
 
 	t.wg.Wait()
 }
 
-func (t *Timer) doStart(onTimerExpiry func()) {
+func (t *Timer) doStart(tickCh TickChannel, doneCh DoneChannel, onTimerExpiry func()) {
 	t.started = true
 	baseTime := t.timeLimit
 	wg := t.getWaitGroup()
 	for {
 		select {
-		case <-t.doneCh:
+		case <-doneCh:
+			wg.Done()
 			return
 		default:
-			t.tickCh <- baseTime
+			if t.isPaused() {
+				time.Sleep(time.Second * 1)
+				continue
+			}
+			t.setRemaining(baseTime)
+			select {
+			case tickCh <- baseTime:
+			case <-doneCh:
+				wg.Done()
+				return
+			}
 			if baseTime == 0 {
 				wg.Done()
 				onTimerExpiry()
@@ -69,6 +102,60 @@ func (t *Timer) doStart(onTimerExpiry func()) {
 	}
 }
 
+// pause stops the countdown where it is until resume is called. A paused
+// timer can still be reset.
+func (t *Timer) pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = true
+}
+
+func (t *Timer) resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = false
+}
+
+func (t *Timer) isPaused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+func (t *Timer) setRemaining(seconds int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remaining = seconds
+}
+
+func (t *Timer) getRemaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.remaining
+}
+
+func (t *Timer) setSpan(ctx context.Context, span trace.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.turnCtx, t.turnSpan = ctx, span
+}
+
+// takeSpan returns the turn's span and forgets it, so that it is only ended
+// once.
+func (t *Timer) takeSpan() (context.Context, trace.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ctx, span := t.turnCtx, t.turnSpan
+	t.turnCtx, t.turnSpan = nil, nil
+	return ctx, span
+}
+
+func (t *Timer) currentSpan() (context.Context, trace.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.turnCtx, t.turnSpan
+}
+
 func (t *Timer) getTickCh() chan int {
 	return t.tickCh
 }
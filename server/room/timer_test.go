@@ -0,0 +1,46 @@
+package room
+
+import (
+	"testing"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+func TestResetStopsPausedTimer(t *testing.T) {
+	r := NewRoom()
+	ticking, stopped, returned := make(chan struct{}, 1), make(chan struct{}), make(chan struct{})
+	emitTick := func(tickChan chan int, doneCh chan bool) {
+		for {
+			select {
+			case <-doneCh:
+				close(stopped)
+				return
+			case <-tickChan:
+				select {
+				case ticking <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+	go func() {
+		r.StartTimer(emitTick, func(types.EventType, any) {})
+		close(returned)
+	}()
+	<-ticking
+
+	r.PauseTimer()
+	r.ResetTimer()
+
+	for name, done := range map[string]chan struct{}{"emitting ticks": stopped, "counting down": returned} {
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("the timer was still %s after one reset", name)
+		}
+	}
+	if state := r.TimerState(); state.Running || state.Paused {
+		t.Fatalf("timer = %+v, want the next turn's timer to start afresh", state)
+	}
+}
@@ -0,0 +1,71 @@
+package room
+
+import (
+	"context"
+
+	"github.com/campbell-rehu/quik-be/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span names for room state transitions.
+const (
+	spanRoomCreated      = "room.created"
+	spanRoomRemoved      = "room.removed"
+	spanRoomEnded        = "room.ended"
+	spanRoomLocked       = "room.locked"
+	spanRoomUnlocked     = "room.unlocked"
+	spanPlayerJoined     = "room.player_joined"
+	spanPlayerLeft       = "room.player_left"
+	spanPlayerKicked     = "room.player_kicked"
+	spanTurn             = "room.turn"
+	spanTimerExpired     = "room.timer_expired"
+	spanPlayerEliminated = "room.player_eliminated"
+	spanRoundEnded       = "room.round_ended"
+	spanGameEnded        = "room.game_ended"
+	spanAnswerChallenged = "room.answer_challenged"
+)
+
+// keyTurnOutcome says whether a turn's timer was reset or ran out.
+const keyTurnOutcome = attribute.Key("quik.turn.outcome")
+
+// traceTransition records a change in the room's state as a span, a child
+// of ctx when ctx carries one.
+func (r *Room) traceTransition(ctx context.Context, name string, attrs ...attribute.KeyValue) {
+	_, span := tracing.Tracer().Start(ctx, name,
+		trace.WithAttributes(append(attrs, tracing.KeyRoomId.String(r.Id))...),
+	)
+	span.End()
+}
+
+// startTurnSpan opens the span covering a turn's countdown. It is ended by
+// endTurnSpan when the timer is reset or runs out.
+func (r *Room) startTurnSpan() {
+	attrs := []attribute.KeyValue{
+		tracing.KeyRoomId.String(r.Id),
+		attribute.Int("quik.turn.time_limit", r.timer.timeLimit),
+	}
+	if len(r.playerOrder) > 0 {
+		attrs = append(attrs, tracing.KeyPlayerId.String(r.playerOrder[r.currentPlayerIndex]))
+	}
+	ctx, span := tracing.Tracer().Start(context.Background(), spanTurn, trace.WithAttributes(attrs...))
+	r.timer.setSpan(ctx, span)
+}
+
+// endTurnSpan ends the current turn's span, returning its context so that
+// what happens next can be recorded under it.
+func (r *Room) endTurnSpan(outcome string) context.Context {
+	ctx, span := r.timer.takeSpan()
+	if span == nil {
+		return context.Background()
+	}
+	span.SetAttributes(keyTurnOutcome.String(outcome))
+	span.End()
+	return ctx
+}
+
+func (r *Room) addTurnEvent(name string) {
+	if _, span := r.timer.currentSpan(); span != nil {
+		span.AddEvent(name)
+	}
+}
@@ -0,0 +1,103 @@
+package socket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"github.com/campbell-rehu/quik-be/cluster"
+	"github.com/campbell-rehu/quik-be/logging"
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/zishang520/socket.io/socket"
+)
+
+// Commands recorded in a room's log for operator actions.
+const (
+	commandEndRoom    = "admin-end-room"
+	commandKickPlayer = "admin-kick-player"
+)
+
+// EndRoom ends a room on behalf of an operator, telling its clients why
+// before removing them from it.
+func (s *Socket) EndRoom(roomId, reason string) error {
+	if room, err := roomPkg.GetRoom(roomId); err == nil {
+		room.RecordCommand(commandEndRoom, "", &types.RoomClosedPayload{RoomId: roomId, Reason: reason})
+	}
+	if err := roomPkg.EndRoom(roomId); err != nil {
+		return err
+	}
+	s.forgetBots(roomId)
+	s.broadcastToRoom(roomId, "", types.EventTypeRoomClosed, &types.RoomClosedPayload{RoomId: roomId, Reason: reason})
+	for _, room := range clientRooms(roomId) {
+		s.publishClose(room)
+	}
+	return nil
+}
+
+// KickPlayer removes a player from a room on behalf of an operator. The
+// player's connection stays open so they can join another room.
+func (s *Socket) KickPlayer(roomId, playerId, reason string) error {
+	room, err := roomPkg.GetRoom(roomId)
+	if err != nil {
+		return err
+	}
+	room.RecordCommand(commandKickPlayer, playerId, &types.PlayerKickedPayload{PlayerId: playerId, Reason: reason})
+	if err := room.KickPlayer(playerId); err != nil {
+		return err
+	}
+	s.broadcastToRoom(roomId, "", types.EventTypePlayerKicked, &types.PlayerKickedPayload{PlayerId: playerId, Reason: reason})
+	for _, r := range clientRooms(roomId) {
+		s.publishMembership(cluster.BroadcastOpLeave, socket.SocketId(playerId), r)
+	}
+	if room.GetPlayerCount() == 0 {
+		room.StopTimer()
+		roomPkg.RemoveRoom(roomId)
+	}
+	return nil
+}
+
+// BroadcastSystemMessage sends a message to a room, or to every room in the
+// cluster when roomId is empty, returning the rooms it was sent to.
+func (s *Socket) BroadcastSystemMessage(roomId, message string) []string {
+	roomIds := []string{roomId}
+	if roomId == "" {
+		roomIds = roomPkg.RoomIds()
+		stored, err := s.node.Adapter().RoomIds()
+		if err != nil {
+			slog.Error("unable to list stored rooms, only messaging local rooms", logging.Err(err))
+		}
+		for _, id := range stored {
+			if !slices.Contains(roomIds, id) {
+				roomIds = append(roomIds, id)
+			}
+		}
+		slices.Sort(roomIds)
+	}
+	for _, id := range roomIds {
+		s.broadcastToRoom(id, "", types.EventTypeSystemMessage, &types.SystemMessagePayload{Message: message})
+	}
+	return roomIds
+}
+
+// clientRooms lists the room names clients join for a room, the plain one
+// and one per protocol version.
+func clientRooms(roomId string) []socket.Room {
+	rooms := []socket.Room{socket.Room(roomId)}
+	for _, p := range protocols {
+		rooms = append(rooms, versionedRoom(roomId, p.version))
+	}
+	return rooms
+}
+
+func (s *Socket) publishClose(room socket.Room) {
+	b, err := json.Marshal(&cluster.Broadcast{Op: cluster.BroadcastOpClose, Room: string(room)})
+	if err != nil {
+		slog.Error("unable to encode room close", logging.Err(err))
+		return
+	}
+	if err := s.node.Adapter().Publish(cluster.BroadcastChannel, b); err != nil {
+		slog.Error("unable to publish room close, closing it locally", logging.KeyRoomId, room, logging.Err(err))
+		s.deliver(b)
+	}
+}
@@ -0,0 +1,239 @@
+package socket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/bot"
+	"github.com/campbell-rehu/quik-be/logging"
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/campbell-rehu/quik-be/words"
+	"github.com/zishang520/socket.io/socket"
+)
+
+// botClient is a bot seated in a room on this node. It sends its moves
+// through the same handlers as connected clients, and reads the room
+// rather than the events emitted to it. Bots are not handed over with their
+// room, so a bot in a room adopted by another node runs out of time.
+type botClient struct {
+	bot    *bot.Bot
+	roomId string
+	// turn counts the turns the bot has been given, so that a move
+	// planned for a turn that has since ended is dropped.
+	turn int
+}
+
+func (c *botClient) Id() socket.SocketId {
+	return socket.SocketId(c.bot.Id)
+}
+
+func (c *botClient) RemoteAddress() string {
+	return "bot"
+}
+
+func (c *botClient) Join(rooms ...socket.Room) {}
+
+func (c *botClient) Leave(room socket.Room) {}
+
+func (c *botClient) JoinedRooms() []socket.Room {
+	return []socket.Room{}
+}
+
+func (c *botClient) Emit(event string, args ...any) error {
+	return nil
+}
+
+func (c *botClient) Close() {}
+
+// botPlayers are the bots playing on this node.
+type botPlayers struct {
+	mu         sync.Mutex
+	levels     map[string]bot.Level
+	words      *words.List
+	difficulty string
+	clients    map[string]*botClient
+}
+
+// SetBots enables bot players. They play at one of levels, difficulty
+// unless asked otherwise, and answer with words from list.
+func (s *Socket) SetBots(levels map[string]bot.Level, difficulty string, list *words.List) {
+	s.bots = &botPlayers{levels: levels, words: list, difficulty: difficulty, clients: make(map[string]*botClient)}
+}
+
+// AddBot seats a new bot in a room, playing at difficulty or the default
+// difficulty when it is empty.
+func (s *Socket) AddBot(roomId, difficulty string) (*types.Player, error) {
+	if s.bots == nil {
+		return nil, bot.ErrDisabled
+	}
+	room, err := roomPkg.GetRoom(roomId)
+	if err != nil {
+		return nil, err
+	}
+	if difficulty == "" {
+		difficulty = s.bots.difficulty
+	}
+	b, err := bot.New(difficulty, s.bots.levels, s.bots.words)
+	if err != nil {
+		return nil, err
+	}
+	if err := room.AddBotToRoom(b.Id, b.Name); err != nil {
+		return nil, err
+	}
+	s.bots.mu.Lock()
+	s.bots.clients[b.Id] = &botClient{bot: b, roomId: room.Id}
+	s.bots.mu.Unlock()
+	slog.Info("bot joined room", logging.KeyRoomId, room.Id, logging.KeyPlayerId, b.Id, "difficulty", difficulty)
+
+	s.broadcastToRoom(room.Id, "", types.EventTypeRoomJoined, &types.RoomJoinedPayload{
+		Players:       room.Players,
+		UsedLetters:   room.UsedLetters,
+		CurrentPlayer: room.GetCurrentPlayer(),
+		PlayerCount:   room.GetPlayerCount(),
+	})
+	return room.Players[b.Id], nil
+}
+
+// FillRoom seats bots in a room until it has seats more players.
+func (s *Socket) FillRoom(room *roomPkg.Room, seats int) {
+	for i := 0; i < seats; i++ {
+		if _, err := s.AddBot(room.Id, ""); err != nil {
+			slog.Error("unable to fill room with a bot", logging.KeyRoomId, room.Id, logging.Err(err))
+			return
+		}
+	}
+}
+
+// playBotTurn plays the turn starting in the room when it is a bot's. The
+// bot chooses its letter, ends its turn and starts the next player's timer
+// just as a client would.
+func (s *Socket) playBotTurn(room *roomPkg.Room) {
+	if s.bots == nil || room.GetPlayerCount() == 0 {
+		return
+	}
+	player := room.GetCurrentPlayer()
+	if player == nil || !player.Bot || player.Eliminated {
+		return
+	}
+	s.bots.mu.Lock()
+	client, ok := s.bots.clients[player.Id]
+	turn := 0
+	if ok {
+		client.turn++
+		turn = client.turn
+	}
+	s.bots.mu.Unlock()
+	if !ok {
+		return
+	}
+	move := client.bot.Move(room.Category(), freeLetters(room))
+	log := slog.With(logging.KeyRoomId, room.Id, logging.KeyPlayerId, player.Id)
+	if move.Failed {
+		log.Debug("bot has no answer this turn")
+		return
+	}
+	log.Debug("bot is answering", "word", move.Word, "delay", move.Delay)
+	var answer func()
+	answer = func() {
+		s.bots.mu.Lock()
+		current := client.turn == turn
+		s.bots.mu.Unlock()
+		if !current || !roomPkg.HasRoom(room.Id) || !room.TimerState().Running || room.GetPlayerCount() == 0 {
+			return
+		}
+		if room.GetCurrentPlayer().Id != player.Id {
+			return
+		}
+		// The timer is paused while an answer is voted on, so the bot
+		// waits for the vote to end.
+		if room.ChallengeInProgress() {
+			time.AfterFunc(time.Second, answer)
+			return
+		}
+		s.dispatch(client, types.EventTypeSelectLetter, &types.SelectLetterPayload{RoomId: room.Id, Letter: move.Letter})
+		s.dispatch(client, types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: room.Id, SelectedLetter: move.Letter, Answer: move.Word})
+		s.dispatch(client, types.EventTypeResetTimer, &types.RoomIdPayload{RoomId: room.Id})
+	}
+	time.AfterFunc(move.Delay, answer)
+}
+
+// voteBots has the bots asked to vote on a challenged answer keep it when
+// they know it.
+func (s *Socket) voteBots(room *roomPkg.Room, challenge *types.ChallengeStartedPayload) {
+	if s.bots == nil {
+		return
+	}
+	for _, voter := range challenge.Voters {
+		s.bots.mu.Lock()
+		client, ok := s.bots.clients[voter]
+		s.bots.mu.Unlock()
+		if !ok {
+			continue
+		}
+		delay, known := client.bot.Vouch(room.Category(), challenge.Answer.Answer)
+		if !known {
+			continue
+		}
+		time.AfterFunc(delay, func() {
+			if room.ChallengeInProgress() {
+				s.dispatch(client, types.EventTypeCastVote, &types.CastVotePayload{RoomId: room.Id, Keep: true})
+			}
+		})
+	}
+}
+
+// dismissBots ends a room once only bots are left in it.
+func (s *Socket) dismissBots(room *roomPkg.Room) {
+	if s.bots == nil || room.GetPlayerCount() == 0 {
+		return
+	}
+	for _, player := range room.Players {
+		if !player.Bot {
+			return
+		}
+	}
+	slog.Info("only bots are left, ending room", logging.KeyRoomId, room.Id)
+	s.forgetBots(room.Id)
+	if err := roomPkg.EndRoom(room.Id); err != nil {
+		slog.Warn("unable to end room", logging.KeyRoomId, room.Id, logging.Err(err))
+	}
+}
+
+// forgetBots stops playing the bots in a room.
+func (s *Socket) forgetBots(roomId string) {
+	if s.bots == nil {
+		return
+	}
+	s.bots.mu.Lock()
+	defer s.bots.mu.Unlock()
+	for id, client := range s.bots.clients {
+		if client.roomId == roomId {
+			delete(s.bots.clients, id)
+		}
+	}
+}
+
+// dispatch sends an event from a bot as if a client had sent it.
+func (s *Socket) dispatch(client Client, eventType types.EventType, payload any) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("unable to encode bot event", logging.KeyEvent, eventType, logging.Err(err))
+		return
+	}
+	s.routeEvent(client, eventType, s.eventHandlers[eventType](client))(string(b))
+}
+
+// freeLetters are the letters of the room's letter set that have not been
+// used this round.
+func freeLetters(room *roomPkg.Room) []string {
+	free := []string{}
+	for _, letter := range room.Letters() {
+		if selectable, ok := room.UsedLetters[letter]; !ok || selectable {
+			free = append(free, letter)
+		}
+	}
+	return free
+}
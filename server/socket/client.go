@@ -0,0 +1,34 @@
+package socket
+
+import (
+	"github.com/zishang520/socket.io/socket"
+)
+
+// Client is a connected player on either transport. Event handlers only
+// talk to clients through this interface so that socket.io and native
+// WebSocket connections drive the same rooms.
+type Client interface {
+	Id() socket.SocketId
+	RemoteAddress() string
+	Join(rooms ...socket.Room)
+	Leave(room socket.Room)
+	JoinedRooms() []socket.Room
+	Emit(event string, args ...any) error
+	Close()
+}
+
+type socketIOClient struct {
+	*socket.Socket
+}
+
+func (c *socketIOClient) RemoteAddress() string {
+	return c.Client().Conn().RemoteAddress()
+}
+
+func (c *socketIOClient) JoinedRooms() []socket.Room {
+	return c.Rooms().Keys()
+}
+
+func (c *socketIOClient) Close() {
+	c.Disconnect(true)
+}
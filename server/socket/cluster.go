@@ -0,0 +1,250 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/cluster"
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/metrics"
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/tracing"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/zishang520/socket.io/socket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// remoteClient is a client connected to another node whose event is being
+// handled by this node because it owns the client's room. Everything sent
+// to it travels back through the cluster adapter.
+type remoteClient struct {
+	s             *Socket
+	id            socket.SocketId
+	remoteAddress string
+}
+
+func (c *remoteClient) Id() socket.SocketId {
+	return c.id
+}
+
+func (c *remoteClient) RemoteAddress() string {
+	return c.remoteAddress
+}
+
+func (c *remoteClient) Join(rooms ...socket.Room) {
+	for _, room := range rooms {
+		c.s.publishMembership(cluster.BroadcastOpJoin, c.id, room)
+	}
+}
+
+func (c *remoteClient) Leave(room socket.Room) {
+	c.s.publishMembership(cluster.BroadcastOpLeave, c.id, room)
+}
+
+func (c *remoteClient) JoinedRooms() []socket.Room {
+	return []socket.Room{}
+}
+
+func (c *remoteClient) Emit(event string, args ...any) error {
+	var message any
+	if len(args) > 0 {
+		message = args[0]
+	}
+	c.s.broadcast(socket.Room(c.id), "", event, message)
+	return nil
+}
+
+func (c *remoteClient) Close() {
+	slog.Warn("cannot close a client connected to another node", logging.KeyPlayerId, c.id)
+}
+
+// bindHandlers creates the event handlers for a newly connected client,
+// routing events for rooms owned by other nodes to those nodes.
+func (s *Socket) bindHandlers(client Client) map[types.EventType]WSDoer {
+	handlers := make(map[types.EventType]WSDoer)
+	for eventType, f := range s.eventHandlers {
+		handlers[eventType] = s.routeEvent(client, eventType, f(client))
+	}
+	return handlers
+}
+
+func (s *Socket) routeEvent(client Client, eventType types.EventType, handle WSDoer) WSDoer {
+	return func(data ...any) {
+		metrics.EventsReceived.WithLabelValues(string(eventType)).Inc()
+		if !s.allowEvent(client, eventType) {
+			return
+		}
+		if eventType == types.EventTypeDisconnect && roomPkg.GetRoomId(string(client.Id())) == "" {
+			// The player's room may live on another node.
+			s.forwardEvent(client, eventType, data, nil)
+		}
+		roomId := roomIdOf(eventType, data)
+		if roomId != "" && !roomPkg.HasRoom(roomId) {
+			if owner, ok := s.node.RemoteOwner(roomId); ok {
+				s.forwardEvent(client, eventType, data, &owner)
+				return
+			}
+		}
+		handleTimed(client, eventType, handle, data)
+	}
+}
+
+// allowEvent checks an event against the client's limits, telling the
+// client when it is dropped and disconnecting clients that keep flooding.
+// Disconnects are always let through so that clients are cleaned up.
+func (s *Socket) allowEvent(client Client, eventType types.EventType) bool {
+	if s.limits == nil || eventType == types.EventTypeDisconnect {
+		return true
+	}
+	allowed, disconnect := s.limits.Allow(string(client.Id()), string(eventType))
+	if allowed {
+		return true
+	}
+	metrics.RateLimited.WithLabelValues("event", string(eventType)).Inc()
+	metrics.EventsEmitted.WithLabelValues(string(types.EventTypeRateLimited)).Inc()
+	client.Emit(string(types.EventTypeRateLimited), &types.RateLimitedPayload{Event: string(eventType), Disconnected: disconnect})
+	if disconnect {
+		metrics.RateLimitDisconnects.Inc()
+		eventLogger(client, eventType).Warn("disconnecting client for flooding events")
+		client.Close()
+	}
+	return false
+}
+
+// handleTimed runs an event handler, recording how long it took and
+// tracing it as a span tagged with the client and the room the event is
+// about.
+func handleTimed(client Client, eventType types.EventType, handle WSDoer, data []any) {
+	attrs := []attribute.KeyValue{
+		tracing.KeyEvent.String(string(eventType)),
+		tracing.KeyPlayerId.String(string(client.Id())),
+	}
+	roomId := roomIdOf(eventType, data)
+	if eventType == types.EventTypeDisconnect {
+		roomId = roomPkg.GetRoomId(string(client.Id()))
+	}
+	if roomId != "" {
+		attrs = append(attrs, tracing.KeyRoomId.String(roomId))
+		recordCommand(roomId, client, eventType, data)
+	}
+	_, span := tracing.Tracer().Start(context.Background(), "socket "+string(eventType),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	start := time.Now()
+	handle(data...)
+	metrics.HandlerDuration.WithLabelValues(string(eventType)).Observe(time.Since(start).Seconds())
+}
+
+// recordCommand adds an event received from a client to its room's log.
+func recordCommand(roomId string, client Client, eventType types.EventType, data []any) {
+	room, err := roomPkg.GetRoom(roomId)
+	if err != nil {
+		return
+	}
+	var payload any
+	if len(data) > 0 {
+		payload = data[0]
+	}
+	room.RecordCommand(string(eventType), string(client.Id()), payload)
+}
+
+// forwardEvent publishes the event to its room's owner, or to every node
+// when the owner is nil.
+func (s *Socket) forwardEvent(client Client, eventType types.EventType, data []any, owner *cluster.NodeInfo) {
+	log := eventLogger(client, eventType)
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Error("unable to encode forwarded event", logging.Err(err))
+		return
+	}
+	event := &cluster.ForwardedEvent{
+		ClientId:        string(client.Id()),
+		RemoteAddress:   client.RemoteAddress(),
+		ProtocolVersion: int(s.session(client.Id()).protocol.version),
+		Event:           string(eventType),
+		Data:            raw,
+	}
+	if owner == nil {
+		err = s.node.BroadcastEvent(event)
+	} else {
+		log.Debug("forwarding event to room owner", "owner", owner.Id)
+		err = s.node.ForwardEvent(*owner, event)
+	}
+	if err != nil {
+		log.Error("unable to forward event", logging.Err(err))
+	}
+}
+
+// handleForwardedEvent runs an event another node received for a room this
+// node owns.
+func (s *Socket) handleForwardedEvent(b []byte) {
+	var event cluster.ForwardedEvent
+	if err := json.Unmarshal(b, &event); err != nil {
+		slog.Error("unable to decode forwarded event", logging.Err(err))
+		return
+	}
+	eventType := types.EventType(event.Event)
+	if eventType == types.EventTypeDisconnect && roomPkg.GetRoomId(event.ClientId) == "" {
+		return
+	}
+	f, ok := s.eventHandlers[eventType]
+	if !ok {
+		return
+	}
+	var data []any
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		slog.Error("unable to decode forwarded event data", logging.KeyEvent, event.Event, logging.Err(err))
+		return
+	}
+
+	clientId := socket.SocketId(event.ClientId)
+	if _, ok := s.sessions.Load(clientId); !ok && eventType != types.EventTypeDisconnect {
+		if sess, err := negotiate(&types.HelloPayload{ProtocolVersion: event.ProtocolVersion}); err == nil {
+			s.sessions.Store(clientId, sess)
+		}
+	}
+	client := &remoteClient{s: s, id: clientId, remoteAddress: event.RemoteAddress}
+	handleTimed(client, eventType, f(client), data)
+}
+
+// EvictRoom stops running a room whose lease another node took. Its
+// players stay in its socket rooms, so they hear from the node that runs
+// it now.
+func (s *Socket) EvictRoom(roomId string) {
+	s.forgetBots(roomId)
+	roomPkg.EvictRoom(roomId)
+}
+
+func (s *Socket) publishMembership(op string, clientId socket.SocketId, room socket.Room) {
+	b, err := json.Marshal(&cluster.Broadcast{Op: op, Room: string(clientId), Event: string(room)})
+	if err != nil {
+		slog.Error("unable to encode room membership", logging.Err(err))
+		return
+	}
+	if err := s.node.Adapter().Publish(cluster.BroadcastChannel, b); err != nil {
+		slog.Error("unable to publish room membership", logging.KeyPlayerId, clientId, logging.Err(err))
+	}
+}
+
+// roomIdOf finds the room an event is about, or "" if it is not about one.
+func roomIdOf(eventType types.EventType, data []any) string {
+	if len(data) == 0 {
+		return ""
+	}
+	if eventType == types.EventTypeJoinRoom {
+		if roomId, ok := data[0].(string); ok {
+			return roomId
+		}
+	}
+	var t types.RoomIdPayload
+	if err := decodePayload(data, &t); err != nil {
+		return ""
+	}
+	return t.RoomId
+}
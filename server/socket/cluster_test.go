@@ -0,0 +1,84 @@
+package socket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/cluster"
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+func TestForwardedEventDoesNotBlock(t *testing.T) {
+	s := newTestSocket(t)
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer roomPkg.EndRoom(room.Id)
+	s.node.Own(room.Id)
+	room.AddPlayerToRoom("p1", "Aroha")
+	room.AddPlayerToRoom("p2", "Mere")
+
+	ticking := make(chan struct{}, 1)
+	unsubscribe, _ := s.node.Adapter().Subscribe(cluster.BroadcastChannel, func(b []byte) {
+		var msg cluster.Broadcast
+		if json.Unmarshal(b, &msg) == nil && msg.Event == string(types.EventTypeCountdownTick) {
+			select {
+			case ticking <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	data, _ := json.Marshal([]any{map[string]any{"roomId": room.Id}})
+	b, _ := json.Marshal(&cluster.ForwardedEvent{ClientId: "p1", Event: string(types.EventTypeCountdownStarted), Data: data})
+	done := make(chan struct{})
+	go func() {
+		s.handleForwardedEvent(b)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handling a forwarded countdown-started blocked until the turn ended")
+	}
+	<-ticking
+}
+
+func TestEvictRoom(t *testing.T) {
+	s := newTestSocket(t)
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	room.AddPlayerToRoom("e1", "Aroha")
+	room.AddPlayerToRoom("e2", "Mere")
+	ticking := make(chan struct{})
+	emitTick := func(tickChan chan int, doneCh chan bool) {
+		for {
+			select {
+			case <-doneCh:
+				return
+			case <-tickChan:
+				select {
+				case ticking <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+	go room.StartTimer(emitTick, func(types.EventType, any) {})
+	<-ticking
+
+	s.EvictRoom(room.Id)
+
+	if roomPkg.HasRoom(room.Id) || roomPkg.GetRoomId("e1") != "" {
+		t.Fatal("the room is still held after it was evicted")
+	}
+	if room.TimerState().Running {
+		t.Fatal("the evicted room's timer is still running")
+	}
+}
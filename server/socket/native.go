@@ -0,0 +1,363 @@
+package socket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/metrics"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/gorilla/websocket"
+	"github.com/zishang520/socket.io/socket"
+)
+
+const (
+	nativeWriteWait  = 10 * time.Second
+	nativePongWait   = 60 * time.Second
+	nativePingPeriod = nativePongWait * 9 / 10
+	nativeSendBuffer = 64
+	nativeMaxMessage = 1 << 16
+)
+
+var errClientClosed = errors.New("client connection is closed")
+
+// nativeHub tracks room membership for native WebSocket clients, which the
+// socket.io server knows nothing about.
+type nativeHub struct {
+	mu      sync.RWMutex
+	clients map[socket.SocketId]*nativeClient
+	rooms   map[socket.Room]map[socket.SocketId]*nativeClient
+}
+
+func newNativeHub() *nativeHub {
+	return &nativeHub{
+		clients: make(map[socket.SocketId]*nativeClient),
+		rooms:   make(map[socket.Room]map[socket.SocketId]*nativeClient),
+	}
+}
+
+func (h *nativeHub) add(c *nativeClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c.id] = c
+}
+
+func (h *nativeHub) remove(c *nativeClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c.id)
+	for room, members := range h.rooms {
+		delete(members, c.id)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// closeAll closes every native client's connection.
+func (h *nativeHub) closeAll() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, c := range h.clients {
+		c.Close()
+	}
+}
+
+func (h *nativeHub) join(c *nativeClient, rooms ...socket.Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, room := range rooms {
+		if h.rooms[room] == nil {
+			h.rooms[room] = make(map[socket.SocketId]*nativeClient)
+		}
+		h.rooms[room][c.id] = c
+	}
+}
+
+func (h *nativeHub) leave(c *nativeClient, room socket.Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.rooms[room], c.id)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// closeRoom removes every native client from the room.
+func (h *nativeHub) closeRoom(room socket.Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.rooms, room)
+}
+
+// joinById and leaveById change the rooms of a client connected to this
+// node on behalf of the node handling its events.
+func (h *nativeHub) joinById(id socket.SocketId, room socket.Room) {
+	h.mu.RLock()
+	c, ok := h.clients[id]
+	h.mu.RUnlock()
+	if ok {
+		h.join(c, room)
+	}
+}
+
+func (h *nativeHub) leaveById(id socket.SocketId, room socket.Room) {
+	h.mu.RLock()
+	c, ok := h.clients[id]
+	h.mu.RUnlock()
+	if ok {
+		h.leave(c, room)
+	}
+}
+
+func (h *nativeHub) roomsOf(c *nativeClient) []socket.Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := []socket.Room{}
+	for room, members := range h.rooms {
+		if _, ok := members[c.id]; ok {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// broadcast sends an event to every native client in the room apart from
+// except. Every client is also reachable through a room named after its id.
+func (h *nativeHub) broadcast(room socket.Room, except socket.SocketId, event string, message any) {
+	h.mu.RLock()
+	recipients := []*nativeClient{}
+	if c, ok := h.clients[socket.SocketId(room)]; ok {
+		recipients = append(recipients, c)
+	}
+	for id, c := range h.rooms[room] {
+		if id != except {
+			recipients = append(recipients, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range recipients {
+		if err := c.Emit(event, message); err != nil {
+			slog.Warn("unable to emit to native client", logging.KeyPlayerId, c.id, logging.KeyEvent, event, logging.Err(err))
+		}
+	}
+}
+
+type nativeClient struct {
+	id      socket.SocketId
+	conn    *websocket.Conn
+	hub     *nativeHub
+	send    chan []byte
+	done    chan struct{}
+	closing sync.Once
+}
+
+func (c *nativeClient) Id() socket.SocketId {
+	return c.id
+}
+
+func (c *nativeClient) RemoteAddress() string {
+	return c.conn.RemoteAddr().String()
+}
+
+func (c *nativeClient) Join(rooms ...socket.Room) {
+	c.hub.join(c, rooms...)
+}
+
+func (c *nativeClient) Leave(room socket.Room) {
+	c.hub.leave(c, room)
+}
+
+func (c *nativeClient) JoinedRooms() []socket.Room {
+	return c.hub.roomsOf(c)
+}
+
+// Emit queues an event frame for the client. Only the first argument is
+// sent, as the payload of a types.Event.
+func (c *nativeClient) Emit(event string, args ...any) error {
+	frame := types.Event{Type: event}
+	if len(args) > 0 {
+		payload, err := json.Marshal(args[0])
+		if err != nil {
+			return err
+		}
+		frame.Payload = payload
+	}
+	message, err := json.Marshal(&frame)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-c.done:
+		return errClientClosed
+	case c.send <- message:
+		return nil
+	default:
+		slog.Warn("native client send buffer is full, closing connection", logging.KeyPlayerId, c.id)
+		c.Close()
+		return errClientClosed
+	}
+}
+
+func (c *nativeClient) Close() {
+	c.closing.Do(func() {
+		close(c.done)
+	})
+}
+
+// HandleNativeWS upgrades the request to a plain WebSocket connection that
+// exchanges types.Event JSON frames and drives the same handlers as
+// socket.io clients. The protocolVersion and sessionToken query parameters
+// stand in for the socket.io handshake auth object.
+func (s *Socket) HandleNativeWS(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.allowOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.WarnContext(r.Context(), "unable to upgrade native client connection", logging.Err(err))
+		return
+	}
+
+	client := &nativeClient{
+		id:   newNativeClientId(),
+		conn: conn,
+		hub:  s.nativeHub,
+		send: make(chan []byte, nativeSendBuffer),
+		done: make(chan struct{}),
+	}
+	s.nativeHub.add(client)
+	go client.writePump()
+
+	slog.InfoContext(r.Context(), "native client connected", logging.KeyPlayerId, client.id, "remote_address", client.RemoteAddress())
+
+	var hello *types.HelloPayload
+	version, token := r.URL.Query().Get("protocolVersion"), r.URL.Query().Get("sessionToken")
+	if version != "" || token != "" {
+		v, _ := strconv.Atoi(version)
+		hello = &types.HelloPayload{ProtocolVersion: v, SessionToken: token}
+	}
+	if !s.handshake(client, hello) {
+		client.Close()
+		s.nativeHub.remove(client)
+		return
+	}
+
+	handlers := s.bindHandlers(client)
+
+	metrics.SocketsActive.WithLabelValues("native").Inc()
+	client.readPump(handlers)
+	metrics.SocketsActive.WithLabelValues("native").Dec()
+
+	client.Close()
+	if disconnect, ok := handlers[types.EventTypeDisconnect]; ok {
+		disconnect()
+	}
+	s.nativeHub.remove(client)
+}
+
+func (c *nativeClient) readPump(handlers map[types.EventType]WSDoer) {
+	c.conn.SetReadLimit(nativeMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(nativePongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(nativePongWait))
+	})
+	for {
+		var event types.Event
+		if err := c.conn.ReadJSON(&event); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				slog.Warn("native client connection closed unexpectedly", logging.KeyPlayerId, c.id, logging.Err(err))
+			}
+			return
+		}
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		handler, ok := handlers[types.EventType(event.Type)]
+		if !ok || types.EventType(event.Type) == types.EventTypeDisconnect {
+			slog.Warn("native client sent an unknown event", logging.KeyPlayerId, c.id, logging.KeyEvent, event.Type)
+			continue
+		}
+		// Events are handled one at a time, in the order the client sent
+		// them.
+		handler(nativeEventData(event.Payload)...)
+	}
+}
+
+// nativeEventData converts a frame payload into the arguments socket.io
+// handlers receive: JSON strings are unwrapped, anything else is passed on
+// as raw JSON for decodePayload.
+func nativeEventData(payload json.RawMessage) []any {
+	if len(payload) == 0 || string(payload) == "null" {
+		return []any{}
+	}
+	var s string
+	if err := json.Unmarshal(payload, &s); err == nil {
+		return []any{s}
+	}
+	return []any{payload}
+}
+
+func (c *nativeClient) writePump() {
+	ticker := time.NewTicker(nativePingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case <-c.done:
+			c.flush()
+			c.conn.SetWriteDeadline(time.Now().Add(nativeWriteWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(nativeWriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(nativeWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+// flush writes any frames queued before the client was closed, such as a
+// protocol-error explaining why.
+func (c *nativeClient) flush() {
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(nativeWriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func newNativeClientId() socket.SocketId {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return socket.SocketId("ws-" + hex.EncodeToString(b))
+}
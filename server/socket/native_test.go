@@ -0,0 +1,271 @@
+package socket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/gorilla/websocket"
+)
+
+// dialNative connects a native client speaking ProtocolV2 and returns the
+// player id it was given.
+func dialNative(t *testing.T, url string) (*websocket.Conn, string) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"?protocolVersion=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var hello types.ServerHelloPayload
+	readNative(t, conn, types.EventTypeServerHello, &hello)
+	return conn, hello.PlayerId
+}
+
+func sendNative(t *testing.T, conn *websocket.Conn, eventType types.EventType, payload any) {
+	t.Helper()
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(&types.Event{Type: string(eventType), Payload: b}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readNative reads frames until one of the given type arrives and decodes
+// its payload into v.
+func readNative(t *testing.T, conn *websocket.Conn, eventType types.EventType, v any) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		var event types.Event
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("waiting for %s: %v", eventType, err)
+		}
+		if event.Type == string(eventType) {
+			if v != nil {
+				if err := json.Unmarshal(event.Payload, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return
+		}
+	}
+}
+
+func TestNativeClientPlaysTurn(t *testing.T) {
+	s := newTestSocket(t)
+	server := httptest.NewServer(http.HandlerFunc(s.HandleNativeWS))
+	defer server.Close()
+
+	conn, playerId := dialNative(t, server.URL)
+	if !strings.HasPrefix(playerId, "ws-") {
+		t.Fatalf("player id = %q, want a native client id", playerId)
+	}
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer roomPkg.EndRoom(room.Id)
+	s.node.Own(room.Id)
+	room.AddPlayerToRoom(playerId, "Aroha")
+	room.AddPlayerToRoom("other-player", "Mere")
+
+	sendNative(t, conn, types.EventTypeJoinRoom, &types.RoomIdPayload{RoomId: room.Id})
+	readNative(t, conn, types.EventTypeRoomJoined, nil)
+
+	// The turn's timer runs until the turn ends, so the client's end-turn
+	// is only read while it runs if starting it does not block the read
+	// loop.
+	sendNative(t, conn, types.EventTypeCountdownStarted, &types.RoomIdPayload{RoomId: room.Id})
+	readNative(t, conn, types.EventTypeRoundStarted, nil)
+	// ProtocolV2 renames tick.
+	readNative(t, conn, "countdown-tick", nil)
+	sendNative(t, conn, types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: room.Id, SelectedLetter: "A"})
+
+	var turn types.StartTurnPayload
+	readNative(t, conn, types.EventTypeStartTurn, &turn)
+	if turn.CurrentPlayer == nil || turn.CurrentPlayer.Id != "other-player" {
+		t.Fatalf("current player = %+v, want the next player", turn.CurrentPlayer)
+	}
+	if room.Players[playerId].Eliminated {
+		t.Fatal("the native player was eliminated despite ending their turn")
+	}
+}
+
+func TestNativeJoinRoomWithoutPayload(t *testing.T) {
+	s := newTestSocket(t)
+	server := httptest.NewServer(http.HandlerFunc(s.HandleNativeWS))
+	defer server.Close()
+
+	conn, _ := dialNative(t, server.URL)
+	if err := conn.WriteJSON(&types.Event{Type: string(types.EventTypeJoinRoom)}); err != nil {
+		t.Fatal(err)
+	}
+	// The server is still answering the client.
+	sendNative(t, conn, types.EventTypeHello, &types.HelloPayload{ProtocolVersion: 2})
+	readNative(t, conn, types.EventTypeServerHello, nil)
+}
+
+// startNativeRound seats n native clients in a new room and starts its
+// first round, returning the room and the clients' connections and player
+// ids in turn order.
+func startNativeRound(t *testing.T, n int, textMode bool) (*roomPkg.Room, []*websocket.Conn, []string) {
+	t.Helper()
+	s := newTestSocket(t)
+	server := httptest.NewServer(http.HandlerFunc(s.HandleNativeWS))
+	t.Cleanup(server.Close)
+
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { roomPkg.EndRoom(room.Id) })
+	s.node.Own(room.Id)
+	if err := room.SetTextMode(textMode); err != nil {
+		t.Fatal(err)
+	}
+	conns, playerIds := []*websocket.Conn{}, []string{}
+	for i := 0; i < n; i++ {
+		conn, playerId := dialNative(t, server.URL)
+		if err := room.AddPlayerToRoom(playerId, "Player"); err != nil {
+			t.Fatal(err)
+		}
+		sendNative(t, conn, types.EventTypeJoinRoom, &types.RoomIdPayload{RoomId: room.Id})
+		readNative(t, conn, types.EventTypeRoomJoined, nil)
+		conns, playerIds = append(conns, conn), append(playerIds, playerId)
+	}
+	sendNative(t, conns[0], types.EventTypeCountdownStarted, &types.RoomIdPayload{RoomId: room.Id})
+	for _, conn := range conns {
+		readNative(t, conn, types.EventTypeRoundStarted, nil)
+	}
+	readNative(t, conns[0], "countdown-tick", nil)
+	return room, conns, playerIds
+}
+
+func TestEndTurnOnlyByCurrentPlayer(t *testing.T) {
+	_, conns, playerIds := startNativeRound(t, 2, false)
+	roomId := roomPkg.GetRoomId(playerIds[0])
+
+	sendNative(t, conns[1], types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: "A"})
+	var refused types.TurnRefusedPayload
+	readNative(t, conns[1], types.EventTypeTurnRefused, &refused)
+	if refused.Reason == "" {
+		t.Fatal("turn-refused gave no reason")
+	}
+
+	sendNative(t, conns[1], types.EventTypeSelectLetter, &types.SelectLetterPayload{RoomId: roomId, Letter: "B"})
+	readNative(t, conns[1], types.EventTypeTurnRefused, nil)
+
+	sendNative(t, conns[0], types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: "A"})
+	var turn types.StartTurnPayload
+	readNative(t, conns[0], types.EventTypeStartTurn, &turn)
+	if turn.CurrentPlayer == nil || turn.CurrentPlayer.Id != playerIds[1] {
+		t.Fatalf("current player = %+v, want the second player", turn.CurrentPlayer)
+	}
+	if _, ok := turn.UsedLetters["B"]; ok {
+		t.Fatalf("used letters = %v, want B left for its turn", turn.UsedLetters)
+	}
+}
+
+func TestRejectedAnswerKeepsTimerRunning(t *testing.T) {
+	_, conns, playerIds := startNativeRound(t, 2, true)
+	roomId := roomPkg.GetRoomId(playerIds[0])
+
+	sendNative(t, conns[0], types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: "A", Answer: "Zebra"})
+	readNative(t, conns[0], types.EventTypeAnswerRejected, nil)
+	// The client resets the timer after ending its turn, which must not
+	// restart the turn that carries on.
+	sendNative(t, conns[0], types.EventTypeResetTimer, &types.RoomIdPayload{RoomId: roomId})
+	for i := 0; i < 2; i++ {
+		var tick types.CountdownTickPayload
+		readNative(t, conns[0], "countdown-tick", &tick)
+		if tick.Countdown >= roomPkg.DefaultTimerDuration {
+			t.Fatalf("countdown = %d, want the rejected player's turn to carry on", tick.Countdown)
+		}
+	}
+
+	sendNative(t, conns[0], types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: "A", Answer: "Aardvark"})
+	var turn types.StartTurnPayload
+	readNative(t, conns[0], types.EventTypeStartTurn, &turn)
+	if turn.CurrentPlayer == nil || turn.CurrentPlayer.Id != playerIds[1] || len(turn.Answers) != 1 {
+		t.Fatalf("start-turn = %+v, want the second player's turn after one answer", turn)
+	}
+}
+
+func TestTurnRefusedDuringVote(t *testing.T) {
+	_, conns, playerIds := startNativeRound(t, 3, true)
+	roomId := roomPkg.GetRoomId(playerIds[0])
+
+	sendNative(t, conns[0], types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: "A", Answer: "Aardvark"})
+	readNative(t, conns[1], types.EventTypeStartTurn, nil)
+	sendNative(t, conns[0], types.EventTypeResetTimer, &types.RoomIdPayload{RoomId: roomId})
+	readNative(t, conns[0], "countdown-tick", nil)
+	sendNative(t, conns[2], types.EventTypeChallengeAnswer, &types.RoomIdPayload{RoomId: roomId})
+	readNative(t, conns[1], types.EventTypeChallengeStarted, nil)
+
+	sendNative(t, conns[1], types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: "B", Answer: "Bear"})
+	var refused types.TurnRefusedPayload
+	readNative(t, conns[1], types.EventTypeTurnRefused, &refused)
+	if refused.Reason == "" {
+		t.Fatal("turn-refused gave no reason")
+	}
+	sendNative(t, conns[1], types.EventTypeResetTimer, &types.RoomIdPayload{RoomId: roomId})
+	readNative(t, conns[1], types.EventTypeTurnRefused, nil)
+}
+
+func TestNativeEventsHandledInOrder(t *testing.T) {
+	_, conns, playerIds := startNativeRound(t, 2, false)
+	roomId := roomPkg.GetRoomId(playerIds[0])
+
+	for i, letter := range []string{"A", "B", "C", "D"} {
+		conn := conns[i%2]
+		sendNative(t, conn, types.EventTypeSelectLetter, &types.SelectLetterPayload{RoomId: roomId, Letter: letter})
+		sendNative(t, conn, types.EventTypeEndTurn, &types.EndTurnPayload{RoomId: roomId, SelectedLetter: letter})
+		// Skip the start of the turn this client was given.
+		var turn types.StartTurnPayload
+		for turn.CurrentPlayer == nil || turn.CurrentPlayer.Id != playerIds[(i+1)%2] {
+			readNative(t, conn, types.EventTypeStartTurn, &turn)
+		}
+		if selectable, ok := turn.UsedLetters[letter]; !ok || selectable {
+			t.Fatalf("used letters = %v, want %s used before the turn ended", turn.UsedLetters, letter)
+		}
+		sendNative(t, conn, types.EventTypeResetTimer, &types.RoomIdPayload{RoomId: roomId})
+		readNative(t, conn, "countdown-tick", nil)
+	}
+}
+
+func TestDisconnectRemovesEmptyRoom(t *testing.T) {
+	removed := make(chan string, 1)
+	roomPkg.SetHooks(roomPkg.Hooks{OnRemove: func(roomId string) { removed <- roomId }})
+	defer roomPkg.SetHooks(roomPkg.Hooks{})
+	s := newTestSocket(t)
+	server := httptest.NewServer(http.HandlerFunc(s.HandleNativeWS))
+	defer server.Close()
+
+	conn, playerId := dialNative(t, server.URL)
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	room.AddPlayerToRoom(playerId, "Aroha")
+	sendNative(t, conn, types.EventTypeJoinRoom, &types.RoomIdPayload{RoomId: room.Id})
+	readNative(t, conn, types.EventTypeRoomJoined, nil)
+
+	conn.Close()
+	select {
+	case roomId := <-removed:
+		if roomId != room.Id || roomPkg.HasRoom(room.Id) {
+			t.Fatalf("removed room %q, want %q", roomId, room.Id)
+		}
+	case <-time.After(3 * time.Second):
+		roomPkg.EndRoom(room.Id)
+		t.Fatal("the room its last player disconnected from was not removed")
+	}
+}
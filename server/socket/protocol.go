@@ -0,0 +1,168 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/types"
+	"github.com/zishang520/socket.io/socket"
+)
+
+type ProtocolVersion int
+
+const (
+	// ProtocolV1 is the protocol spoken by clients that do not negotiate a
+	// version: JSON-encoded string payloads and the original event names.
+	ProtocolV1 ProtocolVersion = 1
+	// ProtocolV2 accepts object payloads, renames tick to countdown-tick and
+	// wraps the letter-selected payload in an object.
+	ProtocolV2 ProtocolVersion = 2
+
+	LatestProtocolVersion = ProtocolV2
+)
+
+const (
+	FeatureMatchmaking    = "matchmaking"
+	FeatureObjectPayloads = "object-payloads"
+)
+
+type protocol struct {
+	version       ProtocolVersion
+	features      []string
+	renamedEvents map[types.EventType]string
+	adaptPayload  func(eventType types.EventType, message any) any
+}
+
+var protocols = []*protocol{
+	{
+		version:  ProtocolV1,
+		features: []string{FeatureMatchmaking},
+	},
+	{
+		version:  ProtocolV2,
+		features: []string{FeatureMatchmaking, FeatureObjectPayloads},
+		renamedEvents: map[types.EventType]string{
+			types.EventTypeCountdownTick: "countdown-tick",
+		},
+		adaptPayload: func(eventType types.EventType, message any) any {
+			if usedLetters, ok := message.(types.UsedLettersPayload); ok && eventType == types.EventTypeLetterSelected {
+				return &types.LetterSelectedPayload{UsedLetters: usedLetters}
+			}
+			return message
+		},
+	},
+}
+
+func SupportedProtocolVersions() []ProtocolVersion {
+	versions := []ProtocolVersion{}
+	for _, p := range protocols {
+		versions = append(versions, p.version)
+	}
+	return versions
+}
+
+func getProtocol(version ProtocolVersion) (*protocol, bool) {
+	i := slices.IndexFunc(protocols, func(p *protocol) bool { return p.version == version })
+	if i == -1 {
+		return nil, false
+	}
+	return protocols[i], true
+}
+
+// adapt translates an event into the name and payload a client speaking
+// this protocol version expects.
+func (p *protocol) adapt(eventType types.EventType, message any) (string, any) {
+	name := string(eventType)
+	if renamed, ok := p.renamedEvents[eventType]; ok {
+		name = renamed
+	}
+	if p.adaptPayload != nil {
+		message = p.adaptPayload(eventType, message)
+	}
+	return name, message
+}
+
+// session is the protocol negotiated with a single client.
+type session struct {
+	protocol *protocol
+	features []string
+	// account is the account the client signed in to, nil for guests.
+	account *auth.Claims
+}
+
+func (s *session) accountId() string {
+	if s.account == nil {
+		return ""
+	}
+	return s.account.AccountId
+}
+
+func (s *session) hasFeature(feature string) bool {
+	return slices.Contains(s.features, feature)
+}
+
+var legacySession = &session{protocol: protocols[0], features: protocols[0].features}
+
+// negotiate picks the protocol for a client from the version and features it
+// declared. Clients that declare nothing are treated as ProtocolV1.
+func negotiate(hello *types.HelloPayload) (*session, error) {
+	if hello == nil || hello.ProtocolVersion == 0 {
+		return legacySession, nil
+	}
+	p, ok := getProtocol(ProtocolVersion(hello.ProtocolVersion))
+	if !ok {
+		return nil, fmt.Errorf("protocol version %d is not supported", hello.ProtocolVersion)
+	}
+	features := p.features
+	if len(hello.Features) > 0 {
+		features = []string{}
+		for _, feature := range p.features {
+			if slices.Contains(hello.Features, feature) {
+				features = append(features, feature)
+			}
+		}
+	}
+	return &session{protocol: p, features: features}, nil
+}
+
+// helloFromHandshake reads the protocol version and features a client sent
+// in the socket.io handshake auth object, if any.
+func helloFromHandshake(handshake *socket.Handshake) *types.HelloPayload {
+	if handshake == nil || handshake.Auth == nil {
+		return nil
+	}
+	var hello types.HelloPayload
+	if err := decodePayload([]any{handshake.Auth}, &hello); err != nil {
+		return nil
+	}
+	return &hello
+}
+
+// decodePayload decodes the first argument of a socket event into v.
+// ProtocolV1 clients send JSON-encoded strings, later versions may send
+// objects directly.
+func decodePayload(data []any, v any) error {
+	if len(data) == 0 {
+		return errors.New("missing event payload")
+	}
+	if raw, ok := data[0].(string); ok {
+		return json.Unmarshal([]byte(raw), v)
+	}
+	raw, err := json.Marshal(data[0])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func versionedRoom(roomId string, version ProtocolVersion) socket.Room {
+	return socket.Room(fmt.Sprintf("%s@v%d", roomId, version))
+}
+
+func roomIdFromVersionedRoom(room socket.Room, version ProtocolVersion) (string, bool) {
+	return strings.CutSuffix(string(room), fmt.Sprintf("@v%d", version))
+}
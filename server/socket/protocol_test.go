@@ -0,0 +1,120 @@
+package socket
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		hello    *types.HelloPayload
+		version  ProtocolVersion
+		features []string
+	}{
+		{"no hello", nil, ProtocolV1, []string{FeatureMatchmaking}},
+		{"no version", &types.HelloPayload{}, ProtocolV1, []string{FeatureMatchmaking}},
+		{"v1", &types.HelloPayload{ProtocolVersion: 1}, ProtocolV1, []string{FeatureMatchmaking}},
+		{"v2", &types.HelloPayload{ProtocolVersion: 2}, ProtocolV2, []string{FeatureMatchmaking, FeatureObjectPayloads}},
+		{"v2 with some features", &types.HelloPayload{ProtocolVersion: 2, Features: []string{FeatureObjectPayloads, "telepathy"}}, ProtocolV2, []string{FeatureObjectPayloads}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess, err := negotiate(tt.hello)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sess.protocol.version != tt.version || !reflect.DeepEqual(sess.features, tt.features) {
+				t.Fatalf("session = v%d %v, want v%d %v", sess.protocol.version, sess.features, tt.version, tt.features)
+			}
+		})
+	}
+	if _, err := negotiate(&types.HelloPayload{ProtocolVersion: 99}); err == nil {
+		t.Fatal("negotiated an unsupported version")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	s := newTestSocket(t)
+
+	client := &fakeClient{id: "v2-client"}
+	if !s.handshake(client, &types.HelloPayload{ProtocolVersion: 2}) {
+		t.Fatal("handshake refused a supported version")
+	}
+	var hello types.ServerHelloPayload
+	if !client.last(string(types.EventTypeServerHello), &hello) || hello.ProtocolVersion != 2 {
+		t.Fatalf("server-hello = %+v, want version 2", hello)
+	}
+	if hello.PlayerId != "v2-client" {
+		t.Fatalf("player id = %q, want the client's id", hello.PlayerId)
+	}
+
+	unsupported := &fakeClient{id: "v99-client"}
+	if s.handshake(unsupported, &types.HelloPayload{ProtocolVersion: 99}) {
+		t.Fatal("handshake accepted an unsupported version")
+	}
+	var protocolError types.ProtocolErrorPayload
+	if !unsupported.last(string(types.EventTypeProtocolError), &protocolError) {
+		t.Fatal("no protocol-error sent for an unsupported version")
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(protocolError.SupportedVersions, want) {
+		t.Fatalf("supported versions = %v, want %v", protocolError.SupportedVersions, want)
+	}
+	if _, ok := s.sessions.Load(unsupported.id); ok {
+		t.Fatal("kept a session for a refused client")
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	usedLetters := types.UsedLettersPayload{"A": true}
+	tests := []struct {
+		name    string
+		version ProtocolVersion
+		event   types.EventType
+		message any
+		want    string
+		payload any
+	}{
+		{"v1 tick", ProtocolV1, types.EventTypeCountdownTick, &types.CountdownTickPayload{Countdown: 3}, "tick", &types.CountdownTickPayload{Countdown: 3}},
+		{"v2 tick", ProtocolV2, types.EventTypeCountdownTick, &types.CountdownTickPayload{Countdown: 3}, "countdown-tick", &types.CountdownTickPayload{Countdown: 3}},
+		{"v1 letters", ProtocolV1, types.EventTypeLetterSelected, usedLetters, "letter-selected", usedLetters},
+		{"v2 letters", ProtocolV2, types.EventTypeLetterSelected, usedLetters, "letter-selected", &types.LetterSelectedPayload{UsedLetters: usedLetters}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := getProtocol(tt.version)
+			if !ok {
+				t.Fatalf("no protocol v%d", tt.version)
+			}
+			name, payload := p.adapt(tt.event, tt.message)
+			if name != tt.want || !reflect.DeepEqual(payload, tt.payload) {
+				t.Fatalf("adapt = %s %#v, want %s %#v", name, payload, tt.want, tt.payload)
+			}
+		})
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	want := types.SelectLetterPayload{RoomId: "room", Letter: "A"}
+	tests := map[string][]any{
+		"v1 string": {`{"roomId":"room","letter":"A"}`},
+		"v2 object": {map[string]any{"roomId": "room", "letter": "A"}},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got types.SelectLetterPayload
+			if err := decodePayload(data, &got); err != nil || got != want {
+				t.Fatalf("decodePayload = %+v, %v, want %+v", got, err, want)
+			}
+		})
+	}
+	var got types.SelectLetterPayload
+	if err := decodePayload(nil, &got); err == nil {
+		t.Fatal("decoded a missing payload")
+	}
+	if err := decodePayload([]any{"not json"}, &got); err == nil {
+		t.Fatal("decoded a malformed payload")
+	}
+}
@@ -0,0 +1,62 @@
+package socket
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+// Drain counts down to ctx's deadline, telling every room on this server
+// once a second that the server is shutting down. It returns straight away
+// when there are no rooms.
+func (s *Socket) Drain(ctx context.Context) {
+	s.draining.Store(true)
+	if len(roomPkg.RoomIds()) == 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		secondsRemaining := 0
+		if deadline, ok := ctx.Deadline(); ok {
+			secondsRemaining = int(time.Until(deadline).Round(time.Second).Seconds())
+		}
+		for _, roomId := range roomPkg.RoomIds() {
+			s.broadcastToRoom(roomId, "", types.EventTypeServerShuttingDown, &types.ServerShuttingDownPayload{
+				SecondsRemaining: secondsRemaining,
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown pauses every room's timer and saves the rooms, warns their
+// players until ctx's deadline, then hands the rooms over to the rest of
+// the cluster and disconnects every client.
+func (s *Socket) Shutdown(ctx context.Context) {
+	roomPkg.PauseTimers()
+	s.node.SaveAll()
+	s.Drain(ctx)
+	s.node.Close()
+	s.node.Handoff()
+	s.Close()
+}
+
+// Draining reports whether Drain has been called.
+func (s *Socket) Draining() bool {
+	return s.draining.Load()
+}
+
+// Close disconnects every client connected to this server.
+func (s *Socket) Close() {
+	slog.Info("closing all client connections")
+	s.DisconnectSockets(true)
+	s.nativeHub.closeAll()
+}
@@ -0,0 +1,102 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	roomPkg "github.com/campbell-rehu/quik-be/room"
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+func TestShutdown(t *testing.T) {
+	s := newTestSocket(t)
+	s.node.SetRoomState(roomPkg.Snapshots{})
+	server := httptest.NewServer(http.HandlerFunc(s.HandleNativeWS))
+	defer server.Close()
+
+	conn, playerId := dialNative(t, server.URL)
+	room, err := roomPkg.AddRoom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer roomPkg.EndRoom(room.Id)
+	s.node.Own(room.Id)
+	room.AddPlayerToRoom(playerId, "Aroha")
+	room.AddPlayerToRoom("other-player", "Mere")
+	sendNative(t, conn, types.EventTypeJoinRoom, &types.RoomIdPayload{RoomId: room.Id})
+	readNative(t, conn, types.EventTypeRoomJoined, nil)
+	sendNative(t, conn, types.EventTypeCountdownStarted, &types.RoomIdPayload{RoomId: room.Id})
+	readNative(t, conn, "countdown-tick", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	shutDown := make(chan struct{})
+	go func() {
+		s.Shutdown(ctx)
+		close(shutDown)
+	}()
+
+	var warning types.ServerShuttingDownPayload
+	readNative(t, conn, types.EventTypeServerShuttingDown, &warning)
+	if warning.SecondsRemaining < 2 || warning.SecondsRemaining > 3 {
+		t.Fatalf("seconds remaining = %d, want the drain deadline", warning.SecondsRemaining)
+	}
+	if !s.Draining() {
+		t.Fatal("the socket is not draining")
+	}
+	if _, err := s.node.Adapter().LoadRoom(room.Id); err != nil {
+		t.Fatalf("the room was not saved before the drain: %v", err)
+	}
+
+	// The client hears the countdown carry on, but not the paused turn's,
+	// until it is disconnected. A tick may have been on its way as the
+	// timer was paused.
+	ticks := 0
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var event types.Event
+		err := conn.ReadJSON(&event)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatal("the client was not disconnected")
+		}
+		if err != nil {
+			break
+		}
+		switch event.Type {
+		case "countdown-tick":
+			ticks++
+		case string(types.EventTypeServerShuttingDown):
+			if err := json.Unmarshal(event.Payload, &warning); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("got %s while the server shut down", event.Type)
+		}
+	}
+	if ticks > 1 {
+		t.Fatalf("got %d ticks while the server shut down, want the timer paused", ticks)
+	}
+	if warning.SecondsRemaining >= 2 {
+		t.Fatalf("seconds remaining = %d, want the countdown to carry on", warning.SecondsRemaining)
+	}
+
+	select {
+	case <-shutDown:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown did not finish after the clients were disconnected")
+	}
+	if elapsed := time.Since(started); elapsed < 2*time.Second {
+		t.Fatalf("shutdown took %s, want it to wait for the drain deadline", elapsed)
+	}
+	if owner, _ := s.node.Adapter().RoomOwner(room.Id); owner != "" {
+		t.Fatalf("room owner = %q, want the room handed off", owner)
+	}
+}
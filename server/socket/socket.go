@@ -3,40 +3,115 @@ package socket
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
+	"log/slog"
 	"net/http"
-
-	"github.com/campbell-rehu/quik-be/helpers"
+	"sync"
+	"sync/atomic"
+
+	"github.com/campbell-rehu/quik-be/auth"
+	"github.com/campbell-rehu/quik-be/cluster"
+	"github.com/campbell-rehu/quik-be/logging"
+	"github.com/campbell-rehu/quik-be/matchmaking"
+	"github.com/campbell-rehu/quik-be/metrics"
+	"github.com/campbell-rehu/quik-be/origins"
+	"github.com/campbell-rehu/quik-be/ratelimit"
 	roomPkg "github.com/campbell-rehu/quik-be/room"
 	"github.com/campbell-rehu/quik-be/types"
+	engineTypes "github.com/zishang520/engine.io/types"
 	"github.com/zishang520/socket.io/socket"
 )
 
 type Socket struct {
 	*socket.Server
 	eventHandlers WSEventHandlers
+	queue         *matchmaking.Queue
+	auth          *auth.Sessions
+	limits        *ratelimit.Events
+	origins       *origins.Checker
+	bots          *botPlayers
+	sessions      sync.Map
+	nativeHub     *nativeHub
+	node          *cluster.Node
+	draining      atomic.Bool
 }
 
 type WSDoer = func(data ...any)
 
-type WSEventHandler = func(client *socket.Socket) WSDoer
+type WSEventHandler = func(client Client) WSDoer
 
 type WSEventHandlers = map[types.EventType]WSEventHandler
 
-func NewSocket() *Socket {
-	sock := socket.NewServer(nil, nil)
-	eventHandlers := make(map[types.EventType]WSEventHandler)
-	return &Socket{sock, eventHandlers}
+func NewSocket(node *cluster.Node) (*Socket, error) {
+	s := &Socket{eventHandlers: make(map[types.EventType]WSEventHandler), nativeHub: newNativeHub(), node: node}
+	opts := socket.DefaultServerOptions()
+	opts.SetAllowRequest(func(ctx *engineTypes.HttpContext) error {
+		if !s.allowOrigin(ctx.Request()) {
+			return origins.ErrOriginNotAllowed
+		}
+		return nil
+	})
+	s.Server = socket.NewServer(nil, opts)
+	subscriptions := map[string]func([]byte){
+		cluster.BroadcastChannel:           s.deliver,
+		cluster.EventsChannel:              s.handleForwardedEvent,
+		cluster.NodeEventsChannel(node.Id): s.handleForwardedEvent,
+	}
+	for channel, handler := range subscriptions {
+		if _, err := node.Adapter().Subscribe(channel, handler); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Socket) SetMatchmakingQueue(queue *matchmaking.Queue) {
+	s.queue = queue
+}
+
+// SetEventLimits limits the events each client may send.
+func (s *Socket) SetEventLimits(limits *ratelimit.Events) {
+	s.limits = limits
+}
+
+// SetAllowedOrigins refuses connections from web pages on origins the
+// checker does not allow. Every origin is allowed until it is set.
+func (s *Socket) SetAllowedOrigins(checker *origins.Checker) {
+	s.origins = checker
+}
+
+// allowOrigin reports whether a client may connect from the page it was
+// loaded by.
+func (s *Socket) allowOrigin(r *http.Request) bool {
+	if s.origins == nil {
+		return true
+	}
+	if err := s.origins.Check(r); err != nil {
+		slog.WarnContext(r.Context(), "connection refused", logging.Err(err))
+		return false
+	}
+	return true
+}
+
+// SetSessions enables signing in to accounts with session tokens issued by
+// sessions.
+func (s *Socket) SetSessions(sessions *auth.Sessions) {
+	s.auth = sessions
 }
 
 func (s *Socket) RegisterWSHandlers() {
+	s.registerWSHandler(types.EventTypeHello, s.OnHello)
 	s.registerWSHandler(types.EventTypeJoinRoom, s.OnJoinRoom)
 	s.registerWSHandler(types.EventTypeDisconnect, s.OnDisconnect)
 	s.registerWSHandler(types.EventTypeCountdownStarted, s.OnCountdownStarted)
 	s.registerWSHandler(types.EventTypeSelectLetter, s.OnSelectLetter)
 	s.registerWSHandler(types.EventTypeEndTurn, s.OnEndTurn)
 	s.registerWSHandler(types.EventTypeResetTimer, s.OnResetTimer)
+	s.registerWSHandler(types.EventTypeSetIsInTextMode, s.OnSetIsInTextMode)
+	s.registerWSHandler(types.EventTypeChallengeAnswer, s.OnChallengeAnswer)
+	s.registerWSHandler(types.EventTypeCastVote, s.OnCastVote)
 	s.registerWSHandler(types.EventTypeLeaveRoom, s.OnLeaveRoom)
+	s.registerWSHandler(types.EventTypeJoinQueue, s.OnJoinQueue)
+	s.registerWSHandler(types.EventTypeLeaveQueue, s.OnLeaveQueue)
 }
 
 func (s *Socket) HandleHTTP(w http.ResponseWriter, r *http.Request) {
@@ -45,44 +120,161 @@ func (s *Socket) HandleHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (s *Socket) HandleWS() {
 	s.On(string(types.EventTypeConnection), func(clients ...any) {
-		client := clients[0].(*socket.Socket)
-		for k, f := range s.eventHandlers {
-			client.On(string(k), f(client))
+		sock := clients[0].(*socket.Socket)
+		client := &socketIOClient{sock}
+		if !s.handshake(client, helloFromHandshake(sock.Handshake())) {
+			client.Close()
+			return
+		}
+		metrics.SocketsActive.WithLabelValues("socket.io").Inc()
+		sock.On(string(types.EventTypeDisconnect), func(...any) {
+			metrics.SocketsActive.WithLabelValues("socket.io").Dec()
+		})
+		for k, f := range s.bindHandlers(client) {
+			sock.On(string(k), f)
 		}
 	})
 }
 
-func (s *Socket) OnJoinRoom(client *socket.Socket) func(data ...any) {
+// handshake negotiates the protocol version with a client and tells it the
+// outcome, reporting whether the client can stay connected.
+func (s *Socket) handshake(client Client, hello *types.HelloPayload) bool {
+	supportedVersions := []int{}
+	for _, version := range SupportedProtocolVersions() {
+		supportedVersions = append(supportedVersions, int(version))
+	}
+
+	account, err := s.verifySession(hello)
+	if err != nil {
+		clientLogger(client).Warn("session token rejected", logging.Err(err))
+		metrics.EventsEmitted.WithLabelValues(string(types.EventTypeUnauthorized)).Inc()
+		client.Emit(string(types.EventTypeUnauthorized), &types.UnauthorizedPayload{Message: err.Error()})
+		return false
+	}
+
+	sess, err := negotiate(hello)
+	if err != nil {
+		clientLogger(client).Warn("protocol negotiation failed", logging.Err(err))
+		metrics.EventsEmitted.WithLabelValues(string(types.EventTypeProtocolError)).Inc()
+		client.Emit(string(types.EventTypeProtocolError), &types.ProtocolErrorPayload{
+			Message:           err.Error(),
+			SupportedVersions: supportedVersions,
+		})
+		return false
+	}
+
+	previous, renegotiated := s.sessions.Load(client.Id())
+	if account == nil && renegotiated {
+		// A client renegotiating its protocol stays signed in.
+		account = previous.(*session).account
+	}
+	sess = &session{protocol: sess.protocol, features: sess.features, account: account}
+	if previous, ok := s.sessions.Swap(client.Id(), sess); ok {
+		s.moveVersionedRooms(client, previous.(*session), sess)
+	}
+	clientLogger(client).Info("client negotiated protocol",
+		"protocol_version", sess.protocol.version,
+		"features", sess.features,
+		logging.KeyAccountId, sess.accountId(),
+	)
+	metrics.EventsEmitted.WithLabelValues(string(types.EventTypeServerHello)).Inc()
+	client.Emit(string(types.EventTypeServerHello), &types.ServerHelloPayload{
+		PlayerId:          string(client.Id()),
+		ProtocolVersion:   int(sess.protocol.version),
+		SupportedVersions: supportedVersions,
+		Features:          sess.features,
+	})
+	return true
+}
+
+// verifySession checks the session token a client sent, returning nil
+// claims for guests.
+func (s *Socket) verifySession(hello *types.HelloPayload) (*auth.Claims, error) {
+	if hello == nil || hello.SessionToken == "" {
+		return nil, nil
+	}
+	if s.auth == nil {
+		return nil, errors.New("accounts are not enabled")
+	}
+	return s.auth.Verify(hello.SessionToken)
+}
+
+// moveVersionedRooms re-joins a client that renegotiated its protocol to
+// the versioned rooms for its new version.
+func (s *Socket) moveVersionedRooms(client Client, previous, next *session) {
+	if previous.protocol.version == next.protocol.version {
+		return
+	}
+	for _, room := range client.JoinedRooms() {
+		roomId, ok := roomIdFromVersionedRoom(room, previous.protocol.version)
+		if !ok {
+			continue
+		}
+		client.Leave(room)
+		client.Join(versionedRoom(roomId, next.protocol.version))
+	}
+}
+
+// clientLogger returns a logger carrying the client's id, which is also its
+// player id.
+func clientLogger(client Client) *slog.Logger {
+	return slog.With(logging.KeyPlayerId, client.Id())
+}
+
+// eventLogger returns a logger for a handler, carrying the client and the
+// event it is handling.
+func eventLogger(client Client, eventType types.EventType) *slog.Logger {
+	return clientLogger(client).With(logging.KeyEvent, eventType)
+}
+
+func (s *Socket) session(clientId socket.SocketId) *session {
+	if sess, ok := s.sessions.Load(clientId); ok {
+		return sess.(*session)
+	}
+	return legacySession
+}
+
+func (s *Socket) OnHello(client Client) WSDoer {
 	return func(data ...any) {
-		helpers.Print(
-			"client with id=%s ip address=%s joining room\n",
-			client.Id(),
-			client.Client().Conn().RemoteAddress(),
-		)
-		roomId := data[0].(string)
+		var t types.HelloPayload
+		if err := decodePayload(data, &t); err != nil {
+			eventLogger(client, types.EventTypeHello).Warn("invalid payload", logging.Err(err))
+			return
+		}
+		if !s.handshake(client, &t) {
+			client.Close()
+		}
+	}
+}
+
+func (s *Socket) OnJoinRoom(client Client) func(data ...any) {
+	return func(data ...any) {
+		log := eventLogger(client, types.EventTypeJoinRoom)
+		log.Info("client joining room", "remote_address", client.RemoteAddress())
+		roomId := roomIdOf(types.EventTypeJoinRoom, data)
+		if roomId == "" {
+			log.Warn("invalid payload", logging.Err(errors.New("missing room id")))
+			return
+		}
+		log = log.With(logging.KeyRoomId, roomId)
+		if s.Draining() {
+			log.Warn("server is shutting down, not joining room")
+			return
+		}
 		room, err := roomPkg.GetRoom(roomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Warn("unable to join room", logging.Err(err))
 			return
 		}
 
 		if room.IsLocked() {
-			helpers.PrintError(
-				errors.New(fmt.Sprintf("Room id=%s is locked. No new players can join", room.Id)),
-			)
+			log.Warn("room is locked, no new players can join")
 			return
 		}
 
-		client.Join(socket.Room(roomId))
-
-		type x struct {
-			Players       map[string]*types.Player `json:"players"`
-			UsedLetters   map[string]bool          `json:"usedLetters"`
-			CurrentPlayer *types.Player            `json:"currentPlayer"`
-			PlayerCount   int                      `json:"playerCount"`
-		}
+		client.Join(socket.Room(roomId), versionedRoom(roomId, s.session(client.Id()).protocol.version))
 
-		s.emitToRoom(client, roomId, types.EventTypeRoomJoined, &x{
+		s.emitToRoom(client, roomId, types.EventTypeRoomJoined, &types.RoomJoinedPayload{
 			Players:       room.Players,
 			UsedLetters:   room.UsedLetters,
 			CurrentPlayer: room.CurrentPlayer,
@@ -91,62 +283,58 @@ func (s *Socket) OnJoinRoom(client *socket.Socket) func(data ...any) {
 	}
 }
 
-func (s *Socket) OnDisconnect(client *socket.Socket) func(data ...any) {
+func (s *Socket) OnDisconnect(client Client) func(data ...any) {
 	return func(data ...any) {
-		helpers.Print(
-			"client with id=%s ip address=%s disconnected\n",
-			client.Id(),
-			client.Client().Conn().RemoteAddress(),
-		)
+		log := eventLogger(client, types.EventTypeDisconnect)
+		log.Info("client disconnected", "remote_address", client.RemoteAddress())
 
 		playerId := string(client.Id())
+		s.sessions.Delete(client.Id())
+		if s.limits != nil {
+			s.limits.Forget(playerId)
+		}
+		if s.queue != nil {
+			s.queue.Leave(playerId)
+		}
 		roomId := roomPkg.GetRoomId(playerId)
 		room, err := roomPkg.GetRoom(roomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Debug("disconnected client was not in a room", logging.Err(err))
 			return
 		}
 
 		room.LeaveRoom(playerId)
 
-		type msg struct {
-			PlayerId string `json:"playerId"`
-		}
-
-		s.emitToRoom(client, roomId, types.EventTypeDisconnected, &msg{PlayerId: playerId})
+		s.emitToRoom(client, roomId, types.EventTypeDisconnected, &types.PlayerIdPayload{PlayerId: playerId})
+		removeEmptyRoom(room)
+		s.dismissBots(room)
 	}
 }
 
-func (s *Socket) OnCountdownStarted(client *socket.Socket) func(data ...any) {
+func (s *Socket) OnCountdownStarted(client Client) func(data ...any) {
 	return func(data ...any) {
-		var t struct {
-			RoomId string `json:"roomId"`
+		log := eventLogger(client, types.EventTypeCountdownStarted)
+		var t types.RoomIdPayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
 		}
-		raw := data[0].(string)
-		json.Unmarshal([]byte(raw), &t)
 
 		roomId := t.RoomId
+		log = log.With(logging.KeyRoomId, roomId)
 		room, err := roomPkg.GetRoom(roomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Warn("unable to start round", logging.Err(err))
 			return
 		}
 
 		room.LockRoom()
 
-		helpers.Print(
-			"room with id=%s is now locked. no new players can join\n",
-			room.Id,
-		)
+		log.Info("room is now locked, no new players can join")
 
-		s.emitToRoom(client, roomId, types.EventTypeRoomLocked, data)
-		type x struct {
-			Category      string          `json:"category"`
-			UsedLetters   map[string]bool `json:"usedLetters"`
-			CurrentPlayer *types.Player   `json:"currentPlayer"`
-		}
-		s.emitToRoom(client, roomId, types.EventTypeRoundStarted, &x{
-			Category:      room.GetCategory(),
+		s.emitToRoom(client, roomId, types.EventTypeRoomLocked, &types.RoomIdPayload{RoomId: roomId})
+		s.emitToRoom(client, roomId, types.EventTypeRoundStarted, &types.RoundStartedPayload{
+			Category:      room.StartRound(),
 			UsedLetters:   room.UsedLetters,
 			CurrentPlayer: room.CurrentPlayer,
 		})
@@ -155,7 +343,15 @@ func (s *Socket) OnCountdownStarted(client *socket.Socket) func(data ...any) {
 	}
 }
 
-func (s *Socket) handleCountdown(client *socket.Socket, room *roomPkg.Room) {
+func (s *Socket) handleCountdown(client Client, room *roomPkg.Room) {
+	if !s.node.Owns(room.Id) {
+		slog.Warn("this node does not own the room, not starting its timer",
+			logging.KeyNodeId, s.node.Id,
+			logging.KeyRoomId, room.Id,
+		)
+		return
+	}
+
 	emitTick := func(tickChan chan int, doneCh chan bool) {
 		s.EmitCountdownTick(client, room.Id, tickChan, doneCh)
 	}
@@ -164,41 +360,49 @@ func (s *Socket) handleCountdown(client *socket.Socket, room *roomPkg.Room) {
 		s.emitToRoom(client, room.Id, eventType, data)
 	}
 
-	room.StartTimer(emitTick, emitEvent)
+	s.playBotTurn(room)
+	// The timer runs until the turn ends, so it must not hold up the
+	// client's next events.
+	go room.StartTimer(emitTick, emitEvent)
 }
 
 func (s *Socket) EmitCountdownTick(
-	client *socket.Socket,
+	client Client,
 	roomId string,
 	tickChan chan int,
 	doneChan chan bool,
 ) {
-	type countdown struct {
-		Countdown int `json:"countdown"`
-	}
 	for {
 		select {
 		case <-doneChan:
 			return
 		case tick := <-tickChan:
-			s.emitToRoom(client, roomId, types.EventTypeCountdownTick, countdown{Countdown: tick})
+			s.emitToRoom(client, roomId, types.EventTypeCountdownTick, &types.CountdownTickPayload{Countdown: tick})
 		}
 	}
 }
 
-func (s *Socket) OnSelectLetter(client *socket.Socket) func(data ...any) {
+func (s *Socket) OnSelectLetter(client Client) func(data ...any) {
 	return func(data ...any) {
-		var t struct {
-			RoomId         string `json:"roomId"`
-			Letter         string `json:"letter"`
-			PreviousLetter string `json:"prevLetter"`
+		log := eventLogger(client, types.EventTypeSelectLetter)
+		var t types.SelectLetterPayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
 		}
-		raw := data[0].(string)
-		json.Unmarshal([]byte(raw), &t)
 
 		room, err := roomPkg.GetRoom(t.RoomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Warn("unable to select letter", logging.KeyRoomId, t.RoomId, logging.Err(err))
+			return
+		}
+		if !room.IsTurnOf(string(client.Id())) {
+			log.Warn("player tried to choose another player's letter", logging.KeyRoomId, t.RoomId)
+			s.emitToClient(string(client.Id()), types.EventTypeTurnRefused, &types.TurnRefusedPayload{Reason: "it is not your turn"})
+			return
+		}
+		if !room.HasLetter(t.Letter) {
+			log.Warn("letter is not in the room's letter set", logging.KeyRoomId, t.RoomId, "letter", t.Letter)
 			return
 		}
 
@@ -211,54 +415,79 @@ func (s *Socket) OnSelectLetter(client *socket.Socket) func(data ...any) {
 	}
 }
 
-func (s *Socket) OnEndTurn(client *socket.Socket) func(data ...any) {
+func (s *Socket) OnEndTurn(client Client) func(data ...any) {
 	return func(data ...any) {
-		var t struct {
-			RoomId         string `json:"roomId"`
-			SelectedLetter string `json:"selectedLetter"`
+		log := eventLogger(client, types.EventTypeEndTurn)
+		var t types.EndTurnPayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
 		}
-		raw := data[0].(string)
-		json.Unmarshal([]byte(raw), &t)
 
 		room, err := roomPkg.GetRoom(t.RoomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Warn("unable to end turn", logging.KeyRoomId, t.RoomId, logging.Err(err))
+			return
+		}
+		if !room.IsTurnOf(string(client.Id())) {
+			log.Warn("player tried to end another player's turn", logging.KeyRoomId, t.RoomId)
+			s.emitToClient(string(client.Id()), types.EventTypeTurnRefused, &types.TurnRefusedPayload{Reason: "it is not your turn"})
+			return
+		}
+		if room.ChallengeInProgress() {
+			log.Info("an answer is being voted on, the turn waits", logging.KeyRoomId, t.RoomId)
+			s.emitToClient(string(client.Id()), types.EventTypeTurnRefused, &types.TurnRefusedPayload{Reason: "an answer is being voted on"})
 			return
 		}
 
-		room.SetNextPlayerIndex()
-		room.ResetTimer()
+		if room.IsInTextMode() {
+			if _, err := room.SubmitAnswer(string(client.Id()), t.SelectedLetter, t.Answer); err != nil {
+				s.emitToClient(string(client.Id()), types.EventTypeAnswerRejected, &types.AnswerRejectedPayload{
+					Answer: t.Answer,
+					Reason: err.Error(),
+				})
+				return
+			}
+		}
 
+		// The letter is marked used before the turn advances so that it is
+		// credited to the player who chose it.
 		room.SetLetterUnselectable(t.SelectedLetter)
 
-		type endTurn struct {
-			CurrentPlayer *types.Player   `json:"currentPlayer"`
-			UsedLetters   map[string]bool `json:"usedLetters"`
-		}
+		room.SetNextPlayerIndex()
+		room.ResetTimer()
 
-		s.emitToRoom(client, room.Id, types.EventTypeStartTurn, &endTurn{
+		s.emitToRoom(client, room.Id, types.EventTypeStartTurn, &types.StartTurnPayload{
 			CurrentPlayer: room.GetCurrentPlayer(),
 			UsedLetters:   room.UsedLetters,
+			Answers:       room.Answers,
 		})
 	}
 }
 
-func (s *Socket) OnResetTimer(client *socket.Socket) WSDoer {
+func (s *Socket) OnResetTimer(client Client) WSDoer {
 	return func(data ...any) {
-		helpers.Print(
-			"client with id=%s ip address=%s reset-timer\n",
-			client.Id(),
-			client.Client().Conn().RemoteAddress(),
-		)
-		var t struct {
-			RoomId string `json:"roomId"`
+		log := eventLogger(client, types.EventTypeResetTimer)
+		var t types.RoomIdPayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
 		}
-		raw := data[0].(string)
-		json.Unmarshal([]byte(raw), &t)
+		log = log.With(logging.KeyRoomId, t.RoomId)
+		log.Info("client reset the timer")
 
 		room, err := roomPkg.GetRoom(t.RoomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Warn("unable to reset timer", logging.Err(err))
+			return
+		}
+		if room.AnswerRejected() {
+			log.Info("the player's answer was rejected, their turn carries on")
+			return
+		}
+		if room.ChallengeInProgress() {
+			log.Info("an answer is being voted on, the turn waits")
+			s.emitToClient(string(client.Id()), types.EventTypeTurnRefused, &types.TurnRefusedPayload{Reason: "an answer is being voted on"})
 			return
 		}
 
@@ -268,46 +497,260 @@ func (s *Socket) OnResetTimer(client *socket.Socket) WSDoer {
 	}
 }
 
-func (s *Socket) OnLeaveRoom(client *socket.Socket) WSDoer {
+// OnSetIsInTextMode turns typed answers on or off for a room that has not
+// started playing, and tells the room.
+func (s *Socket) OnSetIsInTextMode(client Client) WSDoer {
 	return func(data ...any) {
-		helpers.Print(
-			"client with id=%s ip address=%s leave-room\n",
-			client.Id(),
-			client.Client().Conn().RemoteAddress(),
-		)
-		var t struct {
-			RoomId   string `json:"roomId"`
-			PlayerId string `json:"playerId"`
+		log := eventLogger(client, types.EventTypeSetIsInTextMode)
+		var t types.TextModePayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
+		}
+		log = log.With(logging.KeyRoomId, t.RoomId)
+
+		room, err := roomPkg.GetRoom(t.RoomId)
+		if err != nil {
+			log.Warn("unable to set text mode", logging.Err(err))
+			return
+		}
+		if err := room.SetTextMode(t.IsInTextMode); err != nil {
+			log.Warn("unable to set text mode", logging.Err(err))
+			return
+		}
+		log.Info("text mode set", "text_mode", t.IsInTextMode)
+
+		s.emitToRoom(client, room.Id, types.EventTypeSetIsInTextMode, &t)
+	}
+}
+
+// OnChallengeAnswer disputes the last answer given in a room and asks its
+// players to vote on it.
+func (s *Socket) OnChallengeAnswer(client Client) WSDoer {
+	return func(data ...any) {
+		log := eventLogger(client, types.EventTypeChallengeAnswer)
+		var t types.RoomIdPayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
+		}
+		log = log.With(logging.KeyRoomId, t.RoomId)
+
+		room, err := roomPkg.GetRoom(t.RoomId)
+		if err != nil {
+			log.Warn("unable to challenge answer", logging.Err(err))
+			return
+		}
+		emitEvent := func(eventType types.EventType, data any) {
+			s.broadcastToRoom(room.Id, "", eventType, data)
+		}
+		challenge, err := room.ChallengeAnswer(string(client.Id()), emitEvent)
+		if err != nil {
+			log.Warn("unable to challenge answer", logging.Err(err))
+			return
+		}
+
+		s.voteBots(room, challenge)
+	}
+}
+
+func (s *Socket) OnCastVote(client Client) WSDoer {
+	return func(data ...any) {
+		log := eventLogger(client, types.EventTypeCastVote)
+		var t types.CastVotePayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
+		}
+		log = log.With(logging.KeyRoomId, t.RoomId)
+
+		room, err := roomPkg.GetRoom(t.RoomId)
+		if err != nil {
+			log.Warn("unable to vote", logging.Err(err))
+			return
+		}
+		if err := room.Vote(string(client.Id()), t.Keep); err != nil {
+			log.Warn("unable to vote", logging.Err(err))
+		}
+	}
+}
+
+func (s *Socket) OnLeaveRoom(client Client) WSDoer {
+	return func(data ...any) {
+		log := eventLogger(client, types.EventTypeLeaveRoom)
+		var t types.LeaveRoomPayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
 		}
-		raw := data[0].(string)
-		json.Unmarshal([]byte(raw), &t)
+		log = log.With(logging.KeyRoomId, t.RoomId)
+		log.Info("client leaving room")
 
 		room, err := roomPkg.GetRoom(t.RoomId)
 		if err != nil {
-			helpers.PrintError(err)
+			log.Warn("unable to leave room", logging.Err(err))
 			return
 		}
 
 		room.LeaveRoom(t.PlayerId)
 
-		if room.GetPlayerCount() == 0 {
-			room.ResetTimer()
-			roomPkg.RemoveRoom(t.RoomId)
+		removeEmptyRoom(room)
+		s.dismissBots(room)
+	}
+}
+
+// removeEmptyRoom removes a room once its last player has left, so that
+// whatever was held for it is released.
+func removeEmptyRoom(room *roomPkg.Room) {
+	if room.GetPlayerCount() == 0 {
+		room.StopTimer()
+		roomPkg.RemoveRoom(room.Id)
+	}
+}
+
+func (s *Socket) OnJoinQueue(client Client) WSDoer {
+	return func(data ...any) {
+		log := eventLogger(client, types.EventTypeJoinQueue)
+		var t types.JoinQueuePayload
+		if err := decodePayload(data, &t); err != nil {
+			log.Warn("invalid payload", logging.Err(err))
+			return
+		}
+
+		if s.queue == nil || !s.session(client.Id()).hasFeature(FeatureMatchmaking) {
+			log.Warn("matchmaking is not enabled for client")
+			return
+		}
+
+		_, err := s.queue.Join(string(client.Id()), t.PlayerName, s.session(client.Id()).accountId(), t.Preferences)
+		if err != nil {
+			log.Warn("unable to join matchmaking queue", logging.Err(err))
+			return
 		}
 	}
 }
 
+func (s *Socket) OnLeaveQueue(client Client) WSDoer {
+	return func(data ...any) {
+		eventLogger(client, types.EventTypeLeaveQueue).Info("client leaving matchmaking queue")
+		if s.queue == nil {
+			return
+		}
+		s.queue.Leave(string(client.Id()))
+	}
+}
+
+func (s *Socket) NotifyQueuePosition(playerId string, position int, waiting int) {
+	s.emitToClient(playerId, types.EventTypeQueuePosition, &types.QueuePositionPayload{Position: position, Waiting: waiting})
+}
+
+func (s *Socket) NotifyMatchFound(playerId string, room *roomPkg.Room) {
+	s.emitToClient(playerId, types.EventTypeMatchFound, &types.MatchFoundPayload{RoomId: room.Id, Preferences: room.Preferences})
+}
+
+func (s *Socket) NotifyQueueTimeout(playerId string) {
+	s.emitToClient(playerId, types.EventTypeQueueTimeout, &types.PlayerIdPayload{PlayerId: playerId})
+}
+
 func (s *Socket) registerWSHandler(eventType types.EventType, f WSEventHandler) {
 	s.eventHandlers[eventType] = f
 }
 
 func (s *Socket) emitToRoom(
-	sock *socket.Socket,
+	sock Client,
 	roomId string,
 	eventType types.EventType,
 	message any,
 ) {
-	helpers.Print("emitting message type=%s to room id=%s, message=%+v", eventType, roomId, message)
-	sock.To(socket.Room(roomId)).Emit(string(eventType), message)
-	sock.Emit(string(eventType), message)
+	s.broadcastToRoom(roomId, sock.Id(), eventType, message)
+	name, payload := s.session(sock.Id()).protocol.adapt(eventType, message)
+	sock.Emit(name, payload)
+}
+
+// broadcastToRoom emits to every client in the room apart from except, in
+// the shape each client's protocol version expects.
+func (s *Socket) broadcastToRoom(
+	roomId string,
+	except socket.SocketId,
+	eventType types.EventType,
+	message any,
+) {
+	slog.Debug("emitting event to room", logging.KeyEvent, eventType, logging.KeyRoomId, roomId)
+	metrics.EventsEmitted.WithLabelValues(string(eventType)).Inc()
+	for _, p := range protocols {
+		name, payload := p.adapt(eventType, message)
+		s.broadcast(versionedRoom(roomId, p.version), except, name, payload)
+	}
+}
+
+// emitToClient sends a message to a single client. Every client is a member
+// of a room named after its own id.
+func (s *Socket) emitToClient(
+	clientId string,
+	eventType types.EventType,
+	message any,
+) {
+	slog.Debug("emitting event to client", logging.KeyEvent, eventType, logging.KeyPlayerId, clientId)
+	metrics.EventsEmitted.WithLabelValues(string(eventType)).Inc()
+	name, payload := s.session(socket.SocketId(clientId)).protocol.adapt(eventType, message)
+	s.broadcast(socket.Room(clientId), "", name, payload)
+}
+
+// broadcast emits to everyone in the room on every replica and transport,
+// apart from the client with id except.
+func (s *Socket) broadcast(room socket.Room, except socket.SocketId, event string, message any) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		slog.Error("unable to encode event", logging.KeyEvent, event, logging.Err(err))
+		return
+	}
+	b, err := json.Marshal(&cluster.Broadcast{
+		Room:    string(room),
+		Except:  string(except),
+		Event:   event,
+		Payload: payload,
+	})
+	if err != nil {
+		slog.Error("unable to encode broadcast", logging.KeyEvent, event, logging.Err(err))
+		return
+	}
+	if err := s.node.Adapter().Publish(cluster.BroadcastChannel, b); err != nil {
+		slog.Error("unable to publish broadcast, delivering it locally", logging.KeyEvent, event, logging.Err(err))
+		s.deliver(b)
+	}
+}
+
+// deliver emits a published broadcast to the clients connected to this
+// replica.
+func (s *Socket) deliver(b []byte) {
+	var msg cluster.Broadcast
+	if err := json.Unmarshal(b, &msg); err != nil {
+		slog.Error("unable to decode broadcast", logging.Err(err))
+		return
+	}
+	switch msg.Op {
+	case cluster.BroadcastOpJoin:
+		s.In(socket.Room(msg.Room)).SocketsJoin(socket.Room(msg.Event))
+		s.nativeHub.joinById(socket.SocketId(msg.Room), socket.Room(msg.Event))
+		return
+	case cluster.BroadcastOpLeave:
+		s.In(socket.Room(msg.Room)).SocketsLeave(socket.Room(msg.Event))
+		s.nativeHub.leaveById(socket.SocketId(msg.Room), socket.Room(msg.Event))
+		return
+	case cluster.BroadcastOpClose:
+		s.In(socket.Room(msg.Room)).SocketsLeave(socket.Room(msg.Room))
+		s.nativeHub.closeRoom(socket.Room(msg.Room))
+		return
+	}
+	var message any
+	if err := json.Unmarshal(msg.Payload, &message); err != nil {
+		slog.Error("unable to decode broadcast payload", logging.KeyEvent, msg.Event, logging.Err(err))
+		return
+	}
+	operator := s.To(socket.Room(msg.Room))
+	if msg.Except != "" {
+		operator = operator.Except(socket.Room(msg.Except))
+	}
+	operator.Emit(msg.Event, message)
+	s.nativeHub.broadcast(socket.Room(msg.Room), socket.SocketId(msg.Except), msg.Event, message)
 }
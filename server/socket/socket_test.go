@@ -0,0 +1,87 @@
+package socket
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/campbell-rehu/quik-be/cluster"
+	"github.com/zishang520/socket.io/socket"
+)
+
+// fakeClient records what it is sent.
+type fakeClient struct {
+	id     socket.SocketId
+	mu     sync.Mutex
+	emits  []fakeEmit
+	rooms  []socket.Room
+	closed bool
+}
+
+type fakeEmit struct {
+	event   string
+	payload any
+}
+
+func (c *fakeClient) Id() socket.SocketId { return c.id }
+
+func (c *fakeClient) RemoteAddress() string { return "test" }
+
+func (c *fakeClient) Join(rooms ...socket.Room) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rooms = append(c.rooms, rooms...)
+}
+
+func (c *fakeClient) Leave(room socket.Room) {}
+
+func (c *fakeClient) JoinedRooms() []socket.Room {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]socket.Room{}, c.rooms...)
+}
+
+func (c *fakeClient) Emit(event string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var payload any
+	if len(args) > 0 {
+		payload = args[0]
+	}
+	c.emits = append(c.emits, fakeEmit{event, payload})
+	return nil
+}
+
+func (c *fakeClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+}
+
+// last returns the payload of the last event of the given name sent to the
+// client, decoded into v, reporting whether there was one.
+func (c *fakeClient) last(event string, v any) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.emits) - 1; i >= 0; i-- {
+		if c.emits[i].event != event {
+			continue
+		}
+		if v != nil {
+			b, _ := json.Marshal(c.emits[i].payload)
+			json.Unmarshal(b, v)
+		}
+		return true
+	}
+	return false
+}
+
+func newTestSocket(t *testing.T) *Socket {
+	t.Helper()
+	s, err := NewSocket(cluster.NewNode(cluster.NodeInfo{Id: "test"}, cluster.NewMemoryAdapter()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RegisterWSHandlers()
+	return s
+}
@@ -0,0 +1,147 @@
+package tlsserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/campbell-rehu/quik-be/logging"
+)
+
+var ErrNoCertificate = errors.New("no certificate loaded")
+
+// Certificate is a certificate and key read from files, which can be read
+// again when the files change so renewed certificates are served without a
+// restart.
+type Certificate struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func LoadCertificate(certFile, keyFile string) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the certificate and key files again, keeping the certificate
+// already loaded if they cannot be read.
+func (c *Certificate) Reload() error {
+	modified, err := c.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load %s and %s: %w", c.certFile, c.keyFile, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modified = modified
+	return nil
+}
+
+// Watch reloads the certificate whenever its files change, checking every
+// interval until stop is called.
+func (c *Certificate) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.reloadIfChanged()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (c *Certificate) reloadIfChanged() {
+	modified, err := c.lastModified()
+	if err != nil {
+		slog.Warn("unable to check the TLS certificate for changes", logging.Err(err))
+		return
+	}
+	c.mu.RLock()
+	changed := modified.After(c.modified)
+	c.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := c.Reload(); err != nil {
+		slog.Error("unable to reload the TLS certificate, still serving the old one", logging.Err(err))
+		return
+	}
+	slog.Info("reloaded the TLS certificate", "cert_file", c.certFile)
+}
+
+// lastModified is when the certificate or key file last changed.
+func (c *Certificate) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate serves the loaded certificate, for tls.Config.
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return c.cert, nil
+}
+
+// Configure sets a server up to serve the certificate, speaking HTTP/2 to
+// clients that support it unless http2 is false.
+func Configure(server *http.Server, cert *Certificate, http2 bool) {
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cert.GetCertificate,
+	}
+	if !http2 {
+		// A non-nil map stops net/http from enabling HTTP/2.
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+}
+
+// Redirect sends plain HTTP requests to the same URL over HTTPS on the
+// port given.
+func Redirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,138 @@
+package tlsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for the host name and
+// its key, marking both files as modified at the time given.
+func writeCertificate(t *testing.T, dir, host string, modified time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	files := map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+	for path, block := range files {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+func servedHost(t *testing.T, c *Certificate) string {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writeCertificate(t, dir, "old.example", start)
+	c, err := LoadCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.reloadIfChanged()
+	if got := servedHost(t, c); got != "old.example" {
+		t.Fatalf("host = %q, want old.example", got)
+	}
+
+	writeCertificate(t, dir, "new.example", start.Add(time.Minute))
+	c.reloadIfChanged()
+	if got := servedHost(t, c); got != "new.example" {
+		t.Fatalf("host = %q, want the renewed certificate", got)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c.reloadIfChanged()
+	if got := servedHost(t, c); got != "new.example" {
+		t.Fatalf("host = %q, want the last good certificate kept", got)
+	}
+
+	if _, err := LoadCertificate(certFile, filepath.Join(dir, "missing.pem")); err == nil {
+		t.Fatal("want an error loading a missing key")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	dir := t.TempDir()
+	c, err := LoadCertificate(writeCertificate(t, dir, "quik.example", time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{}
+	Configure(server, c, true)
+	if server.TLSConfig.GetCertificate == nil || server.TLSNextProto != nil {
+		t.Fatal("want the certificate served with HTTP/2 left on")
+	}
+	server = &http.Server{}
+	Configure(server, c, false)
+	if server.TLSNextProto == nil {
+		t.Fatal("want HTTP/2 turned off")
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	tests := []struct {
+		port   string
+		target string
+		want   string
+	}{
+		{"443", "http://quik.example/room/abc?x=1", "https://quik.example/room/abc?x=1"},
+		{"8443", "http://quik.example:8080/healthz", "https://quik.example:8443/healthz"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		Redirect(tt.port).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, nil))
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != tt.want {
+			t.Fatalf("status = %d, location = %q, want %d %q", rec.Code, rec.Header().Get("Location"), http.StatusPermanentRedirect, tt.want)
+		}
+	}
+}
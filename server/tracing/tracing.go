@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/campbell-rehu/quik-be/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/campbell-rehu/quik-be"
+	serviceName         = "quik"
+)
+
+// Attribute keys shared by every span, so that the HTTP requests, socket
+// events and room transitions of one room can be found together.
+const (
+	KeyRoomId   = attribute.Key("quik.room_id")
+	KeyPlayerId = attribute.Key("quik.player_id")
+	KeyEvent    = attribute.Key("quik.event")
+)
+
+// KeyTraceId is the log attribute carrying the id of the trace a request
+// belongs to.
+const KeyTraceId = "trace_id"
+
+type Options struct {
+	// Endpoint is the URL of an OTLP/HTTP collector, e.g.
+	// http://localhost:4318. Spans are not exported when it is empty.
+	Endpoint string
+	// SampleRatio is the fraction of traces to record, from 0 to 1.
+	SampleRatio float64
+	NodeId      string
+}
+
+// Setup installs the global tracer provider. Without an endpoint the
+// default no-op provider is kept. The returned function flushes and stops
+// the exporter.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(opts.Endpoint)}
+	if !strings.Contains(opts.Endpoint, "://") {
+		exporterOpts = []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint), otlptracehttp.WithInsecure()}
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceInstanceID(opts.NodeId),
+	))
+	if err != nil && !errors.Is(err, resource.ErrSchemaURLConflict) {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer is the tracer every package records its spans with.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// SetRoomId tags the span in ctx with the room it is about.
+func SetRoomId(ctx context.Context, roomId string) {
+	if roomId != "" {
+		trace.SpanFromContext(ctx).SetAttributes(KeyRoomId.String(roomId))
+	}
+}
+
+// Middleware starts a span for each request served by router, named by the
+// route pattern that matched it, continuing any trace the caller started.
+func Middleware(router *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := router.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+		if span.SpanContext().IsValid() {
+			ctx = logging.WithAttrs(ctx, KeyTraceId, span.SpanContext().TraceID().String())
+		}
+
+		rec := &logging.StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.Status))
+		if rec.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.Status))
+		}
+	})
+}
+
+// Inject adds the trace in ctx to the headers of a request made to another
+// node.
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
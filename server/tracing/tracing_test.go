@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareNamesSpansByRoute(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	if _, err := Setup(context.Background(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /room/{roomId}", func(w http.ResponseWriter, r *http.Request) {
+		SetRoomId(r.Context(), r.PathValue("roomId"))
+		w.WriteHeader(http.StatusNotFound)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/room/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	Middleware(router, router).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /room/{roomId}" {
+		t.Fatalf("span name = %q, want the route pattern", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id = %s, want the caller's trace to be continued", got)
+	}
+	attrs := attribute.NewSet(span.Attributes()...)
+	if v, _ := attrs.Value(KeyRoomId); v.AsString() != "abc" {
+		t.Fatalf("%s = %q, want abc", KeyRoomId, v.AsString())
+	}
+	if v, _ := attrs.Value("http.response.status_code"); v.AsInt64() != http.StatusNotFound {
+		t.Fatalf("status code = %d, want %d", v.AsInt64(), http.StatusNotFound)
+	}
+}
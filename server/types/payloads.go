@@ -0,0 +1,191 @@
+package types
+
+// Payloads sent by clients. Clients send these as JSON-encoded strings.
+
+type RoomIdPayload struct {
+	RoomId string `json:"roomId"`
+}
+
+type SelectLetterPayload struct {
+	RoomId         string `json:"roomId"`
+	Letter         string `json:"letter"`
+	PreviousLetter string `json:"prevLetter"`
+}
+
+// EndTurnPayload ends a turn. Answer is the word the player gave, and is
+// only read in typed answers mode.
+type EndTurnPayload struct {
+	RoomId         string `json:"roomId"`
+	SelectedLetter string `json:"selectedLetter"`
+	Answer         string `json:"answer"`
+}
+
+type TextModePayload struct {
+	RoomId       string `json:"roomId"`
+	IsInTextMode bool   `json:"isInTextMode"`
+}
+
+// CastVotePayload votes on a challenged answer, to keep it or to strike it.
+type CastVotePayload struct {
+	RoomId string `json:"roomId"`
+	Keep   bool   `json:"keep"`
+}
+
+type LeaveRoomPayload struct {
+	RoomId   string `json:"roomId"`
+	PlayerId string `json:"playerId"`
+}
+
+type JoinQueuePayload struct {
+	PlayerName  string      `json:"playerName"`
+	Preferences Preferences `json:"preferences"`
+}
+
+// HelloPayload declares the protocol a client speaks. It is read from the
+// socket.io handshake auth object or sent as a hello event. SessionToken
+// signs the client in to an account, guests leave it empty.
+type HelloPayload struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Features        []string `json:"features"`
+	SessionToken    string   `json:"sessionToken"`
+}
+
+// Payloads emitted by the server.
+
+// ServerHelloPayload confirms the protocol negotiated with a client.
+// PlayerId is the id the client plays as, which native WebSocket clients
+// have no other way of learning.
+type ServerHelloPayload struct {
+	PlayerId          string   `json:"playerId"`
+	ProtocolVersion   int      `json:"protocolVersion"`
+	SupportedVersions []int    `json:"supportedVersions"`
+	Features          []string `json:"features"`
+}
+
+type ProtocolErrorPayload struct {
+	Message           string `json:"message"`
+	SupportedVersions []int  `json:"supportedVersions"`
+}
+
+// RateLimitedPayload names an event the server dropped because the client
+// sent too many of it.
+type RateLimitedPayload struct {
+	Event        string `json:"event"`
+	Disconnected bool   `json:"disconnected"`
+}
+
+type UnauthorizedPayload struct {
+	Message string `json:"message"`
+}
+
+type RoomJoinedPayload struct {
+	Players       map[string]*Player `json:"players"`
+	UsedLetters   map[string]bool    `json:"usedLetters"`
+	CurrentPlayer *Player            `json:"currentPlayer"`
+	PlayerCount   int                `json:"playerCount"`
+}
+
+type PlayerIdPayload struct {
+	PlayerId string `json:"playerId"`
+}
+
+type RoundStartedPayload struct {
+	Category      string          `json:"category"`
+	UsedLetters   map[string]bool `json:"usedLetters"`
+	CurrentPlayer *Player         `json:"currentPlayer"`
+}
+
+type CountdownTickPayload struct {
+	Countdown int `json:"countdown"`
+}
+
+type UsedLettersPayload = map[string]bool
+
+// LetterSelectedPayload is the letter-selected payload from protocol
+// version 2 on.
+type LetterSelectedPayload struct {
+	UsedLetters map[string]bool `json:"usedLetters"`
+}
+
+type StartTurnPayload struct {
+	CurrentPlayer *Player         `json:"currentPlayer"`
+	UsedLetters   map[string]bool `json:"usedLetters"`
+	Answers       []Answer        `json:"answers"`
+}
+
+// AnswerRejectedPayload tells a player why their answer was not accepted.
+// Their turn carries on, so they can answer again before time runs out.
+type AnswerRejectedPayload struct {
+	Answer string `json:"answer"`
+	Reason string `json:"reason"`
+}
+
+// TurnRefusedPayload tells a player why the letter they tried to choose, or
+// the turn they tried to end or reset, was refused.
+type TurnRefusedPayload struct {
+	Reason string `json:"reason"`
+}
+
+// ChallengeStartedPayload asks Voters to vote on a challenged answer
+// within VoteSeconds. The challenger is counted as voting to strike it and
+// the player who gave it does not vote. The timer is paused until the vote
+// ends.
+type ChallengeStartedPayload struct {
+	ChallengerId string   `json:"challengerId"`
+	Answer       Answer   `json:"answer"`
+	Voters       []string `json:"voters"`
+	VoteSeconds  int      `json:"voteSeconds"`
+}
+
+// ChallengeResolvedPayload is the outcome of a vote. The answer is struck,
+// and the player who gave it eliminated, when more players voted to strike
+// it than to keep it, so a tie keeps it.
+type ChallengeResolvedPayload struct {
+	Answer Answer `json:"answer"`
+	Keep   int    `json:"keep"`
+	Strike int    `json:"strike"`
+	Struck bool   `json:"struck"`
+}
+
+type PlayerEliminatedPayload struct {
+	EliminatedPlayer *Player `json:"eliminatedPlayer"`
+}
+
+type RoundEndedPayload struct {
+	WinningPlayer *Player `json:"winningPlayer"`
+}
+
+type GameEndedPayload struct {
+	GameWinner    *Player         `json:"gameWinner"`
+	UsedLetters   map[string]bool `json:"usedLetters"`
+	CurrentPlayer *Player         `json:"currentPlayer"`
+	PlayerCount   int             `json:"playerCount"`
+}
+
+type QueuePositionPayload struct {
+	Position int `json:"position"`
+	Waiting  int `json:"waiting"`
+}
+
+type MatchFoundPayload struct {
+	RoomId      string      `json:"roomId"`
+	Preferences Preferences `json:"preferences"`
+}
+
+type ServerShuttingDownPayload struct {
+	SecondsRemaining int `json:"secondsRemaining"`
+}
+
+type RoomClosedPayload struct {
+	RoomId string `json:"roomId"`
+	Reason string `json:"reason"`
+}
+
+type PlayerKickedPayload struct {
+	PlayerId string `json:"playerId"`
+	Reason   string `json:"reason"`
+}
+
+type SystemMessagePayload struct {
+	Message string `json:"message"`
+}
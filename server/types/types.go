@@ -2,29 +2,51 @@ package types
 
 import (
 	"encoding/json"
+	"strings"
 )
 
 type EventType string
 
 const (
-	EventTypeConnection       EventType = "connection"
-	EventTypeDisconnect       EventType = "disconnect"
-	EventTypeJoinRoom         EventType = "join-room"
-	EventTypeRoomJoined       EventType = "room-joined"
-	EventTypeDisconnected     EventType = "disconnected"
-	EventTypeRoomLocked       EventType = "room-locked"
-	EventTypeCountdownStarted EventType = "countdown-started"
-	EventTypeRoundStarted     EventType = "round-started"
-	EventTypeCountdownTick    EventType = "tick"
-	EventTypeSelectLetter     EventType = "select-letter"
-	EventTypeLetterSelected   EventType = "letter-selected"
-	EventTypeStartTurn        EventType = "start-turn"
-	EventTypeEndTurn          EventType = "end-turn"
-	EventTypeResetTimer       EventType = "reset-timer"
-	EventTypeLeaveRoom        EventType = "leave-room"
-	EventTypePlayerEliminated EventType = "player-eliminated"
-	EventTypeRoundEnded       EventType = "round-ended"
-	EventTypeGameEnded        EventType = "game-ended"
+	EventTypeConnection         EventType = "connection"
+	EventTypeDisconnect         EventType = "disconnect"
+	EventTypeJoinRoom           EventType = "join-room"
+	EventTypeRoomJoined         EventType = "room-joined"
+	EventTypeDisconnected       EventType = "disconnected"
+	EventTypeRoomLocked         EventType = "room-locked"
+	EventTypeCountdownStarted   EventType = "countdown-started"
+	EventTypeRoundStarted       EventType = "round-started"
+	EventTypeCountdownTick      EventType = "tick"
+	EventTypeSelectLetter       EventType = "select-letter"
+	EventTypeLetterSelected     EventType = "letter-selected"
+	EventTypeStartTurn          EventType = "start-turn"
+	EventTypeEndTurn            EventType = "end-turn"
+	EventTypeResetTimer         EventType = "reset-timer"
+	EventTypeLeaveRoom          EventType = "leave-room"
+	EventTypePlayerEliminated   EventType = "player-eliminated"
+	EventTypeRoundEnded         EventType = "round-ended"
+	EventTypeGameEnded          EventType = "game-ended"
+	EventTypeJoinQueue          EventType = "join-queue"
+	EventTypeLeaveQueue         EventType = "leave-queue"
+	EventTypeQueuePosition      EventType = "queue-position"
+	EventTypeMatchFound         EventType = "match-found"
+	EventTypeQueueTimeout       EventType = "queue-timeout"
+	EventTypeHello              EventType = "hello"
+	EventTypeServerHello        EventType = "server-hello"
+	EventTypeProtocolError      EventType = "protocol-error"
+	EventTypeServerShuttingDown EventType = "server-shutting-down"
+	EventTypeRoomClosed         EventType = "room-closed"
+	EventTypePlayerKicked       EventType = "player-kicked"
+	EventTypeSystemMessage      EventType = "system-message"
+	EventTypeUnauthorized       EventType = "unauthorized"
+	EventTypeRateLimited        EventType = "rate-limited"
+	EventTypeSetIsInTextMode    EventType = "set-is-in-text-mode"
+	EventTypeAnswerRejected     EventType = "answer-rejected"
+	EventTypeTurnRefused        EventType = "turn-refused"
+	EventTypeChallengeAnswer    EventType = "challenge-answer"
+	EventTypeChallengeStarted   EventType = "challenge-started"
+	EventTypeCastVote           EventType = "cast-vote"
+	EventTypeChallengeResolved  EventType = "challenge-resolved"
 )
 
 type Event struct {
@@ -33,9 +55,61 @@ type Event struct {
 }
 
 type Player struct {
-	Id         string `json:"id"`
-	Name       string `json:"name"`
-	IsTurn     bool   `json:"isTurn"`
-	Eliminated bool   `json:"eliminated"`
-	WinCount   int    `json:"winCount"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// AccountId is the account the player signed in with, empty for
+	// guests.
+	AccountId string `json:"accountId"`
+	// Bot is set for players the server plays itself.
+	Bot        bool `json:"bot"`
+	IsTurn     bool `json:"isTurn"`
+	Eliminated bool `json:"eliminated"`
+	WinCount   int  `json:"winCount"`
+}
+
+// Answer is a word a player gave on their turn in typed answers mode.
+// Unknown answers are not in the server's dictionary, and struck answers
+// were challenged and voted down.
+type Answer struct {
+	PlayerId string `json:"playerId"`
+	Letter   string `json:"letter"`
+	Answer   string `json:"answer"`
+	Unknown  bool   `json:"unknown"`
+	Struck   bool   `json:"struck"`
+}
+
+const (
+	LetterSetEasy string = "easy"
+	LetterSetHard        = "hard"
+)
+
+var LetterSets []string = []string{
+	LetterSetEasy, LetterSetHard,
+}
+
+// Letters are the letters players choose from in each letter set. The easy
+// set leaves out the letters few words start with.
+var Letters = map[string][]string{
+	LetterSetEasy: strings.Split("ABCDEFGHIJKLMNOPRSTW", ""),
+	LetterSetHard: strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ", ""),
+}
+
+// Languages are the languages players can ask to be matched in, as ISO
+// 639-1 codes.
+const (
+	LanguageEnglish string = "en"
+	LanguageMaori          = "mi"
+)
+
+var Languages []string = []string{
+	LanguageEnglish, LanguageMaori,
+}
+
+// Preferences are the game settings a player asks for when queueing for a
+// quick match. An empty field means the player does not mind. A room with
+// a letter set only lets players choose letters from it.
+type Preferences struct {
+	Difficulty string `json:"difficulty"`
+	Language   string `json:"language"`
+	LetterSet  string `json:"letterSet"`
 }
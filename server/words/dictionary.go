@@ -0,0 +1,152 @@
+package words
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Strictness is what happens to typed answers the dictionary does not know.
+type Strictness string
+
+const (
+	// StrictnessOff accepts every answer without looking it up.
+	StrictnessOff Strictness = "off"
+	// StrictnessWarn accepts unknown answers but marks them, so players
+	// can see which ones to challenge.
+	StrictnessWarn Strictness = "warn"
+	// StrictnessReject refuses unknown answers.
+	StrictnessReject Strictness = "reject"
+)
+
+var Strictnesses = []string{string(StrictnessOff), string(StrictnessWarn), string(StrictnessReject)}
+
+var ErrNotInDictionary = errors.New("answer is not in the dictionary")
+
+// LoadDir reads every .txt file in a directory into one list. Files are in
+// the format Parse reads, except that words before the first category go
+// in the category the file is named after, so fish.txt may simply list
+// fish. Underscores in file names stand for spaces.
+func LoadDir(dir string) (*List, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+	l := New()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		category := strings.ReplaceAll(strings.TrimSuffix(filepath.Base(path), ".txt"), "_", " ")
+		file, err := parse(f, category)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		l.Merge(file)
+	}
+	return l, nil
+}
+
+// Dictionary checks typed answers against a word list, forgiving small
+// typos. Answers are compared without regard to case, accents, spaces or
+// punctuation, so "ant eater" matches Anteater.
+type Dictionary struct {
+	list       *List
+	strictness Strictness
+}
+
+func NewDictionary(list *List, strictness Strictness) *Dictionary {
+	return &Dictionary{list: list, strictness: strictness}
+}
+
+func (d *Dictionary) Strictness() Strictness {
+	return d.strictness
+}
+
+// Check looks up an answer in the category and returns the word it
+// matched. The error wraps ErrNotInDictionary when no word is close
+// enough, or the word it is closest to does not start with letter.
+func (d *Dictionary) Check(category, letter, answer string) (string, error) {
+	key := fold(answer)
+	best, bestDistance := "", -1
+	for _, word := range d.list.Words(category) {
+		distance := editDistance(key, fold(word))
+		if distance <= tolerance(key) && (bestDistance == -1 || distance < bestDistance) {
+			best, bestDistance = word, distance
+		}
+		if distance == 0 {
+			break
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: %q is not a known %s", ErrNotInDictionary, answer, category)
+	}
+	if !strings.EqualFold(Initial(best), letter) {
+		return "", fmt.Errorf("%w: %q looks like %s, which does not start with %s", ErrNotInDictionary, answer, best, letter)
+	}
+	return best, nil
+}
+
+// fold reduces a word to the lower case letters and digits it is spelled
+// with.
+func fold(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(word) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// tolerance is how many typos are forgiven in a folded word: none in short
+// words, where one letter makes another word, and more in longer ones.
+func tolerance(folded string) int {
+	switch n := len([]rune(folded)); {
+	case n <= 4:
+		return 0
+	case n <= 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance counts the letters inserted, deleted, changed or swapped
+// with their neighbour to turn a into b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
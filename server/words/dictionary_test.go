@@ -0,0 +1,87 @@
+package words
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"fish.txt":                "Snapper\nkahawai\n",
+		"musical_instruments.txt": "Ukulele\n[Fish]\nTarakihi\n",
+		"notes.md":                "Not a word list\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Snapper", "kahawai", "Tarakihi"}; !reflect.DeepEqual(l.Words("Fish"), want) {
+		t.Fatalf("fish = %v, want %v", l.Words("Fish"), want)
+	}
+	if want := []string{"Ukulele"}; !reflect.DeepEqual(l.Words("Musical Instruments"), want) {
+		t.Fatalf("musical instruments = %v, want %v", l.Words("Musical Instruments"), want)
+	}
+	if _, err := LoadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("loaded a missing directory")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	l := New()
+	l.Add("Fish", "Snapper", "Tarakihi", "Eel", "Ōrange roughy")
+	l.Add("Animals", "Anteater")
+	d := NewDictionary(l, StrictnessReject)
+	tests := []struct {
+		category, letter, answer string
+		want                     string
+		known                    bool
+	}{
+		{"Fish", "S", "snapper", "Snapper", true},
+		{"Fish", "S", "Snaper", "Snapper", true},
+		{"Fish", "T", "Tarakhii", "Tarakihi", true},
+		{"Fish", "O", "orange roughy", "Ōrange roughy", true},
+		{"Animals", "A", "Ant-eater", "Anteater", true},
+		{"Fish", "E", "Eels", "", false},
+		{"Fish", "C", "Cod", "", false},
+		{"Fish", "N", "Nsapper", "", false},
+		{"Animals", "S", "Snapper", "", false},
+		{"Tools", "S", "Snapper", "", false},
+	}
+	for _, tt := range tests {
+		got, err := d.Check(tt.category, tt.letter, tt.answer)
+		if tt.known && (err != nil || got != tt.want) {
+			t.Errorf("Check(%q, %q, %q) = %q, %v, want %q", tt.category, tt.letter, tt.answer, got, err, tt.want)
+		}
+		if !tt.known && !errors.Is(err, ErrNotInDictionary) {
+			t.Errorf("Check(%q, %q, %q) = %q, %v, want %v", tt.category, tt.letter, tt.answer, got, err, ErrNotInDictionary)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"snapper", "snapper", 0},
+		{"snaper", "snapper", 1},
+		{"snpaper", "snapper", 1},
+		{"snappre", "snapper", 1},
+		{"kitten", "sitting", 3},
+		{"", "eel", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
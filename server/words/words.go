@@ -0,0 +1,123 @@
+// Package words keeps the words the server knows for each category.
+package words
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed words.txt
+var defaultWords string
+
+// List holds words by category. Categories are matched without regard to
+// case.
+type List struct {
+	categories map[string][]string
+}
+
+func New() *List {
+	return &List{categories: make(map[string][]string)}
+}
+
+// Default is the built-in list, with words for every category in
+// types.C.
+func Default() *List {
+	l, err := Parse(strings.NewReader(defaultWords))
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Load reads a word list file. See Parse for its format.
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	l, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Parse reads a word list where a line in brackets, such as [Fish], starts
+// a category and each following line is a word in it. Blank lines and
+// lines starting with # are ignored.
+func Parse(r io.Reader) (*List, error) {
+	return parse(r, "")
+}
+
+// parse reads a word list, putting words before the first category in
+// category.
+func parse(r io.Reader, category string) (*List, error) {
+	l := New()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			category = strings.TrimSpace(line[1 : len(line)-1])
+		case category == "":
+			return nil, fmt.Errorf("line %d: word %q is not in a category", n, line)
+		default:
+			l.Add(category, line)
+		}
+	}
+	return l, scanner.Err()
+}
+
+// Add adds words to a category, skipping any it already has.
+func (l *List) Add(category string, words ...string) {
+	key := strings.ToLower(category)
+	for _, word := range words {
+		if !slices.ContainsFunc(l.categories[key], func(w string) bool { return strings.EqualFold(w, word) }) {
+			l.categories[key] = append(l.categories[key], word)
+		}
+	}
+}
+
+// Merge adds every word in other to the list.
+func (l *List) Merge(other *List) {
+	for category, words := range other.categories {
+		l.Add(category, words...)
+	}
+}
+
+// Words lists a category's words.
+func (l *List) Words(category string) []string {
+	return l.categories[strings.ToLower(category)]
+}
+
+// Starting lists a category's words starting with the letter.
+func (l *List) Starting(category, letter string) []string {
+	words := []string{}
+	for _, word := range l.Words(category) {
+		if strings.EqualFold(Initial(word), letter) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// Initial is the letter a word starts with, in upper case and without
+// accents, so Ōtaki starts with O. Leading punctuation is skipped.
+func Initial(word string) string {
+	for _, r := range norm.NFD.String(word) {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToUpper(r))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package words
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/campbell-rehu/quik-be/types"
+)
+
+func TestParse(t *testing.T) {
+	l, err := Parse(strings.NewReader(`
+# Fish found in New Zealand
+[Fish]
+Snapper
+kahawai
+
+[fish]
+snapper
+Tarakihi
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Snapper", "kahawai", "Tarakihi"}; !reflect.DeepEqual(l.Words("FISH"), want) {
+		t.Fatalf("words = %v, want %v", l.Words("FISH"), want)
+	}
+	if want := []string{"Tarakihi"}; !reflect.DeepEqual(l.Starting("Fish", "t"), want) {
+		t.Fatalf("starting with t = %v, want %v", l.Starting("Fish", "t"), want)
+	}
+}
+
+func TestParseWordOutsideCategory(t *testing.T) {
+	if _, err := Parse(strings.NewReader("Snapper\n[Fish]\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("err = %v, want the line of the stray word", err)
+	}
+}
+
+func TestLoadMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("[Fish]\nZander\nsnapper\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	extra, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New()
+	l.Add("Fish", "Snapper")
+	l.Merge(extra)
+	if want := []string{"Snapper", "Zander"}; !reflect.DeepEqual(l.Words("Fish"), want) {
+		t.Fatalf("words = %v, want %v", l.Words("Fish"), want)
+	}
+}
+
+func TestInitial(t *testing.T) {
+	tests := map[string]string{
+		"apple":    "A",
+		"Ōtaki":    "O",
+		"'Ohana":   "O",
+		"élan":     "E",
+		"":         "",
+		"12 Drums": "D",
+	}
+	for word, want := range tests {
+		if got := Initial(word); got != want {
+			t.Errorf("Initial(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestDefaultCoversCategories(t *testing.T) {
+	l := Default()
+	for difficulty, categories := range types.C {
+		for _, category := range categories {
+			if len(l.Words(category)) == 0 {
+				t.Errorf("no words for %s category %q", difficulty, category)
+			}
+		}
+	}
+}
@@ -39,8 +39,11 @@ export const RoomContextProvider: React.FC<Props & PropsWithChildren> = ({
   const navigate = useNavigate()
 
   const parseResponse = async (response: Response) => {
-    const json = await response.json()
-    return JSON.parse(json)
+    const { data, error } = await response.json()
+    if (error) {
+      throw new Error(error.message)
+    }
+    return data
   }
 
   const createRoom = async () => {